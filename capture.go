@@ -0,0 +1,102 @@
+package errs
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// CaptureMode controls if call stacks are captured
+// by New, Errorf, WrapWithCallStack and the WrapWithFuncParams family.
+//
+// Errors created without a call stack still have their message
+// and function parameters, only the call stack locations
+// are missing from the formatted error.
+type CaptureMode uint32
+
+const (
+	// CaptureFull captures the call stack of every error.
+	CaptureFull CaptureMode = iota
+
+	// CaptureSampled captures the call stack of
+	// one in every SetCaptureSampleRate errors.
+	CaptureSampled
+
+	// CaptureOff never calls runtime.Callers.
+	CaptureOff
+)
+
+// String implements the fmt.Stringer interface.
+func (m CaptureMode) String() string {
+	switch m {
+	case CaptureFull:
+		return "full"
+	case CaptureSampled:
+		return "sampled"
+	case CaptureOff:
+		return "off"
+	}
+	return fmt.Sprintf("CaptureMode(%d)", uint32(m))
+}
+
+const defaultCaptureSampleRate = 100
+
+var (
+	captureMode atomic.Uint32
+	// captureSampleRate of zero means defaultCaptureSampleRate
+	captureSampleRate atomic.Int64
+	captureCounter    atomic.Uint64
+)
+
+// SetCaptureMode sets the CaptureMode for all errors
+// created after the call. It is safe to call concurrently.
+//
+// Has no effect if the package was built with the
+// errs_nostack build tag which disables call stack capture
+// at compile time.
+func SetCaptureMode(mode CaptureMode) {
+	captureMode.Store(uint32(mode))
+}
+
+// CurrentCaptureMode returns the CaptureMode set with SetCaptureMode,
+// or CaptureOff if the package was built with the errs_nostack build tag.
+//
+// Default: CaptureFull
+func CurrentCaptureMode() CaptureMode {
+	if captureDisabled {
+		return CaptureOff
+	}
+	return CaptureMode(captureMode.Load())
+}
+
+// SetCaptureSampleRate sets how many errors share one captured
+// call stack in CaptureSampled mode: one in every n errors
+// gets a call stack. Values less than 1 are treated as 1.
+//
+// Default: 100
+func SetCaptureSampleRate(n int) {
+	captureSampleRate.Store(int64(max(n, 1)))
+}
+
+// captureCallStack reports if a full call stack
+// should be captured according to the CaptureMode.
+func captureCallStack() bool {
+	switch CurrentCaptureMode() {
+	case CaptureFull:
+		return true
+	case CaptureSampled:
+		rate := captureSampleRate.Load()
+		if rate == 0 {
+			rate = defaultCaptureSampleRate
+		}
+		return (captureCounter.Add(1)-1)%uint64(rate) == 0
+	}
+	return false
+}
+
+// captureCallerPC reports if the program counter of the calling function
+// should be captured for a wrapper whose wrapped error already has
+// a call stack. Capturing a single program counter is cheap,
+// so it is only skipped in CaptureOff mode.
+func captureCallerPC() bool {
+	return CurrentCaptureMode() != CaptureOff
+}
@@ -0,0 +1,29 @@
+//go:build errs_nostack
+
+package errs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoStack_NoCallStack(t *testing.T) {
+	// Setting the capture mode has no effect
+	SetCaptureMode(CaptureFull)
+	assert.Equal(t, CaptureOff, CurrentCaptureMode())
+
+	err := New("no stack")
+	assert.Equal(t, "no stack\n", err.Error())
+	assert.Empty(t, err.(*withCallStack).CallStack())
+	assert.Nil(t, err.(*withCallStack).StackTrace())
+
+	err = captureTestFunc(1, "a")
+	require.Error(t, err)
+	assert.Empty(t, CallStackFrames(err))
+	assert.Equal(t, "capture test\n<unknown>(1, `a`)\n", err.Error())
+	callStack, params := err.(*withCallStackFuncParams).CallStackParams()
+	assert.Empty(t, callStack)
+	assert.Equal(t, []any{1, "a"}, params)
+}
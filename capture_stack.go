@@ -0,0 +1,7 @@
+//go:build !errs_nostack
+
+package errs
+
+// captureDisabled is true if the package was
+// built with the errs_nostack build tag.
+const captureDisabled = false
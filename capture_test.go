@@ -0,0 +1,109 @@
+package errs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// skipWithoutCallStacks skips tests expecting captured call stacks
+// if the package was built with the errs_nostack build tag.
+func skipWithoutCallStacks(t testing.TB) {
+	t.Helper()
+	if captureDisabled {
+		t.Skip("call stack capture disabled by errs_nostack build tag")
+	}
+}
+
+func withCaptureMode(t testing.TB, mode CaptureMode, sampleRate int) {
+	t.Helper()
+	skipWithoutCallStacks(t)
+	t.Cleanup(func() {
+		SetCaptureMode(CaptureFull)
+		SetCaptureSampleRate(100)
+		captureCounter.Store(0)
+	})
+	SetCaptureMode(mode)
+	SetCaptureSampleRate(sampleRate)
+	captureCounter.Store(0)
+}
+
+func captureTestFunc(i int, s string) (err error) {
+	defer WrapWith2FuncParams(&err, i, s)
+
+	return New("capture test")
+}
+
+func TestCaptureMode_String(t *testing.T) {
+	assert.Equal(t, "full", CaptureFull.String())
+	assert.Equal(t, "sampled", CaptureSampled.String())
+	assert.Equal(t, "off", CaptureOff.String())
+	assert.Equal(t, "CaptureMode(9)", CaptureMode(9).String())
+}
+
+func TestCaptureOff(t *testing.T) {
+	withCaptureMode(t, CaptureOff, 1)
+	assert.Equal(t, CaptureOff, CurrentCaptureMode())
+
+	err := New("no stack")
+	assert.Empty(t, err.(*withCallStack).CallStack())
+	assert.Nil(t, err.(*withCallStack).StackTrace())
+	assert.Equal(t, "no stack\n", err.Error())
+	assert.ErrorIs(t, err, Sentinel("no stack"))
+
+	err = Errorf("wrapped: %w", ErrNotFound)
+	assert.Equal(t, "wrapped: not found\n", err.Error())
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Message and function parameters are preserved
+	err = captureTestFunc(1, "a")
+	assert.Empty(t, err.(*withCallStackFuncParams).CallStack())
+	assert.Equal(t, "capture test\n<unknown>(1, `a`)\n", err.Error())
+}
+
+func TestCaptureSampled(t *testing.T) {
+	withCaptureMode(t, CaptureSampled, 3)
+
+	var captured int
+	for range 9 {
+		if len(New("sampled").(*withCallStack).CallStack()) > 0 {
+			captured++
+		}
+	}
+	assert.Equal(t, 3, captured)
+
+	// Sample rate values less than 1 capture every call stack
+	SetCaptureSampleRate(0)
+	for range 3 {
+		assert.NotEmpty(t, New("sampled").(*withCallStack).CallStack())
+	}
+}
+
+func TestCaptureSampled_CallerPC(t *testing.T) {
+	withCaptureMode(t, CaptureSampled, 1000)
+
+	// The first error is sampled
+	err := captureTestFunc(1, "a")
+	require.NotEmpty(t, CallStackFrames(err))
+
+	// Not sampled errors still get the program counter
+	// of the wrapping function with parameters
+	err = captureTestFunc(2, "b")
+	frames := ResolveCallStack(err.(*withCallStackFuncParams).CallStack())
+	require.Len(t, frames, 1)
+	assert.Equal(t, "github.com/domonda/go-errs.captureTestFunc", frames[0].Function)
+	assert.Contains(t, err.Error(), "github.com/domonda/go-errs.captureTestFunc(2, `b`)\n")
+}
+
+func BenchmarkCaptureMode(b *testing.B) {
+	for _, mode := range []CaptureMode{CaptureFull, CaptureSampled, CaptureOff} {
+		b.Run(mode.String(), func(b *testing.B) {
+			withCaptureMode(b, mode, 100)
+			b.ReportAllocs()
+			for b.Loop() {
+				_ = captureTestFunc(1, "a")
+			}
+		})
+	}
+}
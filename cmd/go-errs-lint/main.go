@@ -0,0 +1,43 @@
+/*
+go-errs-lint checks the usage of github.com/domonda/go-errs
+with the analyzer of the go-errs-wrap/analyzer package.
+
+It reports functions with a named error result that are missing
+a defer errs.Wrap statement, stale defer errs.WrapWithFuncParams
+statements, declarations shadowing the wrapped error result
+and misuse of the go-errs package.
+
+# Usage
+
+	go-errs-lint [flags] <packages>
+
+# Flags
+
+	-missing=false  Don't report functions missing a defer errs.Wrap statement
+	-shadow=false   Don't report declarations shadowing the wrapped error result
+	                and naked returns dropping checked errors
+	-minvariadic    Expect specialized WrapWithNFuncParams functions
+	-fix            Apply all suggested fixes
+
+Run go-errs-lint -help for all flags of the analysis driver.
+
+# Examples
+
+	go-errs-lint ./...
+	go-errs-lint -fix ./pkg/...
+
+It can also run as vet tool:
+
+	go vet -vettool=$(which go-errs-lint) ./...
+*/
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/domonda/go-errs/cmd/go-errs-wrap/analyzer"
+)
+
+func main() {
+	singlechecker.Main(analyzer.Analyzer)
+}
@@ -0,0 +1,534 @@
+// Package analyzer provides a golang.org/x/tools/go/analysis Analyzer
+// with the checks of go-errs-wrap -validate and checks for common
+// misuse of the go-errs package, so they can run with go vet,
+// gopls, golangci-lint or the go-errs-lint command.
+package analyzer
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"go/types"
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+
+	"github.com/domonda/go-errs/cmd/go-errs-wrap/rewrite"
+)
+
+const doc = `check usage of github.com/domonda/go-errs
+
+Reports functions with a named error result that don't defer an errs.Wrap
+function (like go-errs-wrap insert -validate), defer errs.WrapWithFuncParams
+statements that don't match the function parameters anymore (like
+go-errs-wrap replace -validate), and misuse of the go-errs package:
+
+  - deferring an errs function whose error result is discarded,
+    like defer errs.WrapWithCallStack(err)
+  - wrapping a pointer to an error that is not a named result
+    of the function, like a shadowed err variable
+  - function parameters passed in a different order than declared
+  - declarations of a variable shadowing the named error result
+    wrapped by a deferred errs.Wrap function, like x, err := f()
+    in an inner scope whose value is not only returned
+  - naked returns after checking another error variable for nil,
+    which don't assign it to the wrapped result
+  - errs.Sentinel declared as var instead of const
+  - errs.New(fmt.Sprintf(...)) instead of errs.Errorf(...)
+
+Functions and files with a //go-errs-wrap:ignore directive are not
+checked for missing and stale wrap statements, the parameters listed
+by a //go-errs-wrap:params directive are expected instead of all.
+
+Suggested fixes are provided where the correct code is unambiguous.`
+
+// errsPkgPath is the import path of the go-errs package.
+const errsPkgPath = "github.com/domonda/go-errs"
+
+// Settings of the checks of an analyzer returned by NewAnalyzer,
+// the defaults of its flags with the names of the JSON fields.
+type Settings struct {
+	// Missing reports functions with a named error result
+	// without a defer errs.Wrap statement
+	Missing bool `json:"missing"`
+	// Shadow reports declarations shadowing the named error result
+	// wrapped by a deferred errs.Wrap function
+	// and naked returns dropping checked errors
+	Shadow bool `json:"shadow"`
+	// MinVariadic expects specialized WrapWithNFuncParams
+	// functions instead of variadic WrapWithFuncParams
+	MinVariadic bool `json:"minvariadic"`
+}
+
+// DefaultSettings returns the settings of Analyzer.
+func DefaultSettings() Settings {
+	return Settings{Missing: true, Shadow: true}
+}
+
+// Analyzer reports missing, stale and misused go-errs error wrapping
+// with the DefaultSettings as defaults of its flags.
+var Analyzer = NewAnalyzer(DefaultSettings())
+
+// NewAnalyzer returns an analyzer like Analyzer with settings
+// as defaults of its flags. The settings of every analyzer
+// are independent of the others.
+func NewAnalyzer(settings Settings) *analysis.Analyzer {
+	a := &analysis.Analyzer{
+		Name:     "goerrswrap",
+		Doc:      doc,
+		URL:      "https://pkg.go.dev/github.com/domonda/go-errs/cmd/go-errs-wrap/analyzer",
+		Requires: []*analysis.Analyzer{inspect.Analyzer},
+		Run: func(pass *analysis.Pass) (any, error) {
+			return run(pass, settings)
+		},
+	}
+	// The flags set the fields of settings used by Run
+	a.Flags.BoolVar(&settings.Missing, "missing", settings.Missing, "report functions with a named error result without a defer errs.Wrap statement")
+	a.Flags.BoolVar(&settings.Shadow, "shadow", settings.Shadow, "report declarations shadowing the named error result wrapped by a deferred errs.Wrap function and naked returns dropping checked errors")
+	a.Flags.BoolVar(&settings.MinVariadic, "minvariadic", settings.MinVariadic, "expect specialized WrapWithNFuncParams functions instead of variadic WrapWithFuncParams")
+	return a
+}
+
+// funcParamsWrapperName matches the names of the functions
+// generated by go-errs-wrap replace and insert.
+var funcParamsWrapperName = regexp.MustCompile(`^WrapWith(\d+FuncParams?|FuncParams)$`)
+
+var (
+	errorType    = types.Universe.Lookup("error").Type()
+	errorPtrType = types.NewPointer(errorType)
+)
+
+func run(pass *analysis.Pass, settings Settings) (any, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	nodeFilter := []ast.Node{
+		(*ast.File)(nil),
+		(*ast.FuncDecl)(nil),
+		(*ast.FuncLit)(nil),
+		(*ast.DeferStmt)(nil),
+		(*ast.GenDecl)(nil),
+		(*ast.CallExpr)(nil),
+	}
+	var (
+		file        *ast.File
+		fileIgnored bool
+		keptVars    = sentinelUseReasons(pass, inspect)
+	)
+	inspect.WithStack(nodeFilter, func(n ast.Node, push bool, stack []ast.Node) bool {
+		if !push {
+			return true
+		}
+		switch node := n.(type) {
+		case *ast.File:
+			if ast.IsGenerated(node) {
+				return false
+			}
+			file = node
+			fileIgnored = rewrite.IsFileIgnored(node)
+		case *ast.FuncDecl:
+			checkDirectives(pass, node)
+			if node.Body != nil {
+				_, directives := enclosingFunc(stack, fileIgnored)
+				if settings.Missing {
+					checkMissingWrap(pass, file, node.Name.Name, node.Name, node.Type, directives, node.Body, settings.MinVariadic)
+				}
+				if settings.Shadow {
+					checkShadowedResult(pass, node.Type, node.Body)
+				}
+			}
+		case *ast.FuncLit:
+			_, directives := enclosingFunc(stack, fileIgnored)
+			if settings.Missing {
+				checkMissingWrap(pass, file, "anonymous function", node.Type, node.Type, directives, node.Body, settings.MinVariadic)
+			}
+			if settings.Shadow {
+				checkShadowedResult(pass, node.Type, node.Body)
+			}
+		case *ast.DeferStmt:
+			funcType, directives := enclosingFunc(stack, fileIgnored)
+			checkDeferredWrap(pass, file, node, funcType, directives, settings.MinVariadic)
+		case *ast.GenDecl:
+			if len(stack) == 2 { // File, GenDecl
+				checkSentinelVar(pass, node, keptVars)
+			}
+		case *ast.CallExpr:
+			checkNewSprintf(pass, node)
+		}
+		return true
+	})
+	return nil, nil
+}
+
+// enclosingFunc returns the type of the innermost function in stack,
+// or nil if there is none, and its go-errs-wrap directives.
+// Function literals are ignored within ignored functions
+// and all functions are ignored in an ignored file.
+func enclosingFunc(stack []ast.Node, fileIgnored bool) (funcType *ast.FuncType, directives rewrite.Directives) {
+	for i := len(stack) - 1; i >= 0; i-- {
+		switch node := stack[i].(type) {
+		case *ast.FuncDecl:
+			if funcType == nil {
+				funcType = node.Type
+				directives = rewrite.FuncDirectives(node.Doc)
+			} else {
+				directives.Ignore = rewrite.FuncDirectives(node.Doc).Ignore
+			}
+		case *ast.FuncLit:
+			if funcType == nil {
+				funcType = node.Type
+			}
+		}
+	}
+	directives.Ignore = directives.Ignore || fileIgnored
+	return funcType, directives
+}
+
+// checkDirectives reports parameters listed by a //go-errs-wrap:params
+// directive of decl that are not parameters of the function.
+func checkDirectives(pass *analysis.Pass, decl *ast.FuncDecl) {
+	for _, name := range rewrite.FuncDirectives(decl.Doc).UnknownParams(decl.Type) {
+		pass.Reportf(decl.Name.Pos(), "%s of %s lists unknown parameter %s", rewrite.ParamsDirective, decl.Name.Name, name)
+	}
+}
+
+// errsFunc returns the function of the go-errs package called by call
+// or nil if call doesn't statically call a function of go-errs.
+func errsFunc(pass *analysis.Pass, call *ast.CallExpr) *types.Func {
+	fn := typeutil.StaticCallee(pass.TypesInfo, call)
+	if fn == nil || fn.Pkg() == nil || fn.Pkg().Path() != errsPkgPath {
+		return nil
+	}
+	return fn
+}
+
+// errsImportName returns the name the go-errs package
+// is imported as in file or an empty string if it is
+// not imported or imported as blank or dot import.
+func errsImportName(file *ast.File) string {
+	for _, imp := range file.Imports {
+		if imp.Path.Value != `"`+errsPkgPath+`"` {
+			continue
+		}
+		if imp.Name == nil {
+			return "errs"
+		}
+		if imp.Name.Name != "_" && imp.Name.Name != "." {
+			return imp.Name.Name
+		}
+	}
+	return ""
+}
+
+// qualify replaces the errs package name in the
+// generated statement with the import name used in file.
+func qualify(statement, importName string) string {
+	if importName == "errs" {
+		return statement
+	}
+	return strings.ReplaceAll(statement, "errs.", importName+".")
+}
+
+// checkMissingWrap reports a function with a named error result
+// in a non test file whose body doesn't defer an errs.Wrap function,
+// expecting a specialized WrapWithNFuncParams function if minVariadic.
+func checkMissingWrap(pass *analysis.Pass, file *ast.File, funcName string, pos ast.Node, funcType *ast.FuncType, directives rewrite.Directives, body *ast.BlockStmt, minVariadic bool) {
+	if strings.HasSuffix(pass.Fset.File(file.Pos()).Name(), "_test.go") {
+		return
+	}
+	statement := rewrite.WrapStatement(file, funcType, directives, nil, minVariadic)
+	if statement == "" || deferredWrap(pass, body) != nil {
+		return
+	}
+	importName := errsImportName(file)
+	if importName != "" {
+		statement = qualify(statement, importName)
+	}
+	diag := analysis.Diagnostic{
+		Pos:     pos.Pos(),
+		End:     pos.End(),
+		Message: fmt.Sprintf("%s is missing %s", funcName, statement),
+	}
+	// The body of a function with results can't be empty,
+	// so the statement is always inserted before the first one.
+	// Without import of go-errs the fix would not compile.
+	if importName != "" {
+		first := body.List[0].Pos()
+		diag.SuggestedFixes = []analysis.SuggestedFix{{
+			Message:   "Insert " + statement,
+			TextEdits: []analysis.TextEdit{{Pos: first, End: first, NewText: []byte(statement + "\n\n")}},
+		}}
+	}
+	pass.Report(diag)
+}
+
+// deferredWrap returns the first statement of body, not including
+// nested function literals, that defers an errs.Wrap function,
+// or nil if there is none.
+func deferredWrap(pass *analysis.Pass, body *ast.BlockStmt) (found *ast.DeferStmt) {
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.DeferStmt:
+			if fn := errsFunc(pass, node.Call); fn != nil && strings.HasPrefix(fn.Name(), "Wrap") {
+				found = node
+			}
+		}
+		return found == nil
+	})
+	return found
+}
+
+// checkDeferredWrap reports misuse of a deferred go-errs function
+// and stale defer errs.WrapWithFuncParams statements,
+// expecting a specialized WrapWithNFuncParams function if minVariadic.
+func checkDeferredWrap(pass *analysis.Pass, file *ast.File, stmt *ast.DeferStmt, funcType *ast.FuncType, directives rewrite.Directives, minVariadic bool) {
+	fn := errsFunc(pass, stmt.Call)
+	if fn == nil || funcType == nil {
+		return
+	}
+	sig := fn.Signature()
+
+	for i := range sig.Results().Len() {
+		if types.Identical(sig.Results().At(i).Type(), errorType) {
+			pass.Reportf(stmt.Call.Pos(), "result of deferred errs.%s is discarded, use errs.WrapWithFuncParams(&err, ...) to wrap the error result", fn.Name())
+			return
+		}
+	}
+
+	resultVarIndex := -1
+	for i := range sig.Params().Len() {
+		if types.Identical(sig.Params().At(i).Type(), errorPtrType) {
+			resultVarIndex = i
+			break
+		}
+	}
+	if resultVarIndex < 0 || resultVarIndex >= len(stmt.Call.Args) {
+		return
+	}
+	resultVar := stmt.Call.Args[resultVarIndex]
+	if !isNamedResultRef(pass, resultVar, funcType) {
+		if unary, ok := resultVar.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+			pass.Reportf(resultVar.Pos(), "errs.%s wraps %s which is not a named result of the function, the wrapped error is never returned", fn.Name(), types.ExprString(unary.X))
+		} else {
+			pass.Reportf(resultVar.Pos(), "errs.%s must be passed the address of a named error result of the function like &err", fn.Name())
+		}
+		return
+	}
+
+	var expected string
+	if funcParamsWrapperName.MatchString(fn.Name()) {
+		if importName := errsImportName(file); importName != "" {
+			expected = qualify(rewrite.WrapStatement(file, funcType, directives, stmt, minVariadic), importName)
+		}
+	}
+	var fixes []analysis.SuggestedFix
+	if expected != "" {
+		fixes = []analysis.SuggestedFix{{
+			Message:   "Replace with " + expected,
+			TextEdits: []analysis.TextEdit{{Pos: stmt.Pos(), End: stmt.End(), NewText: []byte(expected)}},
+		}}
+	}
+
+	if !paramsInOrder(pass, stmt.Call.Args[resultVarIndex+1:], funcType) {
+		pass.Report(analysis.Diagnostic{
+			Pos:            stmt.Call.Lparen + 1,
+			End:            stmt.Call.Rparen,
+			Message:        fmt.Sprintf("function parameters passed to errs.%s are not in the order of their declaration", fn.Name()),
+			SuggestedFixes: fixes,
+		})
+		return
+	}
+
+	if expected != "" && formatNode(pass.Fset, stmt) != expected {
+		pass.Report(analysis.Diagnostic{
+			Pos:            stmt.Pos(),
+			End:            stmt.End(),
+			Message:        fmt.Sprintf("stale error wrapper, expected %s", expected),
+			SuggestedFixes: fixes,
+		})
+	}
+}
+
+// isNamedResultRef reports if expr is the address
+// of a named result variable of funcType.
+func isNamedResultRef(pass *analysis.Pass, expr ast.Expr, funcType *ast.FuncType) bool {
+	unary, ok := expr.(*ast.UnaryExpr)
+	if !ok || unary.Op != token.AND {
+		return false
+	}
+	ident, ok := ast.Unparen(unary.X).(*ast.Ident)
+	if !ok || funcType.Results == nil {
+		return false
+	}
+	obj := pass.TypesInfo.Uses[ident]
+	for _, field := range funcType.Results.List {
+		for _, name := range field.Names {
+			if obj != nil && pass.TypesInfo.Defs[name] == obj {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// paramsInOrder reports if the parameters of funcType referenced
+// by args, optionally wrapped with errs.KeepSecret,
+// are in the order of their declaration.
+func paramsInOrder(pass *analysis.Pass, args []ast.Expr, funcType *ast.FuncType) bool {
+	paramIndex := make(map[types.Object]int)
+	for _, field := range funcType.Params.List {
+		for _, name := range field.Names {
+			if obj := pass.TypesInfo.Defs[name]; obj != nil {
+				paramIndex[obj] = len(paramIndex)
+			}
+		}
+	}
+	last := -1
+	for _, arg := range args {
+		if call, ok := arg.(*ast.CallExpr); ok && len(call.Args) == 1 {
+			if fn := errsFunc(pass, call); fn != nil && fn.Name() == "KeepSecret" {
+				arg = call.Args[0]
+			}
+		}
+		ident, ok := arg.(*ast.Ident)
+		if !ok {
+			continue
+		}
+		index, ok := paramIndex[pass.TypesInfo.Uses[ident]]
+		if !ok {
+			continue
+		}
+		if index < last {
+			return false
+		}
+		last = index
+	}
+	return true
+}
+
+// checkSentinelVar reports package level variables
+// of type errs.Sentinel that should be constants.
+// keptVars are the reasons why variables used in the package
+// can't be constants, see sentinelUseReasons.
+func checkSentinelVar(pass *analysis.Pass, decl *ast.GenDecl, keptVars map[types.Object]string) {
+	if decl.Tok != token.VAR {
+		return
+	}
+	// Only offer to change var to const if every
+	// variable of the declaration can be a Sentinel constant
+	var sentinels []*ast.Ident
+	canBeConst := true
+	for _, spec := range decl.Specs {
+		spec := spec.(*ast.ValueSpec)
+		for i, name := range spec.Names {
+			obj := pass.TypesInfo.Defs[name]
+			if !isSentinel(obj) {
+				canBeConst = false
+				continue
+			}
+			sentinels = append(sentinels, name)
+			if i >= len(spec.Values) || pass.TypesInfo.Types[spec.Values[i]].Value == nil {
+				canBeConst = false
+			}
+			// Uses of exported variables in other
+			// packages can't be checked by the analyzer
+			if keptVars[obj] != "" || name.IsExported() {
+				canBeConst = false
+			}
+		}
+	}
+	for _, name := range sentinels {
+		diag := analysis.Diagnostic{
+			Pos:     name.Pos(),
+			End:     name.End(),
+			Message: fmt.Sprintf("errs.Sentinel %s declared as var, declare it as const so it can't be reassigned", name.Name),
+		}
+		if canBeConst {
+			diag.SuggestedFixes = []analysis.SuggestedFix{{
+				Message:   "Declare as const",
+				TextEdits: []analysis.TextEdit{{Pos: decl.TokPos, End: decl.TokPos + token.Pos(len("var")), NewText: []byte("const")}},
+			}}
+		}
+		pass.Report(diag)
+	}
+}
+
+// sentinelUseReasons returns the errs.Sentinel variables of the package
+// that are used in ways a constant can't be used, like being
+// reassigned or having their address taken, with the reason
+// returned by rewrite.SentinelUseReason for their first such use.
+func sentinelUseReasons(pass *analysis.Pass, inspect *inspector.Inspector) map[types.Object]string {
+	reasons := make(map[types.Object]string)
+	inspect.WithStack([]ast.Node{(*ast.Ident)(nil)}, func(n ast.Node, push bool, stack []ast.Node) bool {
+		if !push {
+			return true
+		}
+		obj := pass.TypesInfo.Uses[n.(*ast.Ident)]
+		if !isSentinel(obj) || obj.Parent() != obj.Pkg().Scope() || reasons[obj] != "" {
+			return true
+		}
+		reasons[obj] = rewrite.SentinelUseReason(pass.TypesInfo, stack)
+		return true
+	})
+	return reasons
+}
+
+// isSentinel reports if obj is a variable of type errs.Sentinel.
+func isSentinel(obj types.Object) bool {
+	v, ok := obj.(*types.Var)
+	if !ok {
+		return false
+	}
+	named, ok := v.Type().(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == errsPkgPath && named.Obj().Name() == "Sentinel"
+}
+
+// checkNewSprintf reports errs.New(fmt.Sprintf(...))
+// with a fix replacing it with errs.Errorf(...).
+func checkNewSprintf(pass *analysis.Pass, call *ast.CallExpr) {
+	if len(call.Args) != 1 {
+		return
+	}
+	if fn := errsFunc(pass, call); fn == nil || fn.Name() != "New" {
+		return
+	}
+	inner, ok := call.Args[0].(*ast.CallExpr)
+	if !ok || inner.Ellipsis.IsValid() {
+		return
+	}
+	sprintf := typeutil.StaticCallee(pass.TypesInfo, inner)
+	if sprintf == nil || sprintf.Pkg() == nil || sprintf.Pkg().Path() != "fmt" || sprintf.Name() != "Sprintf" {
+		return
+	}
+	diag := analysis.Diagnostic{
+		Pos:     call.Pos(),
+		End:     call.End(),
+		Message: "use errs.Errorf(...) instead of errs.New(fmt.Sprintf(...))",
+	}
+	if sel, ok := call.Fun.(*ast.SelectorExpr); ok {
+		diag.SuggestedFixes = []analysis.SuggestedFix{{
+			Message: "Replace with errs.Errorf",
+			TextEdits: []analysis.TextEdit{
+				{Pos: sel.Sel.Pos(), End: sel.Sel.End(), NewText: []byte("Errorf")},
+				{Pos: call.Lparen + 1, End: inner.Lparen + 1},
+				{Pos: inner.Rparen, End: inner.Rparen + 1},
+			},
+		}}
+	}
+	pass.Report(diag)
+}
+
+func formatNode(fset *token.FileSet, node ast.Node) string {
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, node); err != nil {
+		return ""
+	}
+	return buf.String()
+}
@@ -0,0 +1,51 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), Analyzer, "a", "aliased", "noimport", "shadow")
+}
+
+func TestAnalyzer_Directives(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), Analyzer, "directives")
+}
+
+func TestAnalyzer_NotMissing(t *testing.T) {
+	settings := DefaultSettings()
+	settings.Missing = false
+	analysistest.Run(t, analysistest.TestData(), NewAnalyzer(settings), "notmissing")
+}
+
+func TestAnalyzer_NoShadow(t *testing.T) {
+	settings := DefaultSettings()
+	settings.Shadow = false
+	analysistest.Run(t, analysistest.TestData(), NewAnalyzer(settings), "noshadow")
+}
+
+func TestAnalyzer_MinVariadic(t *testing.T) {
+	settings := DefaultSettings()
+	settings.MinVariadic = true
+	analysistest.Run(t, analysistest.TestData(), NewAnalyzer(settings), "minvariadic")
+}
+
+func TestNewAnalyzer_Flags(t *testing.T) {
+	a := NewAnalyzer(Settings{Missing: true})
+	assert.Equal(t, "true", a.Flags.Lookup("missing").Value.String())
+	assert.Equal(t, "false", a.Flags.Lookup("shadow").Value.String())
+
+	// Setting the flags of one analyzer doesn't change others
+	require.NoError(t, a.Flags.Set("minvariadic", "true"))
+	assert.Equal(t, "false", Analyzer.Flags.Lookup("minvariadic").Value.String())
+	assert.Equal(t, "false", NewAnalyzer(DefaultSettings()).Flags.Lookup("minvariadic").Value.String())
+
+	// The flags apply to the checks, the missing
+	// wrap statements of the package are not reported
+	require.NoError(t, a.Flags.Set("missing", "false"))
+	analysistest.Run(t, analysistest.TestData(), a, "notmissing")
+}
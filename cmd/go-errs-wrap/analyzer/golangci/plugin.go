@@ -0,0 +1,80 @@
+// Package golangci registers the go-errs analyzer
+// as golangci-lint module plugin named goerrswrap.
+//
+// Import it in the .custom-gcl.yml of golangci-lint custom:
+//
+//	version: v2.5.0
+//	plugins:
+//	  - module: github.com/domonda/go-errs
+//	    import: github.com/domonda/go-errs/cmd/go-errs-wrap/analyzer/golangci
+//	    version: latest
+//
+// and enable it in .golangci.yml with the optional Settings:
+//
+//	linters:
+//	  enable:
+//	    - goerrswrap
+//	  settings:
+//	    custom:
+//	      goerrswrap:
+//	        type: module
+//	        settings:
+//	          shadow: false
+package golangci
+
+import (
+	"github.com/golangci/plugin-module-register/register"
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/domonda/go-errs"
+	"github.com/domonda/go-errs/cmd/go-errs-wrap/analyzer"
+)
+
+// Name of the linter in the golangci-lint configuration.
+const Name = "goerrswrap"
+
+func init() {
+	register.Plugin(Name, New)
+}
+
+// Settings of the linter, nil fields keep
+// the analyzer.DefaultSettings of the field with the JSON name.
+type Settings struct {
+	Missing     *bool `json:"missing"`
+	Shadow      *bool `json:"shadow"`
+	MinVariadic *bool `json:"minvariadic"`
+}
+
+type plugin struct {
+	settings Settings
+}
+
+// New returns the plugin for the settings
+// of the linter from the golangci-lint configuration.
+func New(settings any) (register.LinterPlugin, error) {
+	s, err := register.DecodeSettings[Settings](settings)
+	if err != nil {
+		return nil, errs.Errorf("%s settings: %w", Name, err)
+	}
+	return &plugin{settings: s}, nil
+}
+
+// BuildAnalyzers returns a new analyzer with the settings,
+// so plugins with different settings don't affect each other.
+func (p *plugin) BuildAnalyzers() ([]*analysis.Analyzer, error) {
+	settings := analyzer.DefaultSettings()
+	if p.settings.Missing != nil {
+		settings.Missing = *p.settings.Missing
+	}
+	if p.settings.Shadow != nil {
+		settings.Shadow = *p.settings.Shadow
+	}
+	if p.settings.MinVariadic != nil {
+		settings.MinVariadic = *p.settings.MinVariadic
+	}
+	return []*analysis.Analyzer{analyzer.NewAnalyzer(settings)}, nil
+}
+
+func (*plugin) GetLoadMode() string {
+	return register.LoadModeTypesInfo
+}
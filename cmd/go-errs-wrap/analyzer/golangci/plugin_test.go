@@ -0,0 +1,39 @@
+package golangci
+
+import (
+	"testing"
+
+	"github.com/golangci/plugin-module-register/register"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/domonda/go-errs/cmd/go-errs-wrap/analyzer"
+)
+
+func TestPlugin(t *testing.T) {
+	newPlugin, err := register.GetPlugin(Name)
+	require.NoError(t, err)
+	p, err := newPlugin(map[string]any{"shadow": false, "minvariadic": true})
+	require.NoError(t, err)
+	assert.Equal(t, register.LoadModeTypesInfo, p.GetLoadMode())
+
+	analyzers, err := p.BuildAnalyzers()
+	require.NoError(t, err)
+	require.Len(t, analyzers, 1)
+	flags := analyzers[0].Flags
+	assert.Equal(t, "true", flags.Lookup("missing").Value.String(), "default")
+	assert.Equal(t, "false", flags.Lookup("shadow").Value.String())
+	assert.Equal(t, "true", flags.Lookup("minvariadic").Value.String())
+
+	// Other plugins and the shared analyzer keep their settings
+	other, err := newPlugin(map[string]any{"missing": false})
+	require.NoError(t, err)
+	analyzers, err = other.BuildAnalyzers()
+	require.NoError(t, err)
+	assert.Equal(t, "false", analyzers[0].Flags.Lookup("missing").Value.String())
+	assert.Equal(t, "true", analyzers[0].Flags.Lookup("shadow").Value.String())
+	assert.Equal(t, "true", analyzer.Analyzer.Flags.Lookup("shadow").Value.String())
+
+	_, err = newPlugin(map[string]any{"unknown": true})
+	assert.ErrorContains(t, err, "goerrswrap settings")
+}
@@ -0,0 +1,312 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// checkShadowedResult reports variables declared in inner scopes of body
+// that shadow the named error result wrapped by a deferred errs.Wrap function.
+//
+// Values returned by a return statement are assigned to the named results
+// before deferred functions run, so return nil, otherErr is wrapped.
+// But an error assigned to a shadowing variable only reaches
+// the wrapped result if it is returned explicitly:
+//
+//	func Load(id string) (data []byte, err error) {
+//	    defer errs.WrapWith1FuncParam(&err, id)
+//
+//	    if cached {
+//	        data, err := cache.Get(id) // shadows err
+//	        if err == nil {
+//	            return data, nil
+//	        }
+//	    }
+//	    ...
+//	}
+//
+// Shadowing variables are not reported if their values can only
+// reach the named result, that is if they are returned and otherwise
+// only assigned or compared with nil:
+//
+//	if err := f(); err != nil {
+//	    return nil, err
+//	}
+//
+// If the shadowing declaration only declares the shadowing variable,
+// the suggested fix assigns to the named result instead.
+//
+// Naked returns in the body of an if statement checking another
+// error variable for nil are reported too, because the checked error
+// is not assigned to the named result:
+//
+//	if closeErr := f.Close(); closeErr != nil {
+//	    return // returns the named result err instead of closeErr
+//	}
+//
+// Their suggested fix returns the checked error explicitly.
+func checkShadowedResult(pass *analysis.Pass, funcType *ast.FuncType, body *ast.BlockStmt) {
+	stmt := deferredWrap(pass, body)
+	if stmt == nil {
+		return
+	}
+	result := wrappedResult(pass, stmt, funcType)
+	if result == nil {
+		return
+	}
+	fn := errsFunc(pass, stmt.Call)
+
+	// shadows returns the variable defined by ident
+	// if it shadows the result and its value
+	// doesn't always reach the result
+	shadows := func(ident *ast.Ident) *types.Var {
+		v, ok := pass.TypesInfo.Defs[ident].(*types.Var)
+		if !ok || v == result || v.Name() != result.Name() || isOnlyReturned(pass, body, v) {
+			return nil
+		}
+		return v
+	}
+	reported := make(map[*ast.ReturnStmt]bool)
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.FuncLit:
+			// Function literals have their own results
+			return false
+
+		case *ast.AssignStmt:
+			if node.Tok != token.DEFINE {
+				return true
+			}
+			var (
+				shadowing *ast.Ident
+				canAssign = true
+			)
+			for _, lhs := range node.Lhs {
+				ident, ok := lhs.(*ast.Ident)
+				if !ok {
+					continue
+				}
+				if v := shadows(ident); v != nil {
+					shadowing = ident
+					canAssign = canAssign && types.Identical(v.Type(), result.Type())
+				} else if ident.Name != "_" && pass.TypesInfo.Defs[ident] != nil {
+					// Declares another new variable
+					canAssign = false
+				}
+			}
+			if shadowing == nil {
+				return true
+			}
+			diag := shadowDiagnostic(shadowing, fn)
+			if canAssign {
+				diag.SuggestedFixes = []analysis.SuggestedFix{{
+					Message:   "Assign to the named result " + result.Name(),
+					TextEdits: []analysis.TextEdit{{Pos: node.TokPos, End: node.TokPos + token.Pos(len(":=")), NewText: []byte("=")}},
+				}}
+			}
+			pass.Report(diag)
+
+		case *ast.ValueSpec:
+			for _, ident := range node.Names {
+				if shadows(ident) != nil {
+					pass.Report(shadowDiagnostic(ident, fn))
+				}
+			}
+
+		case *ast.RangeStmt:
+			if node.Tok != token.DEFINE {
+				return true
+			}
+			for _, expr := range []ast.Expr{node.Key, node.Value} {
+				if ident, ok := expr.(*ast.Ident); ok && shadows(ident) != nil {
+					pass.Report(shadowDiagnostic(ident, fn))
+				}
+			}
+
+		case *ast.IfStmt:
+			checked := checkedNotNil(pass, node.Cond)
+			if checked == nil || checked == result || assignsTo(pass, node.Body, result) {
+				return true
+			}
+			ast.Inspect(node.Body, func(n ast.Node) bool {
+				switch ret := n.(type) {
+				case *ast.FuncLit:
+					return false
+				case *ast.ReturnStmt:
+					if len(ret.Results) == 0 && !reported[ret] {
+						reported[ret] = true
+						pass.Report(nakedReturnDiagnostic(ret, funcType, result, checked, fn))
+					}
+				}
+				return true
+			})
+
+		case *ast.TypeSwitchStmt:
+			// switch err := err.(type) declares implicit
+			// variables that are not in TypesInfo.Defs
+			if assign, ok := node.Assign.(*ast.AssignStmt); ok && len(assign.Lhs) == 1 {
+				if ident, ok := assign.Lhs[0].(*ast.Ident); ok && ident.Name == result.Name() {
+					pass.Report(shadowDiagnostic(ident, fn))
+				}
+			}
+		}
+		return true
+	})
+}
+
+func shadowDiagnostic(ident *ast.Ident, fn *types.Func) analysis.Diagnostic {
+	return analysis.Diagnostic{
+		Pos:     ident.Pos(),
+		End:     ident.End(),
+		Message: "declaration of " + ident.Name + " shadows the named result wrapped by the deferred errs." + fn.Name() + ", errors assigned to it are only wrapped if returned",
+	}
+}
+
+func nakedReturnDiagnostic(ret *ast.ReturnStmt, funcType *ast.FuncType, result, checked *types.Var, fn *types.Func) analysis.Diagnostic {
+	var results []string
+	for _, field := range funcType.Results.List {
+		for _, name := range field.Names {
+			if name.Name == result.Name() {
+				results = append(results, checked.Name())
+			} else {
+				results = append(results, name.Name)
+			}
+		}
+	}
+	return analysis.Diagnostic{
+		Pos:     ret.Pos(),
+		End:     ret.End(),
+		Message: "naked return after checking " + checked.Name() + " doesn't assign it to the named result wrapped by the deferred errs." + fn.Name(),
+		SuggestedFixes: []analysis.SuggestedFix{{
+			Message:   "Return " + checked.Name(),
+			TextEdits: []analysis.TextEdit{{Pos: ret.Pos(), End: ret.End(), NewText: []byte("return " + strings.Join(results, ", "))}},
+		}},
+	}
+}
+
+// isOnlyReturned reports whether the value of v can only leave body
+// by being returned, that is if v is used in a return statement
+// and otherwise only assigned or compared with nil.
+func isOnlyReturned(pass *analysis.Pass, body *ast.BlockStmt, v *types.Var) bool {
+	var (
+		returned bool
+		other    bool
+		stack    []ast.Node
+	)
+	ast.Inspect(body, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		if ident, ok := n.(*ast.Ident); ok && pass.TypesInfo.Uses[ident] == v {
+			switch {
+			case inReturn(stack):
+				returned = true
+			case isAssigned(ident, stack[len(stack)-1]) || isComparedWithNil(pass, ident, stack[len(stack)-1]):
+			default:
+				other = true
+			}
+		}
+		stack = append(stack, n)
+		return true
+	})
+	return returned && !other
+}
+
+// inReturn reports whether the innermost return statement or function literal
+// in stack is a return statement.
+func inReturn(stack []ast.Node) bool {
+	for i := len(stack) - 1; i >= 0; i-- {
+		switch stack[i].(type) {
+		case *ast.ReturnStmt:
+			return true
+		case *ast.FuncLit:
+			return false
+		}
+	}
+	return false
+}
+
+func isAssigned(ident *ast.Ident, parent ast.Node) bool {
+	assign, ok := parent.(*ast.AssignStmt)
+	return ok && slices.Contains(assign.Lhs, ast.Expr(ident))
+}
+
+func isComparedWithNil(pass *analysis.Pass, ident *ast.Ident, parent ast.Node) bool {
+	binary, ok := parent.(*ast.BinaryExpr)
+	if !ok || (binary.Op != token.EQL && binary.Op != token.NEQ) {
+		return false
+	}
+	other := binary.X
+	if other == ident {
+		other = binary.Y
+	}
+	return pass.TypesInfo.Types[other].IsNil()
+}
+
+// checkedNotNil returns the error variable compared with != nil
+// by cond or one of the operands of && in cond, or nil.
+func checkedNotNil(pass *analysis.Pass, cond ast.Expr) *types.Var {
+	binary, ok := ast.Unparen(cond).(*ast.BinaryExpr)
+	if !ok {
+		return nil
+	}
+	switch binary.Op {
+	case token.LAND:
+		if v := checkedNotNil(pass, binary.X); v != nil {
+			return v
+		}
+		return checkedNotNil(pass, binary.Y)
+	case token.NEQ:
+		for _, operand := range []ast.Expr{binary.X, binary.Y} {
+			ident, ok := ast.Unparen(operand).(*ast.Ident)
+			if !ok || !isComparedWithNil(pass, ident, binary) {
+				continue
+			}
+			if v, ok := pass.TypesInfo.Uses[ident].(*types.Var); ok && types.Identical(v.Type(), errorType) {
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+// assignsTo reports whether v is assigned in node
+// outside of function literals.
+func assignsTo(pass *analysis.Pass, node ast.Node, v *types.Var) (found bool) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				if ident, ok := ast.Unparen(lhs).(*ast.Ident); ok && pass.TypesInfo.Uses[ident] == v {
+					found = true
+				}
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// wrappedResult returns the named result of funcType whose address
+// is passed to the deferred call of stmt, or nil.
+func wrappedResult(pass *analysis.Pass, stmt *ast.DeferStmt, funcType *ast.FuncType) *types.Var {
+	for _, arg := range stmt.Call.Args {
+		if !isNamedResultRef(pass, arg, funcType) {
+			continue
+		}
+		ident := ast.Unparen(arg.(*ast.UnaryExpr).X).(*ast.Ident)
+		if v, ok := pass.TypesInfo.Uses[ident].(*types.Var); ok && types.Identical(v.Type(), errorType) {
+			return v
+		}
+	}
+	return nil
+}
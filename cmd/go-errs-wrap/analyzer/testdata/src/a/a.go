@@ -0,0 +1,145 @@
+package a
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/domonda/go-errs"
+)
+
+func Wrapped(ctx context.Context, id string) (err error) {
+	defer errs.WrapWith2FuncParams(&err, ctx, id)
+
+	return nil
+}
+
+func WrappedVariadic(ctx context.Context, id string) (err error) {
+	defer errs.WrapWithFuncParams(&err, ctx, id)
+
+	return nil
+}
+
+func WrappedSecret(user, password string) (err error) {
+	defer errs.WrapWith2FuncParams(&err, user, errs.KeepSecret(password))
+
+	return nil
+}
+
+func WrappedWithContext(ctx context.Context, id string) (err error) {
+	defer errs.WrapWithContext(ctx, &err, id)
+
+	return nil
+}
+
+func Unnamed(id string) error {
+	return nil
+}
+
+func Missing(id string) (err error) { // want `Missing is missing defer errs.WrapWith1FuncParam\(&err, id\)`
+	return nil
+}
+
+func MissingNoParams() (err error) { // want `MissingNoParams is missing defer errs.WrapWith0FuncParams\(&err\)`
+	return nil
+}
+
+func MissingInFuncLit() {
+	_ = func(id int) (err error) { // want `anonymous function is missing defer errs.WrapWith1FuncParam\(&err, id\)`
+		return nil
+	}
+}
+
+func Stale(ctx context.Context, id string, n int) (err error) {
+	defer errs.WrapWith2FuncParams(&err, ctx, id) // want `stale error wrapper, expected defer errs.WrapWith3FuncParams\(&err, ctx, id, n\)`
+
+	return nil
+}
+
+func StaleVariadic(id string, n int) (err error) {
+	defer errs.WrapWithFuncParams(&err, id) // want `stale error wrapper, expected defer errs.WrapWithFuncParams\(&err, id, n\)`
+
+	return nil
+}
+
+func WrongOrder(ctx context.Context, id string) (err error) {
+	defer errs.WrapWith2FuncParams(&err, id, ctx) // want `function parameters passed to errs.WrapWith2FuncParams are not in the order of their declaration`
+
+	return nil
+}
+
+func WrongOrderSecret(user, password string) (err error) {
+	defer errs.WrapWith2FuncParams(&err, errs.KeepSecret(password), user) // want `function parameters passed to errs.WrapWith2FuncParams are not in the order of their declaration`
+
+	return nil
+}
+
+func DiscardedResult(id string) (err error) {
+	defer errs.WrapWithCallStack(err) // want `result of deferred errs.WrapWithCallStack is discarded`
+
+	return nil
+}
+
+func Shadowed(id string) (err error) {
+	defer errs.WrapWith1FuncParam(&err, id)
+
+	if id == "" {
+		err := fmt.Errorf("empty id")           // want `declaration of err shadows the named result`
+		defer errs.WrapWith1FuncParam(&err, id) // want `errs.WrapWith1FuncParam wraps err which is not a named result of the function`
+		return err
+	}
+	return nil
+}
+
+func NotResult(id string) error {
+	var err error
+	defer errs.WrapWith1FuncParam(&err, id) // want `errs.WrapWith1FuncParam wraps err which is not a named result of the function`
+	return err
+}
+
+func PointerVar(id string) (err error) {
+	errPtr := &err
+	defer errs.WrapWith1FuncParam(errPtr, id) // want `errs.WrapWith1FuncParam must be passed the address of a named error result`
+	return nil
+}
+
+func Recovered() (err error) {
+	defer errs.WrapWith0FuncParams(&err)
+	defer errs.RecoverPanicAsError(&err)
+
+	return nil
+}
+
+var ErrVar errs.Sentinel = "var" // want `errs.Sentinel ErrVar declared as var`
+
+var errUnexported errs.Sentinel = "unexported" // want `errs.Sentinel errUnexported declared as var`
+
+var errReassigned errs.Sentinel = "reassigned" // want `errs.Sentinel errReassigned declared as var`
+
+var errAddressTaken errs.Sentinel = "address taken" // want `errs.Sentinel errAddressTaken declared as var`
+
+func useSentinels() (error, *errs.Sentinel) {
+	errReassigned = "changed"
+	return errUnexported, &errAddressTaken
+}
+
+var ( // Fix only if all can be const
+	ErrConverted = errs.Sentinel("converted")            // want `errs.Sentinel ErrConverted declared as var`
+	ErrComputed  = errs.Sentinel(fmt.Sprint("computed")) // want `errs.Sentinel ErrComputed declared as var`
+)
+
+const ErrConst errs.Sentinel = "const"
+
+var ErrInterface error = errs.Sentinel("interface")
+
+func NewSprintf(id string) error {
+	return errs.New(fmt.Sprintf("not found: %s", id)) // want `use errs.Errorf\(...\) instead of errs.New\(fmt.Sprintf\(...\)\)`
+}
+
+func NewSprintfVariadic(args []any) error {
+	return errs.New(fmt.Sprintf("%v", args...))
+}
+
+func localSentinel() {
+	var err errs.Sentinel = "local"
+	_ = err
+}
@@ -0,0 +1,5 @@
+package a
+
+func missingInTest() (err error) {
+	return nil
+}
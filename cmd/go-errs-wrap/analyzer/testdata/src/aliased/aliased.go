@@ -0,0 +1,21 @@
+package aliased
+
+import (
+	"fmt"
+
+	goerrs "github.com/domonda/go-errs"
+)
+
+func Missing(id string) (err error) { // want `Missing is missing defer goerrs.WrapWith1FuncParam\(&err, id\)`
+	return nil
+}
+
+func Stale(id string, n int) (err error) {
+	defer goerrs.WrapWith1FuncParam(&err, id) // want `stale error wrapper, expected defer goerrs.WrapWith2FuncParams\(&err, id, n\)`
+
+	return nil
+}
+
+func NewSprintf(id string) error {
+	return goerrs.New(fmt.Sprintf("not found: %s", id)) // want `use errs.Errorf`
+}
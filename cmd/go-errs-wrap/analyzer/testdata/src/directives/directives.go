@@ -0,0 +1,46 @@
+package directives
+
+import "github.com/domonda/go-errs"
+
+// go-errs-wrap:ignore hot path
+func Ignored(id string) (err error) {
+	f := func(n int) (err error) {
+		return nil
+	}
+	return f(0)
+}
+
+// go-errs-wrap:ignore
+func IgnoredStale(id string, n int) (err error) {
+	defer errs.WrapWith1FuncParam(&err, id)
+
+	return nil
+}
+
+// Restricted passes only id.
+//
+// go-errs-wrap:params id
+func Restricted(id string, data []byte) (err error) {
+	defer errs.WrapWith1FuncParam(&err, id)
+
+	return nil
+}
+
+// go-errs-wrap:params id,n
+func RestrictedStale(id string, data []byte, n int) (err error) {
+	defer errs.WrapWith1FuncParam(&err, id) // want `stale error wrapper, expected defer errs.WrapWith2FuncParams\(&err, id, n\)`
+
+	return nil
+}
+
+// go-errs-wrap:params id
+func RestrictedMissing(id string, data []byte) (err error) { // want `RestrictedMissing is missing defer errs.WrapWith1FuncParam\(&err, id\)`
+	return nil
+}
+
+// go-errs-wrap:params id,size
+func Unknown(id string) (err error) { // want `//go-errs-wrap:params of Unknown lists unknown parameter size`
+	defer errs.WrapWith1FuncParam(&err, id)
+
+	return nil
+}
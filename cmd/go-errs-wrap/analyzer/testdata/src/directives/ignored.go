@@ -0,0 +1,7 @@
+//go-errs-wrap:ignore generated by hand
+
+package directives
+
+func IgnoredFile(id string) (err error) {
+	return nil
+}
@@ -0,0 +1,20 @@
+// Package errs is a stub of github.com/domonda/go-errs for analyzer tests.
+package errs
+
+import "context"
+
+type Sentinel string
+
+func (s Sentinel) Error() string { return string(s) }
+
+func New(text string) error                                                { return Sentinel(text) }
+func Errorf(format string, a ...any) error                                 { return Sentinel(format) }
+func KeepSecret(val any) any                                               { return val }
+func WrapWithCallStack(err error) error                                    { return err }
+func WrapWithFuncParams(resultVar *error, params ...any)                   {}
+func WrapWith0FuncParams(resultVar *error)                                 {}
+func WrapWith1FuncParam(resultVar *error, p0 any)                          {}
+func WrapWith2FuncParams(resultVar *error, p0, p1 any)                     {}
+func WrapWith3FuncParams(resultVar *error, p0, p1, p2 any)                 {}
+func WrapWithContext(ctx context.Context, resultVar *error, params ...any) {}
+func RecoverPanicAsError(resultVar *error)                                 {}
@@ -0,0 +1,9 @@
+package minvariadic
+
+import "github.com/domonda/go-errs"
+
+func Variadic(id string) (err error) {
+	defer errs.WrapWithFuncParams(&err, id) // want `stale error wrapper, expected defer errs.WrapWith1FuncParam\(&err, id\)`
+
+	return nil
+}
@@ -0,0 +1,5 @@
+package noimport
+
+func Missing(id string) (err error) { // want `Missing is missing defer errs.WrapWith1FuncParam\(&err, id\)`
+	return nil
+}
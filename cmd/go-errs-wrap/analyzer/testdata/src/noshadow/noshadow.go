@@ -0,0 +1,13 @@
+package noshadow
+
+import "github.com/domonda/go-errs"
+
+func Shadowed(id string) (err error) {
+	defer errs.WrapWith1FuncParam(&err, id)
+
+	if id != "" {
+		err := errs.New(id)
+		_ = err
+	}
+	return nil
+}
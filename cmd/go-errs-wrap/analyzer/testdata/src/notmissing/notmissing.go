@@ -0,0 +1,13 @@
+package notmissing
+
+import "github.com/domonda/go-errs"
+
+func NotWrapped(id string) (err error) {
+	return nil
+}
+
+func Stale(id string, n int) (err error) {
+	defer errs.WrapWith1FuncParam(&err, id) // want `stale error wrapper`
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package shadow
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/domonda/go-errs"
+)
+
+func load(id string) ([]byte, error) { return nil, nil }
+
+func Shadowed(id string, cached bool) (data []byte, err error) {
+	defer errs.WrapWith2FuncParams(&err, id, cached)
+
+	if cached {
+		data, err := load(id) // want `declaration of err shadows the named result wrapped by the deferred errs.WrapWith2FuncParams`
+		if err == nil {
+			return data, nil
+		}
+	}
+	return load(id)
+}
+
+func ShadowedOnlyErr(id string) (err error) {
+	defer errs.WrapWith1FuncParam(&err, id)
+
+	if id != "" {
+		_, err := load(id) // want `declaration of err shadows`
+		_ = err
+	}
+	if _, err := strconv.Atoi(id); err != nil { // want `declaration of err shadows`
+		return nil
+	}
+	return nil
+}
+
+func ShadowedVar(id string) (err error) {
+	defer errs.WrapWith1FuncParam(&err, id)
+
+	if id == "" {
+		var err error // want `declaration of err shadows`
+		_ = err
+	}
+	return nil
+}
+
+func ShadowedRange(all []error) (err error) {
+	defer errs.WrapWith1FuncParam(&err, all)
+
+	for _, err := range all { // want `declaration of err shadows`
+		_ = err
+	}
+	return nil
+}
+
+func ShadowedTypeSwitch(in error) (err error) {
+	defer errs.WrapWith1FuncParam(&err, in)
+
+	switch err := in.(type) { // want `declaration of err shadows`
+	case interface{ Timeout() bool }:
+		_ = err
+	}
+	return nil
+}
+
+func ShadowedOtherType(id string) (err error) {
+	defer errs.WrapWith1FuncParam(&err, id)
+
+	{
+		err := 1 // want `declaration of err shadows`
+		_ = err
+	}
+	return nil
+}
+
+func CustomResultName(id string) (data []byte, loadErr error) {
+	defer errs.WrapWith1FuncParam(&loadErr, id)
+
+	if id != "" {
+		data, loadErr := load(id) // want `declaration of loadErr shadows`
+		_, _ = data, loadErr
+	}
+	var err error // other name doesn't shadow
+	return nil, err
+}
+
+func FuncLit(id string) (err error) {
+	defer errs.WrapWith1FuncParam(&err, id)
+
+	f := func() error {
+		_, err := load(id) // own scope of the function literal
+		return err
+	}
+	return f()
+}
+
+func ReturnedShadow(id string) (data []byte, err error) {
+	defer errs.WrapWith1FuncParam(&err, id)
+
+	if data, err := load(id); err != nil { // returned, so wrapped
+		return nil, err
+	} else if len(data) > 0 {
+		return data, nil
+	}
+	for _, err := range []error{nil} { // returned, so wrapped
+		if err == nil {
+			continue
+		}
+		return nil, err
+	}
+	var err2 error
+	if err := errors.New(id); err != nil {
+		return nil, errors.Join(err, err2)
+	}
+	return nil, nil
+}
+
+func NakedReturn(id string) (data []byte, err error) {
+	defer errs.WrapWith1FuncParam(&err, id)
+
+	data, loadErr := load(id)
+	if loadErr != nil && id != "" {
+		return // want `naked return after checking loadErr doesn't assign it to the named result wrapped by the deferred errs.WrapWith1FuncParam`
+	}
+	if loadErr != nil {
+		err = loadErr
+		return
+	}
+	return
+}
+
+func NotWrapped(id string) (err error) { // want `NotWrapped is missing`
+	if id != "" {
+		_, err := load(id)
+		return err
+	}
+	return errors.New("empty")
+}
@@ -6,6 +6,55 @@ marker comments and can remove them, replace them with properly generated
 defer errs.WrapWithFuncParams statements, or insert new wrap statements into
 functions that don't have them yet.
 
+The path argument is a Go file or a package pattern like ./pkg/...
+matched like by go list. Packages are loaded with golang.org/x/tools/go/packages
+and type-checked, so that go-errs is recognized if it is dot-imported or
+vendored, error results declared with an alias of error are wrapped,
+and parameters implementing errs.Secret are not wrapped with errs.KeepSecret.
+Files excluded by build constraints are processed without type information,
+test files and generated files are skipped.
+
+# Configuration
+
+Project settings are read from a .go-errs-wrap.yaml file in the directory
+of the path argument or its parent directories, see rewrite.Config:
+
+	# Files and directories relative to the configuration file
+	include: [cmd, pkg]
+	exclude: [internal/generated, "*_mock.go"]
+	skip:
+	  packages: [example.com/project/legacy/...]
+	  functions: [Close, "*.ServeHTTP"]
+	minvariadic: true
+	tests: false
+	generated: false
+	keepsecret:
+	  names: [password, token]
+	  types: [example.com/project/auth.Credentials]
+	  tagged: true
+	overrides:
+	  - dir: internal/hot
+	    skip:
+	      functions: ["*"]
+
+Options enable settings in addition to the configuration,
+-keepsecret and the -secret* options replace its keepsecret rules.
+
+# Directives
+
+Comments in the source control single functions and files:
+
+	//go-errs-wrap:ignore [reason]
+	    In the doc comment of a function: the function and the function
+	    literals within are not changed or reported by any command.
+	    In a comment before the package clause: the file is ignored.
+	//go-errs-wrap:params a,b
+	    In the doc comment of a function: generated wrap statements
+	    pass only the listed parameters.
+
+gofmt formats them as // go-errs-wrap:ignore in doc comments,
+both spellings are recognized.
+
 # Usage
 
 	go-errs-wrap <command> [options] <path>
@@ -16,23 +65,63 @@ functions that don't have them yet.
 	replace  Replace defer errs.Wrap or //#wrap-result-err with generated code
 	insert   Insert defer errs.Wrap at the first line of functions with named
 	         error results that don't already have one (followed by empty line)
+	stats    Report per package and in total how many functions return errors,
+	         have a deferred errs.Wrap, are missing or stale, how many use the
+	         variadic or WrapWithNFuncParams, and how many parameters are
+	         wrapped with errs.KeepSecret (alias: coverage)
+	migrate  Rewrite github.com/pkg/errors usages to go-errs: New, Errorf,
+	         Wrap, Wrapf, WithMessage, WithStack and Cause, with -fmterrorf
+	         also fmt.Errorf wrapping with %w, and fix the imports
+	sentinels
+	         Convert package level var ErrX = errors.New("...") declarations
+	         to const ErrX errs.Sentinel = "..." if no code of the loaded
+	         packages assigns them, takes their address or relies on their type.
+	         No file is changed if the converted packages don't type-check
 
 # Options
 
 	-out <path>   Output to different location instead of modifying source
+	-config <file>
+	              Configuration file instead of .go-errs-wrap.yaml
+	              found in the directory of the path or its parents
+	-noconfig     Don't read a configuration file
+	-nameresults  Name anonymous results of functions returning an error
+	              as (_ T, err error) so that insert and replace can wrap them
+	-errname      Name for the error result used by -nameresults (default "err"),
+	              a variation like errResult is used if the name is taken
+	-keepsecret   Wrap sensitive parameters with errs.KeepSecret: parameters named
+	              like errs.DefaultRedactionFieldNames or key and private keys
+	              of the crypto packages
+	-secretnames  Comma separated parameter name patterns instead of the
+	              defaults of -keepsecret, implies -keepsecret
+	-secrettypes  Comma separated qualified type names like crypto/rsa.PrivateKey
+	              instead of the defaults of -keepsecret, implies -keepsecret
 	-validate     Dry run mode: check for issues without modifying files. Reports issues
 	              to stderr and exits with error code 1 if any are found. Useful for CI
 	              validation to ensure code quality standards are met.
 	              - remove: checks if any defer errs.Wrap statements exist
 	              - replace: checks if any defer errs.Wrap statements need updating
 	              - insert: checks if any functions are missing defer errs.Wrap
+	              - migrate: checks if github.com/pkg/errors is still used
+	              - sentinels: checks for error variables that can be constants
+	              With -keepsecret sensitive parameters passed without
+	              errs.KeepSecret are reported too.
+	-diff         Print the unified diff of every file that would change
+	              instead of modifying files, can be combined with -validate
+	-format       Report format of -validate: text (default) to stderr,
+	              json or sarif to stdout with file, line, column, rule and
+	              replacement of every finding, implies -validate.
+	              stats writes text (default) or json to stdout
+	-threshold    Minimum coverage percentage of stats, exit code 1 below it
+	-fmterrorf    Also rewrite fmt.Errorf calls with a %w verb to errs.Errorf
+	              with migrate
 	-verbose      Print progress information
 	-help         Show help message
 
 # Exit Codes
 
 	0   Success (no issues found in validate mode, or operation completed successfully)
-	1   Error occurred (validation failed, file not found, invalid arguments, etc.)
+	1   Error occurred (validation failed, coverage below -threshold, file not found, etc.)
 
 # Examples
 
@@ -48,6 +137,15 @@ Insert wrap statements into functions missing them:
 
 	go-errs-wrap insert ./pkg/mypackage/file.go
 
+Insert wrap statements also into functions with anonymous results:
+
+	go-errs-wrap insert -nameresults ./pkg/...
+
+Wrap sensitive parameters with errs.KeepSecret and check it in CI:
+
+	go-errs-wrap replace -keepsecret -secrettypes example.com/auth.Credentials ./...
+	go-errs-wrap insert -validate -keepsecret ./...
+
 Replace and output to a different location:
 
 	go-errs-wrap replace -out ./output ./pkg/mypackage
@@ -63,6 +161,30 @@ Validate that all replacements are present (for CI):
 Validate that all functions have error wrappers (for CI):
 
 	go-errs-wrap insert -validate ./pkg/...
+
+Review the changes of replace as diff:
+
+	go-errs-wrap replace -diff ./...
+
+Report missing wrap statements as SARIF for GitHub code scanning:
+
+	go-errs-wrap insert -format sarif ./... > go-errs-wrap.sarif
+
+Migrate from github.com/pkg/errors and fmt.Errorf to go-errs:
+
+	go-errs-wrap migrate -fmterrorf ./...
+
+Convert sentinel error variables of all packages of the module to constants:
+
+	go-errs-wrap sentinels ./...
+
+Fail CI if less than 90% of the functions returning errors are wrapped:
+
+	go-errs-wrap stats -threshold 90 ./...
+
+Validate with the settings of a configuration file outside of the project:
+
+	go-errs-wrap insert -validate -config ci/go-errs-wrap.yaml ./...
 */
 package main
 
@@ -79,9 +201,20 @@ import (
 
 var (
 	outPath     string
+	configPath  string
+	noConfig    bool
 	verbose     bool
 	minVariadic bool
+	nameResults bool
+	errName     string
+	keepSecret  bool
+	secretNames string
+	secretTypes string
 	validate    bool
+	diff        bool
+	format      string
+	threshold   float64
+	fmtErrorf   bool
 	printHelp   bool
 )
 
@@ -104,9 +237,20 @@ func main() {
 	// Create a new FlagSet for parsing flags after the command
 	fs := flag.NewFlagSet(command, flag.ExitOnError)
 	fs.StringVar(&outPath, "out", "", "output to different location instead of modifying source")
+	fs.StringVar(&configPath, "config", "", "configuration file instead of "+rewrite.ConfigFileName)
+	fs.BoolVar(&noConfig, "noconfig", false, "don't read a configuration file")
 	fs.BoolVar(&verbose, "verbose", false, "print progress information")
 	fs.BoolVar(&minVariadic, "minvariadic", false, "minimize use of variadic WrapWithFuncParams")
+	fs.BoolVar(&nameResults, "nameresults", false, "name anonymous results of functions returning an error")
+	fs.StringVar(&errName, "errname", "err", "name for the error result used by -nameresults")
+	fs.BoolVar(&keepSecret, "keepsecret", false, "wrap sensitive parameters with errs.KeepSecret")
+	fs.StringVar(&secretNames, "secretnames", "", "comma separated parameter name patterns for -keepsecret")
+	fs.StringVar(&secretTypes, "secrettypes", "", "comma separated qualified type names for -keepsecret")
 	fs.BoolVar(&validate, "validate", false, "check for issues without modifying files")
+	fs.BoolVar(&diff, "diff", false, "print unified diffs instead of modifying files")
+	fs.StringVar(&format, "format", "text", "output format of -validate and stats: text, json or sarif")
+	fs.Float64Var(&threshold, "threshold", 0, "minimum coverage percentage of stats")
+	fs.BoolVar(&fmtErrorf, "fmterrorf", false, "also rewrite fmt.Errorf with %w to errs.Errorf with migrate")
 	fs.BoolVar(&printHelp, "help", false, "show help message")
 	fs.Parse(os.Args[2:]) // #nosec G104 -- using ExitOnError mode, Parse will exit on error
 
@@ -122,41 +266,162 @@ func main() {
 		os.Exit(1)
 	}
 
+	// File path or package pattern like "./..." with go list semantics
 	sourcePath := args[0]
 
-	// Check if user passed a "..." pattern (e.g., "./..." or "./pkg/...")
-	recursive := false
-	if before, ok := strings.CutSuffix(sourcePath, "/..."); ok {
-		sourcePath = before
-		recursive = true
-	} else if sourcePath == "..." {
-		sourcePath = "."
-		recursive = true
+	statsCommand := command == "stats" || command == "coverage"
+	switch format {
+	case "text":
+	case "json", "sarif":
+		if statsCommand {
+			if format == "sarif" {
+				fmt.Fprintln(os.Stderr, "error: stats supports -format text or json")
+				os.Exit(1)
+			}
+			break
+		}
+		if diff {
+			fmt.Fprintf(os.Stderr, "error: -diff can't be combined with -format %s\n", format) // #nosec G705 -- CLI stderr output, not HTTP response
+			os.Exit(1)
+		}
+		validate = true
+	default:
+		fmt.Fprintf(os.Stderr, "error: unknown format %q\n", format) // #nosec G705 -- CLI stderr output, not HTTP response
+		printUsage()
+		os.Exit(1)
+	}
+
+	var diffOut io.Writer
+	if diff {
+		diffOut = os.Stdout
 	}
 
 	var verboseOut io.Writer
 	if verbose {
+		// Keep stdout parsable for diffs and reports
 		verboseOut = os.Stdout
+		if diff || format != "text" {
+			verboseOut = os.Stderr
+		}
+	}
+
+	var resultName string
+	if nameResults {
+		resultName = errName
 	}
 
-	var err error
+	var secrets *rewrite.SecretPolicy
+	if keepSecret || secretNames != "" || secretTypes != "" {
+		secrets = rewrite.DefaultSecretPolicy()
+		if secretNames != "" {
+			secrets.Names = strings.Split(secretNames, ",")
+		}
+		if secretTypes != "" {
+			secrets.Types = strings.Split(secretTypes, ",")
+		}
+	}
+
+	var (
+		config *rewrite.Config
+		err    error
+	)
+	switch {
+	case noConfig:
+	case configPath != "":
+		config, err = rewrite.LoadConfig(configPath)
+	default:
+		config, err = rewrite.FindConfig(sourcePath)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", errs.UnwrapCallStack(err))
+		os.Exit(1)
+	}
+	if config != nil && verboseOut != nil {
+		fmt.Fprintf(verboseOut, "using configuration: %s\n", config.File())
+	}
+
+	options := rewrite.Options{
+		OutPath:     outPath,
+		MinVariadic: minVariadic,
+		NameResults: resultName,
+		Secrets:     secrets,
+		FmtErrorf:   fmtErrorf,
+		Validate:    validate,
+		DiffOut:     diffOut,
+		Config:      config,
+		VerboseOut:  verboseOut,
+	}
 	switch command {
 	case "remove":
-		err = rewrite.Remove(sourcePath, outPath, recursive, validate, verboseOut)
+		err = rewrite.Remove(sourcePath, options)
 	case "replace":
-		err = rewrite.Replace(sourcePath, outPath, recursive, minVariadic, validate, verboseOut)
+		err = rewrite.Replace(sourcePath, options)
 	case "insert":
-		err = rewrite.Insert(sourcePath, outPath, recursive, minVariadic, validate, verboseOut)
+		err = rewrite.Insert(sourcePath, options)
+	case "migrate":
+		err = rewrite.Migrate(sourcePath, options)
+	case "sentinels":
+		err = rewrite.ConvertSentinels(sourcePath, options)
+	case "stats", "coverage":
+		err = stats(sourcePath, options)
 	default:
 		fmt.Fprintf(os.Stderr, "error: unknown command %q\n", command) // #nosec G705 -- CLI stderr output, not HTTP response
 		printUsage()
 		os.Exit(1)
 	}
 
+	var findings []rewrite.Finding
+	if validationErrs := errs.As[*rewrite.ValidationError](err); len(validationErrs) > 0 {
+		findings = validationErrs[0].Findings
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", errs.UnwrapCallStack(err))
+		os.Exit(1)
+	}
+
+	if statsCommand {
+		return // The report was printed by stats
+	}
+
+	// Report findings, json and sarif also without findings
+	switch format {
+	case "json":
+		err = rewrite.WriteJSON(os.Stdout, findings)
+	case "sarif":
+		err = rewrite.WriteSARIF(os.Stdout, findings)
+	default:
+		for _, finding := range findings {
+			fmt.Fprintln(os.Stderr, finding)
+		}
+	}
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "error:", errs.UnwrapCallStack(err))
 		os.Exit(1)
 	}
+	if len(findings) > 0 {
+		fmt.Fprintln(os.Stderr, "error:", &rewrite.ValidationError{Findings: findings})
+		os.Exit(1)
+	}
+}
+
+// stats prints the error wrapping statistics of sourcePath
+// and returns an error if the coverage is below the threshold.
+func stats(sourcePath string, options rewrite.Options) error {
+	report, err := rewrite.CollectStats(sourcePath, options)
+	if err != nil {
+		return err
+	}
+	if format == "json" {
+		err = report.WriteJSON(os.Stdout)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		return err
+	}
+	if report.Total.Coverage < threshold {
+		return errs.Errorf("coverage %.1f%% is below the threshold of %g%%", report.Total.Coverage, threshold)
+	}
+	return nil
 }
 
 func printUsage() {
@@ -170,39 +435,105 @@ Commands:
   replace  Replace defer errs.Wrap or //#wrap-result-err with generated code
   insert   Insert defer errs.Wrap at the first line of functions with named
            error results that don't already have one (followed by empty line)
+  stats    Report the error wrapping coverage per package and in total:
+           functions returning errors, wrapped, missing, stale, variadic vs.
+           WrapWithNFuncParams and KeepSecret parameters (alias: coverage)
+  migrate  Rewrite github.com/pkg/errors usages to go-errs: New, Errorf,
+           Wrap, Wrapf, WithMessage, WithStack and Cause, with -fmterrorf
+           also fmt.Errorf wrapping with %w, and fix the imports
+  sentinels
+           Convert package level var ErrX = errors.New("...") declarations
+           to const ErrX errs.Sentinel = "..." if no code of the loaded
+           packages assigns them, takes their address or relies on their type.
+           No file is changed if the converted packages don't type-check
 
 Arguments:
-  path     Source directory or file path
-           - If directory: recursively process all *.go files (excluding *_test.go)
+  path     Source file, directory or package pattern like ./pkg/...
+           - Patterns are matched like by go list, test files and
+             generated files are skipped unless enabled by the configuration
            - If file: process only that file
+           - Packages are type-checked to resolve the go-errs import
+             and the types of parameters and results
 
 Options:
   -out <path>     Output to different location instead of modifying source
                   - If source is directory: create copy of directory structure
                   - If source is file: write to specified file path
                   - Non-Go files are copied unchanged
+  -config <file>  Configuration file instead of .go-errs-wrap.yaml found
+                  in the directory of the path or its parent directories
+  -noconfig       Don't read a configuration file
   -validate       Dry run mode: check for issues without modifying files. Reports issues
                   to stderr and exits with error code 1 if any are found. Useful for CI
                   validation to ensure code quality standards are met.
                   - remove: checks if any defer errs.Wrap statements exist
                   - replace: checks if any defer errs.Wrap statements need updating
                   - insert: checks if any functions are missing defer errs.Wrap
+                  - migrate: checks if github.com/pkg/errors is still used
+                  - sentinels: checks for error variables that can be constants
+                  With -keepsecret sensitive parameters passed without
+                  errs.KeepSecret are reported too.
                   Note: -out option is ignored when -validate is used.
+  -diff           Print the unified diff of every file that would change
+                  instead of modifying files, can be combined with -validate
+  -format <format>
+                  Report format of -validate: text (default) to stderr,
+                  json or sarif to stdout with file, line, column, rule and
+                  replacement of every finding, implies -validate.
+                  stats writes text (default) or json to stdout
+  -threshold <percent>
+                  Minimum coverage percentage of stats, exit code 1 below it
+  -fmterrorf      Also rewrite fmt.Errorf calls with a %w verb to errs.Errorf
+                  with migrate
   -minvariadic    Use specialized WrapWithNFuncParams functions instead of
                   preserving existing variadic WrapWithFuncParams calls
+  -nameresults    Name anonymous results of functions returning an error
+                  as (_ T, err error) so that insert and replace can wrap them
+  -errname <name> Name for the error result used by -nameresults (default "err"),
+                  a variation like errResult is used if the name is taken
+  -keepsecret     Wrap sensitive parameters with errs.KeepSecret: parameters named
+                  like errs.DefaultRedactionFieldNames or key and private keys
+                  of the crypto packages
+  -secretnames <list>
+                  Comma separated parameter name patterns instead of the
+                  defaults of -keepsecret, implies -keepsecret
+  -secrettypes <list>
+                  Comma separated qualified type names like crypto/rsa.PrivateKey
+                  instead of the defaults of -keepsecret, implies -keepsecret
   -verbose        Print progress information
   -help           Show help message
 
 Exit Codes:
   0   Success (no issues found in validate mode, or operation completed successfully)
-  1   Error occurred (validation failed, file not found, invalid arguments, etc.)
+  1   Error occurred (validation failed, coverage below -threshold, file not found, etc.)
 
 Examples:
   go-errs-wrap remove ./pkg/...
   go-errs-wrap replace ./pkg/mypackage/file.go
   go-errs-wrap insert ./pkg/mypackage/file.go
+  go-errs-wrap insert -nameresults ./pkg/...
+  go-errs-wrap insert -validate -keepsecret ./...
   go-errs-wrap replace -out ./output ./pkg/mypackage
   go-errs-wrap remove -validate ./pkg/...
   go-errs-wrap replace -validate ./pkg/...
-  go-errs-wrap insert -validate ./pkg/...`)
+  go-errs-wrap insert -validate ./pkg/...
+  go-errs-wrap replace -diff ./...
+  go-errs-wrap insert -format sarif ./... > go-errs-wrap.sarif
+  go-errs-wrap stats -threshold 90 ./...
+  go-errs-wrap migrate -fmterrorf ./...
+  go-errs-wrap sentinels ./...
+  go-errs-wrap insert -validate -config ci/go-errs-wrap.yaml ./...
+
+Configuration:
+  A .go-errs-wrap.yaml file can declare include/exclude globs, skipped
+  packages and functions, minvariadic, keepsecret rules, whether test
+  and generated files are processed, and overrides per directory.
+  Options enable settings in addition to the configuration,
+  -keepsecret and the -secret* options replace its keepsecret rules.
+
+Directives:
+  //go-errs-wrap:ignore      in the doc comment of a function or before the
+                             package clause ignores the function or file
+  //go-errs-wrap:params a,b  in the doc comment of a function passes only
+                             the listed parameters to the wrap statement`)
 }
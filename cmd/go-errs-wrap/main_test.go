@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/domonda/go-errs/cmd/go-errs-wrap/rewrite"
+)
+
+// TestMain runs main instead of the tests
+// if the test binary was started by runMain.
+func TestMain(m *testing.M) {
+	if os.Getenv("GO_ERRS_WRAP_RUN_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runMain runs the command with args in a sub-process
+// and returns its stdout, stderr and exit code.
+func runMain(t *testing.T, args ...string) (stdout, stderr string, exitCode int) {
+	t.Helper()
+
+	cmd := exec.Command(os.Args[0], args...) // #nosec G204 -- the test binary itself
+	cmd.Env = append(os.Environ(), "GO_ERRS_WRAP_RUN_MAIN=1")
+	var outBuf, errBuf bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &outBuf, &errBuf
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return outBuf.String(), errBuf.String(), exitErr.ExitCode()
+	}
+	require.NoError(t, err)
+	return outBuf.String(), errBuf.String(), 0
+}
+
+// writeStatsModule writes a module with a vendored go-errs stub,
+// one wrapped and one missing function and a function literal.
+func writeStatsModule(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"go.mod":             "module example.com/m\n\ngo 1.24\n\nrequire github.com/domonda/go-errs v1.0.0\n",
+		"vendor/modules.txt": "# github.com/domonda/go-errs v1.0.0\n## explicit\ngithub.com/domonda/go-errs\n",
+		"vendor/github.com/domonda/go-errs/errs.go": `package errs
+
+func WrapWith0FuncParams(resultVar *error) {}
+`,
+		"m.go": `package m
+
+import "github.com/domonda/go-errs"
+
+func Wrapped() (err error) {
+	defer errs.WrapWith0FuncParams(&err)
+
+	f := func() error { return nil }
+	return f()
+}
+
+func Missing() (err error) {
+	return nil
+}
+`,
+	} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	}
+	return dir
+}
+
+func TestStatsJSON(t *testing.T) {
+	dir := writeStatsModule(t)
+
+	stdout, stderr, exitCode := runMain(t, "stats", "-noconfig", "-format", "json", dir)
+	require.Equal(t, 0, exitCode, stderr)
+
+	var report rewrite.StatsReport
+	require.NoError(t, json.Unmarshal([]byte(stdout), &report), "stdout is one JSON object:\n%s", stdout)
+	assert.Equal(t, rewrite.Stats{
+		ErrorFuncs:    2,
+		Wrapped:       1,
+		Missing:       1,
+		ErrorFuncLits: 1,
+		Specialized:   1,
+		Coverage:      50,
+	}, report.Total)
+	require.Len(t, report.Packages, 1)
+	assert.Equal(t, "example.com/m", report.Packages[0].Package)
+}
+
+func TestStatsThreshold(t *testing.T) {
+	dir := writeStatsModule(t)
+
+	stdout, stderr, exitCode := runMain(t, "stats", "-noconfig", "-threshold", "50", dir)
+	assert.Equal(t, 0, exitCode, stderr)
+	assert.Contains(t, stdout, "50.0%  total")
+
+	stdout, stderr, exitCode = runMain(t, "stats", "-noconfig", "-threshold", "60", dir)
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, stdout, "50.0%  total", "report printed before failing")
+	assert.Contains(t, stderr, "coverage 50.0% is below the threshold of 60%")
+
+	stdout, _, exitCode = runMain(t, "stats", "-noconfig", "-format", "json", "-threshold", "60", dir)
+	assert.Equal(t, 1, exitCode)
+	assert.True(t, json.Valid([]byte(stdout)), stdout)
+
+	_, stderr, exitCode = runMain(t, "stats", "-noconfig", "-format", "sarif", dir)
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, stderr, "stats supports -format text or json")
+}
@@ -0,0 +1,360 @@
+package rewrite
+
+import (
+	"bytes"
+	"errors"
+	"go/ast"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/domonda/go-errs"
+)
+
+// ConfigFileName is the name of the configuration file
+// that FindConfig looks for.
+const ConfigFileName = ".go-errs-wrap.yaml"
+
+// Config is the project configuration of go-errs-wrap
+// read from a .go-errs-wrap.yaml file.
+//
+// Paths and glob patterns are relative to the directory of the file,
+// the patterns of an override to the directory of the override.
+// Glob patterns use the syntax of path.Match with slash separated paths
+// and ** matching any number of directories. A pattern matching
+// a directory matches all files in it.
+//
+// Example:
+//
+//	minvariadic: true
+//	exclude:
+//	  - internal/generated
+//	  - "**/*_mock.go"
+//	skip:
+//	  packages: [example.com/project/internal/legacy/...]
+//	  functions: ["Close", "*.ServeHTTP"]
+//	keepsecret:
+//	  types: [example.com/project/auth.Credentials]
+//	overrides:
+//	  - dir: internal/hot
+//	    skip:
+//	      functions: ["*"]
+//	  - dir: pkg/testutil
+//	    tests: true
+type Config struct {
+	ConfigSettings `yaml:",inline"`
+
+	// Overrides are settings for the files of a directory,
+	// applied in order after the settings of the configuration.
+	Overrides []ConfigOverride `yaml:"overrides"`
+
+	dir  string // directory of the configuration file
+	file string
+}
+
+// ConfigSettings are the settings of a Config or ConfigOverride.
+type ConfigSettings struct {
+	// Include restricts processing to files matching one of the patterns.
+	Include []string `yaml:"include"`
+	// Exclude skips files matching one of the patterns.
+	Exclude []string `yaml:"exclude"`
+	// Skip packages and functions.
+	Skip struct {
+		// Packages are package path patterns like go list accepts
+		// them, with a /... suffix matching all sub-packages.
+		Packages []string `yaml:"packages"`
+		// Functions are patterns matching function names like
+		// Close or Server.ServeHTTP for methods.
+		// Function literals within skipped functions are skipped too.
+		Functions []string `yaml:"functions"`
+	} `yaml:"skip"`
+	// MinVariadic sets the default of the -minvariadic option.
+	MinVariadic *bool `yaml:"minvariadic"`
+	// Tests enables processing of _test.go files.
+	Tests *bool `yaml:"tests"`
+	// Generated enables processing of generated files.
+	Generated *bool `yaml:"generated"`
+	// KeepSecret enables automatic errs.KeepSecret wrapping,
+	// see SecretPolicy. Rules that are not set use
+	// the rules of DefaultSecretPolicy.
+	KeepSecret *struct {
+		Names  []string `yaml:"names"`
+		Types  []string `yaml:"types"`
+		Tagged *bool    `yaml:"tagged"`
+	} `yaml:"keepsecret"`
+}
+
+// ConfigOverride are settings for the files of a directory.
+type ConfigOverride struct {
+	// Dir is the directory relative to the configuration file.
+	Dir string `yaml:"dir"`
+
+	ConfigSettings `yaml:",inline"`
+}
+
+// LoadConfig reads the configuration file at filePath.
+func LoadConfig(filePath string) (config *Config, err error) {
+	defer errs.WrapWithFuncParams(&err, filePath)
+
+	filePath, err = filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filePath) // #nosec G304 -- configuration file path from the user
+	if err != nil {
+		return nil, err
+	}
+	config = &Config{dir: filepath.Dir(filePath), file: filePath}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err = decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, errs.Errorf("invalid configuration file %s: %w", filePath, err)
+	}
+	for _, override := range config.Overrides {
+		if override.Dir == "" || filepath.IsAbs(override.Dir) || strings.HasPrefix(path.Clean(filepath.ToSlash(override.Dir)), "..") {
+			return nil, errs.Errorf("invalid configuration file %s: override dir %q must be a sub-directory", filePath, override.Dir)
+		}
+	}
+	return config, nil
+}
+
+// FindConfig looks for a .go-errs-wrap.yaml file in the directory
+// of sourcePath and its parent directories and returns it loaded,
+// or nil if there is none.
+// The sourcePath is a file, a directory with an optional /... suffix,
+// or a package pattern in which case the search starts
+// at the current working directory.
+func FindConfig(sourcePath string) (config *Config, err error) {
+	defer errs.WrapWithFuncParams(&err, sourcePath)
+
+	dir := strings.TrimSuffix(sourcePath, "...")
+	if info, err := os.Stat(dir); err != nil {
+		dir = "."
+	} else if !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		filePath := filepath.Join(dir, ConfigFileName)
+		if _, err := os.Stat(filePath); err == nil {
+			return LoadConfig(filePath)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// File returns the path of the configuration file.
+func (c *Config) File() string {
+	if c == nil {
+		return ""
+	}
+	return c.file
+}
+
+// tests reports whether the configuration enables
+// processing test files for any directory.
+func (c *Config) tests() bool {
+	if c == nil {
+		return false
+	}
+	if c.Tests != nil && *c.Tests {
+		return true
+	}
+	return slices.ContainsFunc(c.Overrides, func(o ConfigOverride) bool { return o.Tests != nil && *o.Tests })
+}
+
+// fileSettings are the effective settings of a Config for one file.
+type fileSettings struct {
+	skip          string // reason why the file is skipped
+	minVariadic   bool
+	secrets       *SecretPolicy
+	skipFunctions []string
+}
+
+// fileSettings returns the settings for the file at filePath
+// in the package with pkgPath that may be empty if unknown.
+// The settings of overrides for directories containing the file
+// are applied in order after the top level settings,
+// test and generated files are skipped unless enabled.
+func (c *Config) fileSettings(filePath, pkgPath string, file *ast.File) (s fileSettings) {
+	tests := false
+	generated := false
+	if c != nil {
+		levels := []struct {
+			dir string
+			*ConfigSettings
+		}{{c.dir, &c.ConfigSettings}}
+		for i := range c.Overrides {
+			dir := filepath.Join(c.dir, c.Overrides[i].Dir)
+			if isInDir(filePath, dir) {
+				levels = append(levels, struct {
+					dir string
+					*ConfigSettings
+				}{dir, &c.Overrides[i].ConfigSettings})
+			}
+		}
+		for _, level := range levels {
+			rel, err := filepath.Rel(level.dir, filePath)
+			if err != nil || strings.HasPrefix(rel, "..") {
+				continue // File outside of the configuration directory
+			}
+			rel = filepath.ToSlash(rel)
+			if len(level.Include) > 0 && !slices.ContainsFunc(level.Include, func(p string) bool { return matchGlob(p, rel) }) {
+				s.skip = "not included by " + c.file
+			}
+			if slices.ContainsFunc(level.Exclude, func(p string) bool { return matchGlob(p, rel) }) {
+				s.skip = "excluded by " + c.file
+			}
+			if pkgPath != "" && slices.ContainsFunc(level.Skip.Packages, func(p string) bool { return matchPackage(p, strings.TrimSuffix(pkgPath, "_test")) }) {
+				s.skip = "package skipped by " + c.file
+			}
+			s.skipFunctions = append(s.skipFunctions, level.Skip.Functions...)
+			if level.MinVariadic != nil {
+				s.minVariadic = *level.MinVariadic
+			}
+			if level.Tests != nil {
+				tests = *level.Tests
+			}
+			if level.Generated != nil {
+				generated = *level.Generated
+			}
+			if level.KeepSecret != nil {
+				s.secrets = DefaultSecretPolicy()
+				if level.KeepSecret.Names != nil {
+					s.secrets.Names = level.KeepSecret.Names
+				}
+				if level.KeepSecret.Types != nil {
+					s.secrets.Types = level.KeepSecret.Types
+				}
+				if level.KeepSecret.Tagged != nil {
+					s.secrets.Tagged = *level.KeepSecret.Tagged
+				}
+			}
+		}
+	}
+	if s.skip == "" && !tests && strings.HasSuffix(filePath, "_test.go") {
+		s.skip = "test file"
+	}
+	if s.skip == "" && !generated && ast.IsGenerated(file) {
+		s.skip = "generated file"
+	}
+	return s
+}
+
+// isInDir reports whether filePath is in dir or one of its sub-directories.
+func isInDir(filePath, dir string) bool {
+	rel, err := filepath.Rel(dir, filePath)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// matchGlob reports whether the slash separated relative path
+// or one of its parent directories matches pattern.
+// The pattern uses the syntax of path.Match,
+// additionally ** matches any number of path elements.
+func matchGlob(pattern, relPath string) bool {
+	patternElems := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathElems := strings.Split(relPath, "/")
+	for n := 1; n <= len(pathElems); n++ {
+		if matchElems(patternElems, pathElems[:n]) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchElems(pattern, elems []string) bool {
+	if len(pattern) == 0 {
+		return len(elems) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(elems); i++ {
+			if matchElems(pattern[1:], elems[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(elems) == 0 {
+		return false
+	}
+	ok, err := path.Match(pattern[0], elems[0])
+	return ok && err == nil && matchElems(pattern[1:], elems[1:])
+}
+
+// matchPackage reports whether pkgPath matches a package pattern
+// with an optional /... suffix like go list.
+func matchPackage(pattern, pkgPath string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/..."); ok {
+		return pkgPath == prefix || strings.HasPrefix(pkgPath, prefix+"/")
+	}
+	return pkgPath == pattern
+}
+
+// funcDeclName returns the name of a function declaration
+// as matched by the skip functions patterns:
+// the function name or for methods the receiver type name
+// without pointer and type parameters followed by a dot and the method name.
+func funcDeclName(decl *ast.FuncDecl) string {
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return decl.Name.Name
+	}
+	recv := decl.Recv.List[0].Type
+	for {
+		switch x := recv.(type) {
+		case *ast.StarExpr:
+			recv = x.X
+			continue
+		case *ast.IndexExpr:
+			recv = x.X
+			continue
+		case *ast.IndexListExpr:
+			recv = x.X
+			continue
+		case *ast.ParenExpr:
+			recv = x.X
+			continue
+		case *ast.Ident:
+			return x.Name + "." + decl.Name.Name
+		}
+		return decl.Name.Name
+	}
+}
+
+// matchFuncName reports whether the function name matches one of the patterns.
+func matchFuncName(patterns []string, name string) bool {
+	return slices.ContainsFunc(patterns, func(pattern string) bool {
+		ok, err := path.Match(pattern, name)
+		return ok && err == nil
+	})
+}
+
+// skipFunctions removes the replacements within the function declarations
+// of file whose names match one of the patterns, see funcDeclName.
+func skipFunctions(file *ast.File, repls replacements, patterns []string) replacements {
+	if len(patterns) == 0 || len(repls) == 0 {
+		return repls
+	}
+	var skipped []*ast.FuncDecl
+	for _, decl := range file.Decls {
+		if fd, ok := decl.(*ast.FuncDecl); ok && matchFuncName(patterns, funcDeclName(fd)) {
+			skipped = append(skipped, fd)
+		}
+	}
+	return slices.DeleteFunc(repls, func(repl replacement) bool {
+		return repl.Node != nil && slices.ContainsFunc(skipped, func(fd *ast.FuncDecl) bool {
+			return fd.Pos() <= repl.Node.Pos() && repl.Node.End() <= fd.End()
+		})
+	})
+}
@@ -0,0 +1,224 @@
+package rewrite
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"internal/generated", "internal/generated/api.go", true},
+		{"internal/generated", "internal/generated/sub/api.go", true},
+		{"internal/generated", "internal/generatedx/api.go", false},
+		{"internal/generated/", "internal/generated/api.go", true},
+		{"*.go", "main.go", true},
+		{"*.go", "pkg/main.go", false},
+		{"**/*_mock.go", "db_mock.go", true},
+		{"**/*_mock.go", "pkg/db/db_mock.go", true},
+		{"**/*_mock.go", "pkg/db/db.go", false},
+		{"pkg/**/gen", "pkg/gen/a.go", true},
+		{"pkg/**/gen", "pkg/a/b/gen/a.go", true},
+		{"pkg/**/gen", "cmd/gen/a.go", false},
+		{"**", "any/file.go", true},
+		{"[", "file.go", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, matchGlob(tt.pattern, tt.path), "%s %s", tt.pattern, tt.path)
+	}
+}
+
+func TestMatchPackage(t *testing.T) {
+	assert.True(t, matchPackage("example.com/m/legacy", "example.com/m/legacy"))
+	assert.False(t, matchPackage("example.com/m/legacy", "example.com/m/legacy/sub"))
+	assert.True(t, matchPackage("example.com/m/legacy/...", "example.com/m/legacy"))
+	assert.True(t, matchPackage("example.com/m/legacy/...", "example.com/m/legacy/sub"))
+	assert.False(t, matchPackage("example.com/m/legacy/...", "example.com/m/legacyx"))
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		t.Helper()
+		path := filepath.Join(dir, ConfigFileName)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+		return path
+	}
+
+	config, err := LoadConfig(write(""))
+	require.NoError(t, err, "empty configuration")
+	assert.Equal(t, filepath.Join(dir, ConfigFileName), config.File())
+
+	config, err = LoadConfig(write("exclude: [gen]\nkeepsecret:\n  names: [pin]\noverrides:\n  - dir: sub\n    minvariadic: true\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gen"}, config.Exclude)
+	assert.Equal(t, []string{"pin"}, config.KeepSecret.Names)
+	require.Len(t, config.Overrides, 1)
+	assert.Equal(t, "sub", config.Overrides[0].Dir)
+	assert.True(t, *config.Overrides[0].MinVariadic)
+
+	_, err = LoadConfig(write("exlude: [gen]\n"))
+	assert.Error(t, err, "unknown field")
+
+	_, err = LoadConfig(write("overrides:\n  - dir: ../other\n"))
+	assert.Error(t, err, "override outside of the configuration directory")
+
+	_, err = LoadConfig(filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestFindConfig(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "pkg", "sub")
+	require.NoError(t, os.MkdirAll(sub, 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(sub, "a.go"), []byte("package sub\n"), 0600))
+
+	config, err := FindConfig(sub)
+	require.NoError(t, err)
+	if config != nil {
+		// A configuration file above the temp directory
+		assert.NotContains(t, config.File(), dir)
+	}
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ConfigFileName), []byte("tests: true\n"), 0600))
+	for _, path := range []string{sub, filepath.Join(sub, "a.go"), filepath.Join(dir, "pkg", "..."), dir} {
+		config, err = FindConfig(path)
+		require.NoError(t, err, path)
+		require.NotNil(t, config, path)
+		assert.Equal(t, filepath.Join(dir, ConfigFileName), config.File(), path)
+	}
+}
+
+func TestInsertWithConfig(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		ConfigFileName: `exclude:
+  - internal/generated
+skip:
+  packages: [example.com/m/legacy/...]
+  functions: [Close, "*.ServeHTTP"]
+keepsecret:
+  names: [pin]
+overrides:
+  - dir: hot
+    minvariadic: true
+    tests: true
+  - dir: gen
+    generated: true
+`,
+		"server.go": `package m
+
+type Server struct{}
+
+func (s *Server) ServeHTTP(path string) (err error) {
+	return nil
+}
+
+func (s *Server) Start(addr string) (err error) {
+	return nil
+}
+
+func Close(name string) (err error) {
+	return nil
+}
+
+func Unlock(pin string) (err error) {
+	return nil
+}
+`,
+		"server_test.go": `package m
+
+func helper(x int) (err error) {
+	return nil
+}
+`,
+		"internal/generated/api.go": `package generated
+
+func API(x int) (err error) {
+	return nil
+}
+`,
+		"legacy/sub/old.go": `package sub
+
+func Old(x int) (err error) {
+	return nil
+}
+`,
+		"hot/hot.go": `package hot
+
+import "github.com/domonda/go-errs"
+
+func Hot(a, b int) (err error) {
+	defer errs.WrapWithFuncParams(&err, a)
+
+	return nil
+}
+`,
+		"variadic.go": `package m
+
+import "github.com/domonda/go-errs"
+
+func Variadic(a, b int) (err error) {
+	defer errs.WrapWithFuncParams(&err, a)
+
+	return nil
+}
+`,
+		"hot/hot_test.go": `package hot
+
+func helper(x int) (err error) {
+	return nil
+}
+`,
+		"gen/gen.go": `// Code generated by a test. DO NOT EDIT.
+
+package gen
+
+func Gen(x int) (err error) {
+	return nil
+}
+`,
+	})
+	read := func(name string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	config, err := FindConfig(filepath.Join(dir, "..."))
+	require.NoError(t, err)
+	require.NotNil(t, config)
+
+	err = Insert(filepath.Join(dir, "..."), Options{Config: config})
+	require.NoError(t, err)
+
+	server := read("server.go")
+	assert.Contains(t, server, "func (s *Server) ServeHTTP(path string) (err error) {\n\treturn nil\n}", "method skipped by pattern")
+	assert.Contains(t, server, "func Close(name string) (err error) {\n\treturn nil\n}", "function skipped by name")
+	assert.Contains(t, server, "defer errs.WrapWith1FuncParam(&err, addr)\n")
+	assert.Contains(t, server, "defer errs.WrapWith1FuncParam(&err, errs.KeepSecret(pin))\n", "keepsecret rules of the configuration")
+	assert.NotContains(t, read("server_test.go"), "defer", "test files are skipped by default")
+	assert.NotContains(t, read("internal/generated/api.go"), "defer", "excluded directory")
+	assert.NotContains(t, read("legacy/sub/old.go"), "defer", "skipped package")
+	assert.Contains(t, read("hot/hot_test.go"), "defer errs.WrapWith1FuncParam(&err, x)\n", "tests override")
+	assert.Contains(t, read("gen/gen.go"), "defer errs.WrapWith1FuncParam(&err, x)\n", "generated override")
+
+	err = Insert(filepath.Join(dir, "..."), Options{Validate: true, Config: config})
+	assert.NoError(t, err, "validate honors the configuration")
+	err = Insert(filepath.Join(dir, "..."), Options{Validate: true})
+	assert.Error(t, err, "validate without configuration")
+
+	err = Replace(filepath.Join(dir, "..."), Options{Config: config})
+	require.NoError(t, err)
+
+	assert.Contains(t, read("variadic.go"), "defer errs.WrapWithFuncParams(&err, a, b)\n")
+	assert.Contains(t, read("hot/hot.go"), "defer errs.WrapWith2FuncParams(&err, a, b)\n", "minvariadic override")
+	assert.Contains(t, read("server.go"), "func Close(name string) (err error) {\n\treturn nil\n}", "skipped function")
+}
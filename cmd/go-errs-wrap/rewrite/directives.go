@@ -0,0 +1,117 @@
+package rewrite
+
+import (
+	"go/ast"
+	"slices"
+	"strings"
+)
+
+// Directive comments controlling go-errs-wrap in Go source files.
+// Because of the hyphens gofmt doesn't recognize them as directives
+// and inserts a space after the slashes in doc comments,
+// so they are recognized with and without that space.
+const (
+	// IgnoreDirective in the doc comment of a function excludes the function
+	// and the function literals within it from all commands,
+	// in a comment before the package clause it excludes the file.
+	// Text after the directive, like a reason, is ignored:
+	//
+	//	//go-errs-wrap:ignore hot path, no deferred wrap
+	//	func (b *Buffer) Write(p []byte) (n int, err error) {
+	IgnoreDirective = "//go-errs-wrap:ignore"
+
+	// ParamsDirective in the doc comment of a function restricts the
+	// parameters passed to generated wrap statements to the listed ones,
+	// separated by commas. Without names no parameters are passed:
+	//
+	//	//go-errs-wrap:params id,version
+	//	func Store(ctx context.Context, id string, version int, data []byte) (err error) {
+	ParamsDirective = "//go-errs-wrap:params"
+)
+
+// Directives are the go-errs-wrap directives of a function.
+type Directives struct {
+	// Ignore is set by IgnoreDirective.
+	Ignore bool
+
+	// Params are the parameter names listed by ParamsDirective,
+	// nil if the parameters are not restricted.
+	Params []string
+}
+
+// FuncDirectives returns the directives in the doc comment of a function
+// declaration, doc may be nil.
+func FuncDirectives(doc *ast.CommentGroup) (d Directives) {
+	if doc == nil {
+		return d
+	}
+	for _, c := range doc.List {
+		name, args := parseDirective(c.Text)
+		switch name {
+		case IgnoreDirective:
+			d.Ignore = true
+		case ParamsDirective:
+			d.Params = append(make([]string, 0), strings.FieldsFunc(args, func(r rune) bool {
+				return r == ',' || r == ' ' || r == '\t'
+			})...)
+		}
+	}
+	return d
+}
+
+// IsFileIgnored reports whether file has an IgnoreDirective
+// in a comment before its package clause.
+func IsFileIgnored(file *ast.File) bool {
+	for _, cg := range file.Comments {
+		if cg.Pos() >= file.Package {
+			break
+		}
+		if slices.ContainsFunc(cg.List, func(c *ast.Comment) bool {
+			name, _ := parseDirective(c.Text)
+			return name == IgnoreDirective
+		}) {
+			return true
+		}
+	}
+	return false
+}
+
+// UnknownParams returns the names of d.Params that are not
+// parameters of funcType.
+func (d Directives) UnknownParams(funcType *ast.FuncType) (unknown []string) {
+	if funcType.Params == nil {
+		return d.Params
+	}
+	for _, name := range d.Params {
+		if !slices.ContainsFunc(funcType.Params.List, func(field *ast.Field) bool {
+			return slices.ContainsFunc(field.Names, func(ident *ast.Ident) bool { return ident.Name == name })
+		}) {
+			unknown = append(unknown, name)
+		}
+	}
+	return unknown
+}
+
+// parseDirective returns the directive of a go-errs-wrap
+// directive comment without a space after the slashes
+// and its arguments, or empty strings if text is not a directive comment.
+func parseDirective(text string) (name, args string) {
+	text = "//" + strings.TrimLeft(strings.TrimPrefix(text, "//"), " \t")
+	if !strings.HasPrefix(text, "//go-errs-wrap:") {
+		return "", ""
+	}
+	if i := strings.IndexAny(text, " \t"); i >= 0 {
+		return text[:i], strings.TrimSpace(text[i:])
+	}
+	return text, ""
+}
+
+// applyDirectives applies the directives of the function to fun.
+func (fun *funcInfo) applyDirectives(d Directives) {
+	fun.ignore = d.Ignore
+	if d.Params != nil {
+		fun.paramNames = slices.DeleteFunc(fun.paramNames, func(name string) bool {
+			return !slices.Contains(d.Params, name)
+		})
+	}
+}
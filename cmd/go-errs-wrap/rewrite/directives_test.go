@@ -0,0 +1,201 @@
+package rewrite
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuncDirectives(t *testing.T) {
+	code := `package test
+
+func None() {}
+
+// Ignored with a reason.
+//
+//go-errs-wrap:ignore hot path
+func Ignored() {}
+
+//go-errs-wrap:params a, b
+func Params(a, b, c int) {}
+
+//go-errs-wrap:params	a,b,,c
+func Separators(a, b, c int) {}
+
+//go-errs-wrap:params
+func NoParams(a int) {}
+
+// go-errs-wrap:ignore as formatted by gofmt
+func Formatted() {}
+
+/*go-errs-wrap:ignore*/
+func Block() {}
+
+//go-errs-wrap:ignored
+func Unknown() {}
+`
+	file, err := parser.ParseFile(token.NewFileSet(), "test.go", code, parser.ParseComments)
+	require.NoError(t, err)
+	directives := make(map[string]Directives)
+	for _, decl := range file.Decls {
+		fd := decl.(*ast.FuncDecl)
+		directives[fd.Name.Name] = FuncDirectives(fd.Doc)
+	}
+
+	assert.Equal(t, Directives{}, directives["None"])
+	assert.Equal(t, Directives{Ignore: true}, directives["Ignored"])
+	assert.Equal(t, Directives{Params: []string{"a", "b"}}, directives["Params"])
+	assert.Equal(t, Directives{Params: []string{"a", "b", "c"}}, directives["Separators"])
+	assert.Equal(t, Directives{Params: []string{}}, directives["NoParams"])
+	assert.Equal(t, Directives{Ignore: true}, directives["Formatted"])
+	assert.Equal(t, Directives{}, directives["Block"])
+	assert.Equal(t, Directives{}, directives["Unknown"])
+	assert.False(t, IsFileIgnored(file))
+
+	params := file.Decls[2].(*ast.FuncDecl)
+	assert.Empty(t, Directives{Params: []string{"a", "c"}}.UnknownParams(params.Type))
+	assert.Equal(t, []string{"x"}, Directives{Params: []string{"a", "x"}}.UnknownParams(params.Type))
+}
+
+func TestIsFileIgnored(t *testing.T) {
+	for code, want := range map[string]bool{
+		"//go-errs-wrap:ignore\n\npackage test\n":                           true,
+		"//go:build linux\n\n//go-errs-wrap:ignore mocks\npackage test\n":   true,
+		"// Package test is a test.\n//go-errs-wrap:ignore\npackage test\n": true,
+		"package test\n\n//go-errs-wrap:ignore\nfunc f() {}\n":              false,
+		"package test\n": false,
+	} {
+		file, err := parser.ParseFile(token.NewFileSet(), "test.go", code, parser.ParseComments)
+		require.NoError(t, err)
+		assert.Equal(t, want, IsFileIgnored(file), code)
+	}
+}
+
+func TestDirectives(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Directives as formatted by gofmt in doc comments
+	inputCode := `package test
+
+import "github.com/domonda/go-errs"
+
+// go-errs-wrap:ignore hot path
+func Hot(p []byte) (n int, err error) {
+	f := func(x int) (err error) {
+		return nil
+	}
+	return 0, f(0)
+}
+
+// go-errs-wrap:ignore
+func HotStale(id string, n int) (err error) {
+	defer errs.WrapWith1FuncParam(&err, id)
+
+	return nil
+}
+
+// go-errs-wrap:params id
+func Store(id string, data []byte) (err error) {
+	return nil
+}
+
+// go-errs-wrap:params id,version
+func Update(id string, data []byte, version int) (err error) {
+	defer errs.WrapWith3FuncParams(&err, id, data, version)
+
+	return nil
+}
+`
+	expectedInsert := `package test
+
+import "github.com/domonda/go-errs"
+
+// go-errs-wrap:ignore hot path
+func Hot(p []byte) (n int, err error) {
+	f := func(x int) (err error) {
+		return nil
+	}
+	return 0, f(0)
+}
+
+// go-errs-wrap:ignore
+func HotStale(id string, n int) (err error) {
+	defer errs.WrapWith1FuncParam(&err, id)
+
+	return nil
+}
+
+// go-errs-wrap:params id
+func Store(id string, data []byte) (err error) {
+	defer errs.WrapWith1FuncParam(&err, id)
+
+	return nil
+}
+
+// go-errs-wrap:params id,version
+func Update(id string, data []byte, version int) (err error) {
+	defer errs.WrapWith3FuncParams(&err, id, data, version)
+
+	return nil
+}
+`
+	inputFile := filepath.Join(tmpDir, "input.go")
+	require.NoError(t, os.WriteFile(inputFile, []byte(inputCode), 0644))
+
+	err := Insert(inputFile, Options{Validate: true})
+	require.Error(t, err, "Store is missing a wrap statement")
+
+	err = Insert(inputFile, Options{})
+	require.NoError(t, err)
+	output, err := os.ReadFile(inputFile)
+	require.NoError(t, err)
+	assert.Equal(t, expectedInsert, string(output))
+
+	err = Insert(inputFile, Options{Validate: true})
+	require.NoError(t, err, "ignored functions are not reported")
+	err = Replace(inputFile, Options{Validate: true})
+	require.Error(t, err, "Update passes data")
+
+	err = Replace(inputFile, Options{})
+	require.NoError(t, err)
+	output, err = os.ReadFile(inputFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(output), "defer errs.WrapWith2FuncParams(&err, id, version)\n")
+	assert.Contains(t, string(output), "defer errs.WrapWith1FuncParam(&err, id)\n\n\treturn nil\n}\n\n// go-errs-wrap:params id\n", "ignored stale statement is kept")
+
+	err = Replace(inputFile, Options{Validate: true})
+	require.NoError(t, err)
+
+	err = Remove(inputFile, Options{})
+	require.NoError(t, err)
+	output, err = os.ReadFile(inputFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(output), "func HotStale(id string, n int) (err error) {\n\tdefer errs.WrapWith1FuncParam(&err, id)\n", "remove ignores the function too")
+	assert.NotContains(t, string(output), "defer errs.WrapWith2FuncParams")
+}
+
+func TestIgnoredFile(t *testing.T) {
+	code := `//go-errs-wrap:ignore
+
+package test
+
+func F(id string) (err error) {
+	return nil
+}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "test.go", code, parser.ParseComments)
+	require.NoError(t, err)
+
+	for _, mode := range []processMode{modeRemove, modeReplace, modeInsert} {
+		replacements, _, err := processFile(fset, file, nil, false, "err", nil, nil, mode)
+		require.NoError(t, err)
+		assert.Empty(t, replacements, mode)
+	}
+}
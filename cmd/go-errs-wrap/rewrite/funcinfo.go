@@ -1,8 +1,13 @@
 package rewrite
 
 import (
+	"fmt"
 	"go/ast"
 	"go/token"
+	"go/types"
+	"maps"
+
+	"github.com/ungerik/go-astvisit"
 )
 
 // funcInfo holds information about a function needed to generate
@@ -11,25 +16,42 @@ type funcInfo struct {
 	funcName        string
 	paramNames      []string
 	keepSecretNames map[string]bool // parameter names that should be wrapped with errs.KeepSecret
+	secretNames     map[string]bool // parameter names of types implementing errs.Secret
 	errorResultName string
 	startPos        token.Pos
 	endPos          token.Pos
+	funcType        *ast.FuncType
+	body            *ast.BlockStmt
+	info            *types.Info // nil without type information
+	ignore          bool        // set by IgnoreDirective
 }
 
 // extractFuncInfo extracts the function information from a function type.
-func extractFuncInfo(funcType *ast.FuncType, funcName string, startPos, endPos token.Pos) *funcInfo {
+// The type information info is optional, if not nil it is used
+// to recognize error results declared with named types
+// like type Error = error and parameters implementing errs.Secret.
+func extractFuncInfo(info *types.Info, funcType *ast.FuncType, funcName string, startPos, endPos token.Pos) *funcInfo {
 	fun := &funcInfo{
 		funcName: funcName,
 		startPos: startPos,
 		endPos:   endPos,
+		funcType: funcType,
+		info:     info,
 	}
 
 	// Extract parameter names (excluding blank identifiers)
 	if funcType.Params != nil {
 		for _, field := range funcType.Params.List {
 			for _, name := range field.Names {
-				if name.Name != "_" {
-					fun.paramNames = append(fun.paramNames, name.Name)
+				if name.Name == "_" {
+					continue
+				}
+				fun.paramNames = append(fun.paramNames, name.Name)
+				if isSecretType(info, field.Type) {
+					if fun.secretNames == nil {
+						fun.secretNames = make(map[string]bool)
+					}
+					fun.secretNames[name.Name] = true
 				}
 			}
 		}
@@ -38,7 +60,7 @@ func extractFuncInfo(funcType *ast.FuncType, funcName string, startPos, endPos t
 	// Find the named error result (use the last one if multiple)
 	if funcType.Results != nil {
 		for _, field := range funcType.Results.List {
-			if isErrorType(field.Type) {
+			if isErrorTypeOf(info, field.Type) {
 				for _, name := range field.Names {
 					if name.Name != "_" {
 						fun.errorResultName = name.Name
@@ -51,6 +73,26 @@ func extractFuncInfo(funcType *ast.FuncType, funcName string, startPos, endPos t
 	return fun
 }
 
+// setKeepSecretNames sets the parameter names to wrap with errs.KeepSecret
+// except for parameters that already implement errs.Secret.
+func (fun *funcInfo) setKeepSecretNames(names map[string]bool) {
+	for name := range fun.secretNames {
+		delete(names, name)
+	}
+	fun.keepSecretNames = names
+}
+
+// addKeepSecretNames adds parameter names to wrap with errs.KeepSecret.
+func (fun *funcInfo) addKeepSecretNames(names map[string]bool) {
+	if len(names) == 0 {
+		return
+	}
+	if fun.keepSecretNames == nil {
+		fun.keepSecretNames = make(map[string]bool)
+	}
+	maps.Copy(fun.keepSecretNames, names)
+}
+
 // isErrorType checks if the type expression is the error type.
 func isErrorType(expr ast.Expr) bool {
 	ident, ok := expr.(*ast.Ident)
@@ -142,7 +184,7 @@ func extractKeepSecretParams(stmt *ast.DeferStmt, errsAliases map[string]bool) m
 // findEnclosingFuncForPos walks the AST to find the innermost function containing the given position.
 // This is used for finding the function context for comments, which aren't visited
 // during the normal AST traversal.
-func findEnclosingFuncForPos(file *ast.File, pos token.Pos) (result *funcInfo) {
+func findEnclosingFuncForPos(file *ast.File, info *types.Info, pos token.Pos) (result *funcInfo) {
 	ast.Inspect(file, func(n ast.Node) bool {
 		if n == nil || pos < n.Pos() || pos > n.End() {
 			return false
@@ -150,9 +192,15 @@ func findEnclosingFuncForPos(file *ast.File, pos token.Pos) (result *funcInfo) {
 
 		switch node := n.(type) {
 		case *ast.FuncDecl:
-			result = extractFuncInfo(node.Type, node.Name.Name, node.Pos(), node.End())
+			result = extractFuncInfo(info, node.Type, node.Name.Name, node.Pos(), node.End())
+			result.body = node.Body
+			result.applyDirectives(FuncDirectives(node.Doc))
 		case *ast.FuncLit:
-			result = extractFuncInfo(node.Type, "(anonymous)", node.Pos(), node.End())
+			// Function literals within ignored functions are ignored
+			ignore := result != nil && result.ignore
+			result = extractFuncInfo(info, node.Type, "(anonymous)", node.Pos(), node.End())
+			result.body = node.Body
+			result.ignore = ignore
 		}
 		// Continue traversing to find nested function literals.
 		// If a nested function also contains the position,
@@ -162,3 +210,81 @@ func findEnclosingFuncForPos(file *ast.File, pos token.Pos) (result *funcInfo) {
 
 	return result
 }
+
+// nameFuncResults names the anonymous results of the function
+// so that a wrap statement can be generated for it.
+// The last error result is named errName, or a variation of it
+// if errName is already used as identifier in the function,
+// all other results are named with the blank identifier.
+// A blank error result of named results is renamed the same way.
+//
+// The name of the error result is set as fun.errorResultName
+// and returned together with the replacements renaming the results.
+// An empty name is returned if the function has no error result.
+func nameFuncResults(fun *funcInfo, errName string) (string, replacements) {
+	results := fun.funcType.Results
+	if results == nil || len(results.List) == 0 {
+		return "", nil
+	}
+	errField := -1
+	for i, field := range results.List {
+		if isErrorTypeOf(fun.info, field.Type) {
+			errField = i
+		}
+	}
+	if errField < 0 {
+		return "", nil
+	}
+	name := unusedIdent(fun, errName)
+
+	var renames replacements
+	if len(results.List[0].Names) > 0 {
+		// Named results, rename the last blank error result
+		names := results.List[errField].Names
+		if names[len(names)-1].Name != "_" {
+			return "", nil
+		}
+		renames.add(RuleUnnamedResult, names[len(names)-1], name, "name error result")
+	} else {
+		for i, field := range results.List {
+			resultName := "_ "
+			if i == errField {
+				resultName = name + " "
+			}
+			if !results.Opening.IsValid() {
+				// Single result without parentheses
+				resultName = "(" + resultName
+				renames.add(RuleUnnamedResult, astvisit.PosNode(field.Type.End()), ")", "name error result")
+			}
+			renames.add(RuleUnnamedResult, astvisit.PosNode(field.Type.Pos()), resultName, "name error result")
+		}
+	}
+	fun.errorResultName = name
+	return name, renames
+}
+
+// unusedIdent returns name if it is not used as identifier
+// in the function, else name followed by "Result" and
+// a number starting at 2 if that is also used.
+func unusedIdent(fun *funcInfo, name string) string {
+	used := make(map[string]bool)
+	collect := func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok {
+			used[ident.Name] = true
+		}
+		return true
+	}
+	ast.Inspect(fun.funcType, collect)
+	if fun.body != nil {
+		ast.Inspect(fun.body, collect)
+	}
+	if !used[name] {
+		return name
+	}
+	base := name + "Result"
+	candidate := base
+	for i := 2; used[candidate]; i++ {
+		candidate = fmt.Sprintf("%s%d", base, i)
+	}
+	return candidate
+}
@@ -100,7 +100,7 @@ func Grouped(a, b, c string) (err error) { return }`,
 			}
 			require.NotNil(t, funcDecl, "no function declaration found")
 
-			ctx := extractFuncInfo(funcDecl.Type, funcDecl.Name.Name, funcDecl.Pos(), funcDecl.End())
+			ctx := extractFuncInfo(nil, funcDecl.Type, funcDecl.Name.Name, funcDecl.Pos(), funcDecl.End())
 
 			assert.Equal(t, tt.wantFuncName, ctx.funcName)
 			assert.Equal(t, tt.wantParams, ctx.paramNames)
@@ -181,16 +181,39 @@ func Another(x string) error {
 	require.NotEqual(t, token.NoPos, innerCommentPos, "inner comment not found")
 
 	// Test outer function context
-	outerCtx := findEnclosingFuncForPos(file, outerCommentPos)
+	outerCtx := findEnclosingFuncForPos(file, nil, outerCommentPos)
 	require.NotNil(t, outerCtx)
 	assert.Equal(t, "Outer", outerCtx.funcName)
 	assert.Equal(t, []string{"a"}, outerCtx.paramNames)
 	assert.Equal(t, "err", outerCtx.errorResultName)
 
 	// Test inner function context (should find the anonymous function)
-	innerCtx := findEnclosingFuncForPos(file, innerCommentPos)
+	innerCtx := findEnclosingFuncForPos(file, nil, innerCommentPos)
 	require.NotNil(t, innerCtx)
 	assert.Equal(t, "(anonymous)", innerCtx.funcName)
 	assert.Equal(t, []string{"b"}, innerCtx.paramNames)
 	assert.Equal(t, "innerErr", innerCtx.errorResultName)
 }
+
+func TestUnusedIdent(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{name: "unused", code: `func f(x int) error { return nil }`, want: "err"},
+		{name: "parameter", code: `func f(err error) error { return err }`, want: "errResult"},
+		{name: "body", code: `func f() error { _, err := g(); return err }`, want: "errResult"},
+		{name: "numbered", code: `func f(errResult int) error { _, err := g(); return err }`, want: "errResult2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := parser.ParseFile(token.NewFileSet(), "test.go", "package test\n"+tt.code, 0)
+			require.NoError(t, err)
+			funcDecl := file.Decls[0].(*ast.FuncDecl)
+			fun := extractFuncInfo(nil, funcDecl.Type, funcDecl.Name.Name, funcDecl.Pos(), funcDecl.End())
+			fun.body = funcDecl.Body
+			assert.Equal(t, tt.want, unusedIdent(fun, "err"))
+		})
+	}
+}
@@ -2,6 +2,7 @@ package rewrite
 
 import (
 	"fmt"
+	"go/ast"
 	"strings"
 )
 
@@ -65,3 +66,32 @@ func generateVariadicWrapStatement(fun *funcInfo) string {
 		formatParams(fun.paramNames, fun.keepSecretNames),
 	)
 }
+
+// WrapStatement returns the defer errs.WrapWith*FuncParams statement
+// that the replace command generates for the existing defer errs.Wrap
+// statement stmt of a function with the type funcType in file,
+// or that the insert command generates if stmt is nil.
+// The directives of the function restrict its parameters,
+// see FuncDirectives.
+//
+// Parameters wrapped with errs.KeepSecret in stmt stay wrapped,
+// and a variadic errs.WrapWithFuncParams stays variadic
+// unless minVariadic is true.
+// An empty string is returned if the function has no named error result
+// or is ignored by the directives.
+func WrapStatement(file *ast.File, funcType *ast.FuncType, directives Directives, stmt *ast.DeferStmt, minVariadic bool) string {
+	fun := extractFuncInfo(nil, funcType, "", funcType.Pos(), funcType.End())
+	fun.applyDirectives(directives)
+	if fun.errorResultName == "" || fun.ignore {
+		return ""
+	}
+	if stmt == nil {
+		return generateWrapStatement(fun)
+	}
+	refs := newErrsRefs(file, nil)
+	fun.keepSecretNames = refs.keepSecretParams(stmt)
+	if !minVariadic && refs.isVariadicWrapWithFuncParams(stmt) {
+		return generateVariadicWrapStatement(fun)
+	}
+	return generateWrapStatement(fun)
+}
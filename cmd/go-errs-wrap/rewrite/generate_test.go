@@ -1,9 +1,13 @@
 package rewrite
 
 import (
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGenerateWrapStatement(t *testing.T) {
@@ -147,3 +151,81 @@ func TestGenerateWrapStatement(t *testing.T) {
 		})
 	}
 }
+
+func TestWrapStatement(t *testing.T) {
+	tests := []struct {
+		name        string
+		code        string
+		minVariadic bool
+		want        string
+	}{
+		{
+			name: "insert",
+			code: `func f(ctx context.Context, id string) (err error) { return nil }`,
+			want: "defer errs.WrapWith2FuncParams(&err, ctx, id)",
+		},
+		{
+			name: "no named error result",
+			code: `func f(id string) error { return nil }`,
+			want: "",
+		},
+		{
+			name: "replace keeps KeepSecret",
+			code: `func f(user, password string, n int) (err error) {
+	defer errs.WrapWith2FuncParams(&err, user, errs.KeepSecret(password))
+	return nil
+}`,
+			want: "defer errs.WrapWith3FuncParams(&err, user, errs.KeepSecret(password), n)",
+		},
+		{
+			name: "replace keeps variadic",
+			code: `func f(id string, n int) (err error) {
+	defer errs.WrapWithFuncParams(&err, id)
+	return nil
+}`,
+			want: "defer errs.WrapWithFuncParams(&err, id, n)",
+		},
+		{
+			name: "replace with minVariadic",
+			code: `func f(id string, n int) (err error) {
+	defer errs.WrapWithFuncParams(&err, id)
+	return nil
+}`,
+			minVariadic: true,
+			want:        "defer errs.WrapWith2FuncParams(&err, id, n)",
+		},
+		{
+			name: "ignore directive",
+			code: `//go-errs-wrap:ignore hot path
+func f(id string) (err error) { return nil }`,
+			want: "",
+		},
+		{
+			name: "params directive",
+			code: `// f stores data.
+//
+//go-errs-wrap:params id, n
+func f(id string, data []byte, n int) (err error) { return nil }`,
+			want: "defer errs.WrapWith2FuncParams(&err, id, n)",
+		},
+		{
+			name: "empty params directive",
+			code: `//go-errs-wrap:params
+func f(data []byte) (err error) { return nil }`,
+			want: "defer errs.WrapWith0FuncParams(&err)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := parser.ParseFile(token.NewFileSet(), "test.go", "package test\n"+tt.code, parser.ParseComments)
+			require.NoError(t, err)
+			funcDecl := file.Decls[0].(*ast.FuncDecl)
+			var stmt *ast.DeferStmt
+			if len(funcDecl.Body.List) > 0 {
+				stmt, _ = funcDecl.Body.List[0].(*ast.DeferStmt)
+			}
+			assert.Equal(t, tt.want, WrapStatement(file, funcDecl.Type, FuncDirectives(funcDecl.Doc), stmt, tt.minVariadic))
+		})
+	}
+}
@@ -0,0 +1,322 @@
+package rewrite
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/domonda/go-errs"
+)
+
+// sourceFile is a parsed Go source file to process.
+type sourceFile struct {
+	path    string
+	pkgPath string // empty if not known
+	fset    *token.FileSet
+	file    *ast.File
+	info    *types.Info // nil if the file was not type-checked
+}
+
+// loadSourceFiles loads the Go source files matched by pattern
+// which is either the path of a .go file or a package pattern
+// with the semantics of go list, like ./... or ./pkg/....
+// Test files are only loaded if tests is true.
+//
+// The files are type-checked with their packages.
+// Files excluded by build constraints are loaded without
+// type information, as are the files of directories
+// that don't belong to a module.
+func loadSourceFiles(pattern string, tests bool, verboseOut io.Writer) (files []*sourceFile, err error) {
+	defer errs.WrapWithFuncParams(&err, pattern, tests, verboseOut)
+
+	// Load files and directories from their own module
+	// by running go list in their directory
+	var dir, onlyFile string
+	if strings.HasSuffix(pattern, ".go") {
+		onlyFile, err = filepath.Abs(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = os.Stat(onlyFile); err != nil {
+			return nil, err
+		}
+		dir = filepath.Dir(onlyFile)
+		pattern = "file=" + onlyFile
+	} else if before, recursive := strings.CutSuffix(pattern, "/..."); isDir(before) {
+		dir, err = filepath.Abs(before)
+		if err != nil {
+			return nil, err
+		}
+		pattern = "."
+		if recursive {
+			pattern = "./..."
+		}
+	}
+
+	// List the files to process first so that only
+	// those have to be fully parsed and type-checked
+	pkgs, err := packages.Load(&packages.Config{Mode: packages.NeedName | packages.NeedFiles, Dir: dir, Tests: tests}, pattern)
+	if err != nil && dir == "" {
+		return nil, err
+	}
+	checkedFiles := make(map[string]bool)
+	var ignoredFiles []string
+	ignoredPkgPaths := make(map[string]string)
+	var listErrs []string
+	if err != nil {
+		listErrs = append(listErrs, err.Error())
+	}
+	for _, pkg := range pkgs {
+		if strings.HasSuffix(pkg.ID, ".test") {
+			continue // Generated test main package
+		}
+		for _, file := range pkg.GoFiles {
+			if onlyFile == "" || file == onlyFile {
+				checkedFiles[file] = true
+			}
+		}
+		for _, file := range pkg.IgnoredFiles {
+			// Files excluded by build constraints
+			if strings.HasSuffix(file, ".go") && (tests || !strings.HasSuffix(file, "_test.go")) && (onlyFile == "" || file == onlyFile) {
+				if _, ok := ignoredPkgPaths[file]; !ok {
+					ignoredFiles = append(ignoredFiles, file)
+					ignoredPkgPaths[file] = pkg.PkgPath
+				}
+			}
+		}
+		for _, e := range pkg.Errors {
+			listErrs = append(listErrs, e.Msg)
+		}
+	}
+
+	fset := token.NewFileSet()
+	if len(checkedFiles) == 0 && len(ignoredFiles) == 0 {
+		if dir == "" {
+			if len(listErrs) > 0 {
+				return nil, errs.New(strings.Join(listErrs, "\n"))
+			}
+			return nil, errs.Errorf("pattern %s matched no Go files", pattern)
+		}
+		// Not part of a module, parse the files without type information
+		if verboseOut != nil {
+			fmt.Fprintf(verboseOut, "loading %s without type information: %s\n", dir, strings.Join(listErrs, "; "))
+		}
+		if onlyFile != "" {
+			file, err := parser.ParseFile(fset, onlyFile, nil, parser.ParseComments)
+			if err != nil {
+				return nil, err
+			}
+			return []*sourceFile{{path: onlyFile, fset: fset, file: file}}, nil
+		}
+		return parseDirFiles(fset, dir, pattern == "./...", tests)
+	}
+
+	if len(checkedFiles) > 0 {
+		cfg := &packages.Config{Dir: dir, Fset: fset, Tests: tests}
+		checked, err := checkPackages(cfg, parser.ParseComments, pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, pkg := range checked {
+			if verboseOut != nil {
+				for _, e := range pkg.Errors {
+					// Type errors are tolerated, affected
+					// expressions are handled without type information
+					fmt.Fprintf(verboseOut, "%s: %s\n", pkg.PkgPath, e)
+				}
+			}
+			for _, file := range pkg.files {
+				path := fset.File(file.Pos()).Name()
+				if !checkedFiles[path] {
+					continue
+				}
+				delete(checkedFiles, path) // Packages may be listed multiple times
+				files = append(files, &sourceFile{path: path, pkgPath: pkg.PkgPath, fset: fset, file: file, info: pkg.info})
+			}
+		}
+	}
+
+	for _, path := range ignoredFiles {
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, &sourceFile{path: path, pkgPath: ignoredPkgPaths[path], fset: fset, file: file})
+	}
+
+	sortSourceFiles(files)
+	return files, nil
+}
+
+// parseDirFiles parses the Go files of dir without type information,
+// test files only if tests is true.
+// If recursive is true, the sub-directories that go list would
+// include in dir/... are parsed too.
+func parseDirFiles(fset *token.FileSet, dir string, recursive, tests bool) (files []*sourceFile, err error) {
+	defer errs.WrapWithFuncParams(&err, fset, dir, recursive, tests)
+
+	err = filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := entry.Name()
+		if entry.IsDir() {
+			if path != dir && (!recursive || isIgnoredDir(name)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".go") || (!tests && strings.HasSuffix(name, "_test.go")) {
+			return nil
+		}
+		path, err = filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return err
+		}
+		files = append(files, &sourceFile{path: path, fset: fset, file: file})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortSourceFiles(files)
+	return files, nil
+}
+
+// isIgnoredDir reports whether go list ignores
+// a directory with name when matching a /... pattern.
+func isIgnoredDir(name string) bool {
+	return name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
+
+// sortSourceFiles sorts files by path for deterministic output.
+func sortSourceFiles(files []*sourceFile) {
+	slices.SortFunc(files, func(a, b *sourceFile) int { return strings.Compare(a.path, b.path) })
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// checkedPackage is a package loaded by checkPackages.
+type checkedPackage struct {
+	*packages.Package
+	files []*ast.File
+	info  *types.Info
+}
+
+// checkPackages loads the packages matching patterns with cfg
+// and type-checks their files parsed with mode.
+// Generated test main packages are skipped.
+// Type errors are appended to the Errors of the packages.
+//
+// The types of dependencies are read from the export data built
+// by go list with the importer of the Go version this command
+// was built with, because golang.org/x/tools/go/packages
+// can't decode export data written by newer Go versions.
+func checkPackages(cfg *packages.Config, mode parser.Mode, patterns ...string) (checked []*checkedPackage, err error) {
+	defer errs.WrapWithFuncParams(&err, cfg, mode, patterns)
+
+	cfg.Mode = packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles |
+		packages.NeedImports | packages.NeedDeps | packages.NeedExportFile
+	if cfg.Fset == nil {
+		cfg.Fset = token.NewFileSet()
+	}
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	for _, pkg := range pkgs {
+		if strings.HasSuffix(pkg.ID, ".test") {
+			continue // Generated test main package
+		}
+		c := &checkedPackage{
+			Package: pkg,
+			info: &types.Info{
+				Types:        make(map[ast.Expr]types.TypeAndValue),
+				Defs:         make(map[*ast.Ident]types.Object),
+				Uses:         make(map[*ast.Ident]types.Object),
+				Implicits:    make(map[ast.Node]types.Object),
+				Instances:    make(map[*ast.Ident]types.Instance),
+				Scopes:       make(map[ast.Node]*types.Scope),
+				Selections:   make(map[*ast.SelectorExpr]*types.Selection),
+				FileVersions: make(map[*ast.File]string),
+			},
+		}
+		for _, path := range pkg.CompiledGoFiles {
+			var src any
+			if content, ok := cfg.Overlay[path]; ok {
+				src = content
+			}
+			file, err := parser.ParseFile(cfg.Fset, path, src, mode)
+			if err != nil {
+				return nil, err
+			}
+			c.files = append(c.files, file)
+		}
+		conf := types.Config{
+			// Imports are resolved by the package
+			// because test variants differ by ID only
+			Importer: importer.ForCompiler(cfg.Fset, "gc", func(path string) (io.ReadCloser, error) {
+				imp := pkg.Imports[path]
+				if imp == nil || imp.ExportFile == "" {
+					return nil, errs.Errorf("no export data for %s", path)
+				}
+				return os.Open(imp.ExportFile)
+			}),
+			Sizes: types.SizesFor("gc", runtime.GOARCH),
+			Error: func(err error) {
+				// Type errors are collected, the others
+				// are already reported by go list
+				if terr, ok := err.(types.Error); ok {
+					pkg.Errors = append(pkg.Errors, packages.Error{
+						Pos:  terr.Fset.Position(terr.Pos).String(),
+						Msg:  terr.Msg,
+						Kind: packages.TypeError,
+					})
+				}
+			},
+		}
+		_, _ = conf.Check(pkg.PkgPath, cfg.Fset, c.files, c.info) // Errors collected above
+		checked = append(checked, c)
+	}
+	return checked, nil
+}
+
+// loadTypeErrors type-checks the packages in dirs with the sources
+// of overlay replacing the files at their paths and returns their errors.
+// Like by loadSourceFiles the types of dependencies
+// are read from export data.
+func loadTypeErrors(dirs []string, overlay map[string][]byte, tests bool) (pkgErrs []packages.Error, err error) {
+	defer errs.WrapWithFuncParams(&err, dirs, overlay, tests)
+
+	cfg := &packages.Config{
+		Dir:     dirs[0],
+		Tests:   tests,
+		Overlay: overlay,
+	}
+	pkgs, err := checkPackages(cfg, parser.SkipObjectResolution, dirs...)
+	if err != nil {
+		return nil, err
+	}
+	for _, pkg := range pkgs {
+		pkgErrs = append(pkgErrs, pkg.Errors...)
+	}
+	return pkgErrs, nil
+}
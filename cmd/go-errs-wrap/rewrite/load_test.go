@@ -0,0 +1,234 @@
+package rewrite
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestModule writes files to a temporary module example.com/m
+// that vendors a stub of go-errs and returns its directory.
+func writeTestModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/m\n\ngo 1.24\n\nrequire github.com/domonda/go-errs v1.0.0\n"
+	files["vendor/modules.txt"] = "# github.com/domonda/go-errs v1.0.0\n## explicit\ngithub.com/domonda/go-errs\n"
+	files["vendor/github.com/domonda/go-errs/errs.go"] = `package errs
+
+type Secret interface {
+	Secret() any
+	String() string
+}
+
+func KeepSecret(val any) Secret { return nil }
+
+func WrapWithFuncParams(resultVar *error, params ...any)   {}
+func WrapWith0FuncParams(resultVar *error)                 {}
+func WrapWith1FuncParam(resultVar *error, p0 any)          {}
+func WrapWith2FuncParams(resultVar *error, p0, p1 any)     {}
+func WrapWith3FuncParams(resultVar *error, p0, p1, p2 any) {}
+
+type Sentinel string
+
+func (s Sentinel) Error() string { return string(s) }
+
+func New(text string) error { return Sentinel(text) }
+`
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	}
+	return dir
+}
+
+func TestInsertTypeChecked(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"alias.go": `package m
+
+// Error is an alias of error
+type Error = error
+
+func Alias(id string) (err Error) {
+	return nil
+}
+`,
+		"dot.go": `package m
+
+import . "github.com/domonda/go-errs"
+
+type password string
+
+func (password) Secret() any    { return nil }
+func (password) String() string { return "***" }
+
+func Dot(pw password) (err error) {
+	return nil
+}
+
+func Replaced(user string, pw password) (err error) {
+	defer WrapWithFuncParams(&err, KeepSecret(pw))
+
+	return nil
+}
+`,
+		"sub/named.go": `package sub
+
+import goerrs "github.com/domonda/go-errs"
+
+func Named(x int) (err error) {
+	defer goerrs.WrapWith0FuncParams(&err)
+
+	return nil
+}
+`,
+		"tagged.go": `//go:build never
+
+package m
+
+func Tagged(x int) (err error) {
+	return nil
+}
+`,
+		"generated.go": `// Code generated by a test. DO NOT EDIT.
+
+package m
+
+func Generated(x int) (err error) {
+	return nil
+}
+`,
+		"testdata/skipped.go": `package skipped
+
+func Skipped(x int) (err error) {
+	return nil
+}
+`,
+	})
+	read := func(name string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	err := Insert(filepath.Join(dir, "..."), Options{})
+	require.NoError(t, err)
+
+	assert.Equal(t, `package m
+
+import "github.com/domonda/go-errs"
+
+// Error is an alias of error
+type Error = error
+
+func Alias(id string) (err Error) {
+	defer errs.WrapWith1FuncParam(&err, id)
+
+	return nil
+}
+`, read("alias.go"))
+	assert.Equal(t, `package m
+
+import . "github.com/domonda/go-errs"
+
+type password string
+
+func (password) Secret() any    { return nil }
+func (password) String() string { return "***" }
+
+func Dot(pw password) (err error) {
+	defer WrapWith1FuncParam(&err, pw)
+
+	return nil
+}
+
+func Replaced(user string, pw password) (err error) {
+	defer WrapWithFuncParams(&err, KeepSecret(pw))
+
+	return nil
+}
+`, read("dot.go"), "dot-imported wrap of Replaced is recognized")
+	assert.Equal(t, `//go:build never
+
+package m
+
+import "github.com/domonda/go-errs"
+
+func Tagged(x int) (err error) {
+	defer errs.WrapWith1FuncParam(&err, x)
+
+	return nil
+}
+`, read("tagged.go"), "files excluded by build constraints are processed")
+	assert.NotContains(t, read("generated.go"), "defer", "generated files are skipped")
+	assert.NotContains(t, read("testdata/skipped.go"), "defer", "testdata is skipped like by go list")
+
+	err = Replace(dir, Options{})
+	require.NoError(t, err)
+
+	assert.Contains(t, read("dot.go"), "defer WrapWithFuncParams(&err, user, pw)\n",
+		"KeepSecret is not needed for parameters implementing errs.Secret")
+	assert.Contains(t, read("sub/named.go"), "defer goerrs.WrapWith0FuncParams(&err)\n",
+		"sub-directories are not processed without /...")
+
+	err = Replace(filepath.Join(dir, "sub"), Options{})
+	require.NoError(t, err)
+
+	assert.Equal(t, `package sub
+
+import goerrs "github.com/domonda/go-errs"
+
+func Named(x int) (err error) {
+	defer goerrs.WrapWith1FuncParam(&err, x)
+
+	return nil
+}
+`, read("sub/named.go"), "named import is used and not imported again")
+}
+
+func TestLoadSourceFilesWithoutModule(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"a.go":              "package a\n",
+		"a_test.go":         "package a\n",
+		"sub/b.go":          "package sub\n",
+		"testdata/c.go":     "package c\n",
+		".hidden/d.go":      "package d\n",
+		"generated.go":      "// Code generated by a test. DO NOT EDIT.\n\npackage a\n",
+		"vendor/pkg/vnd.go": "package pkg\n",
+	} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	}
+
+	paths := func(files []*sourceFile) (paths []string) {
+		for _, f := range files {
+			assert.Nil(t, f.info, "no type information without module")
+			rel, err := filepath.Rel(dir, f.path)
+			require.NoError(t, err)
+			paths = append(paths, filepath.ToSlash(rel))
+		}
+		return paths
+	}
+
+	files, err := loadSourceFiles(dir, false, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.go", "generated.go"}, paths(files), "generated files are skipped by process")
+
+	files, err = loadSourceFiles(filepath.Join(dir, "..."), false, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.go", "generated.go", "sub/b.go"}, paths(files))
+
+	files, err = loadSourceFiles(dir, true, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.go", "a_test.go", "generated.go"}, paths(files))
+
+	_, err = loadSourceFiles(filepath.Join(dir, "missing.go"), false, nil)
+	assert.Error(t, err)
+}
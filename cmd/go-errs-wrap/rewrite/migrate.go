@@ -0,0 +1,439 @@
+package rewrite
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/ungerik/go-astvisit"
+	"golang.org/x/tools/go/ast/astutil"
+
+	"github.com/domonda/go-errs"
+)
+
+// pkgErrorsPath is the import path of github.com/pkg/errors.
+const pkgErrorsPath = "github.com/pkg/errors"
+
+// Migrate rewrites the usages of github.com/pkg/errors
+// in Go source files at the given path to go-errs:
+//
+//	errors.New(text)                      errs.New(text)
+//	errors.Errorf(format, args...)        errs.Errorf(format, args...)
+//	errors.Wrap(err, "message")           errs.Errorf("message: %w", err)
+//	errors.Wrapf(err, "format", args...)  errs.Errorf("format: %w", args..., err)
+//	errors.WithMessage(err, "message")    errs.Errorf("message: %w", err)
+//	errors.WithStack(err)                 errs.WrapWithCallStack(err)
+//	errors.Cause(err)                     errs.Root(err)
+//
+// Messages that are not string literals are passed as arguments
+// for a "%s: %w" format. Because errors.Wrap and its variants return nil
+// for a nil error but errs.Errorf doesn't, they are only rewritten
+// within an if statement checking that the error variable is not nil,
+// like in if err != nil { return errors.Wrap(err, "message") },
+// else a warning is printed to stderr.
+// With Options.FmtErrorf, fmt.Errorf calls with a constant format
+// containing the %w verb are rewritten to errs.Errorf too.
+//
+// errors.Is, errors.As and errors.Unwrap are the same in the standard library,
+// the import of github.com/pkg/errors is replaced with the errors package
+// if nothing else of it is used, else it is kept and a warning is printed
+// for every function without go-errs equivalent.
+// Imports that are no longer used are removed.
+//
+// The sourcePath is used like by Replace.
+func Migrate(sourcePath string, options Options) (err error) {
+	defer errs.WrapWithFuncParams(&err, sourcePath, options)
+
+	return process(sourcePath, options, modeMigrate)
+}
+
+// migration holds the state of migrating a file, see migrateFile.
+type migration struct {
+	fset          *token.FileSet
+	file          *ast.File
+	info          *types.Info // nil without type information
+	source        []byte
+	fmtErrorf     bool
+	errsQualifier string
+	imports       astvisit.Imports
+
+	// migrated are the selectors of the migrated calls
+	migrated map[*ast.SelectorExpr]bool
+}
+
+// migrateFile returns the replacements migrating the usages of
+// github.com/pkg/errors and optionally fmt.Errorf in astFile to go-errs
+// and the imports they need, see Migrate.
+// Functions matching the skipFunctions patterns are not changed.
+func migrateFile(fset *token.FileSet, astFile *ast.File, info *types.Info, source []byte, fmtErrorf bool, skipFunctions []string, verboseOut io.Writer) (replacements replacements, imports astvisit.Imports, err error) {
+	defer errs.WrapWithFuncParams(&err, fset, astFile, info, source, fmtErrorf, skipFunctions, verboseOut)
+
+	if IsFileIgnored(astFile) {
+		if verboseOut != nil {
+			fmt.Fprintf(verboseOut, "%s: ignoring file with %s\n", fset.Position(astFile.Package), IgnoreDirective)
+		}
+		return nil, nil, nil
+	}
+
+	m := &migration{
+		fset:          fset,
+		file:          astFile,
+		info:          info,
+		source:        source,
+		fmtErrorf:     fmtErrorf,
+		errsQualifier: qualifier(astFile),
+		imports:       make(astvisit.Imports),
+		migrated:      make(map[*ast.SelectorExpr]bool),
+	}
+
+	var keepImport, stdlibCalls bool
+	for _, decl := range astFile.Decls {
+		skip := false
+		if fd, ok := decl.(*ast.FuncDecl); ok {
+			switch {
+			case FuncDirectives(fd.Doc).Ignore:
+				if verboseOut != nil {
+					fmt.Fprintf(verboseOut, "%s: ignoring function %s with %s\n", fset.Position(fd.Pos()), fd.Name.Name, IgnoreDirective)
+				}
+				skip = true
+			case matchFuncName(skipFunctions, funcDeclName(fd)):
+				skip = true
+			}
+		}
+		if !skip {
+			ast.Inspect(decl, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				node, replacement, rule, debugID := m.migrateCall(call)
+				if node == nil {
+					return true
+				}
+				if verboseOut != nil {
+					fmt.Fprintf(verboseOut, "%s: replacing %s with %s\n", fset.Position(call.Pos()), formatNode(fset, node), replacement)
+				}
+				replacements.add(rule, node, replacement, debugID)
+				// Calls within a replaced call are part of its replacement
+				return node != call
+			})
+		}
+
+		// Check the remaining references to github.com/pkg/errors
+		ast.Inspect(decl, func(n ast.Node) bool {
+			sel, ok := n.(*ast.SelectorExpr)
+			if !ok || m.migrated[sel] {
+				return true
+			}
+			pkgPath, name := m.pkgFunc(sel)
+			if pkgPath != pkgErrorsPath {
+				return true
+			}
+			switch name {
+			case "Is", "As", "Unwrap":
+				stdlibCalls = true
+			case "Wrap", "Wrapf", "WithMessage", "WithMessagef":
+				// Warned about by migrateCall
+				keepImport = true
+			default:
+				if !skip {
+					fmt.Fprintf(os.Stderr, "warning: %s: %s has no go-errs equivalent, %s stays imported\n",
+						fset.Position(sel.Pos()), formatNode(fset, sel), pkgErrorsPath,
+					)
+				}
+				keepImport = true
+			}
+			return true
+		})
+	}
+
+	if stdlibCalls && !keepImport {
+		m.replacePkgErrorsImport(&replacements)
+	}
+	if len(replacements) == 0 {
+		return nil, nil, nil
+	}
+	if len(m.migrated) > 0 && !importsErrs(astFile) {
+		m.imports[errsImportPath] = struct{}{}
+	}
+	return replacements, m.imports, nil
+}
+
+// replacePkgErrorsImport adds the replacements of the github.com/pkg/errors
+// import with the errors package of the standard library.
+// Within an import block an import named errors is removed and
+// the standard library import added to sort it into its group,
+// else the import path is replaced keeping the name for its references.
+func (m *migration) replacePkgErrorsImport(replacements *replacements) {
+	const debugID = "errors for " + pkgErrorsPath
+	for _, decl := range m.file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			imp := spec.(*ast.ImportSpec)
+			if imp.Path.Value != strconv.Quote(pkgErrorsPath) {
+				continue
+			}
+			if importName(imp) != "errors" || !gen.Lparen.IsValid() {
+				replacements.add(RulePkgErrors, imp.Path, `"errors"`, debugID)
+				continue
+			}
+			replacements.add(RulePkgErrors, imp, nil, debugID)
+			m.imports[`"errors"`] = struct{}{}
+		}
+	}
+}
+
+// migrateCall returns the node of call to replace with the returned
+// go-errs replacement and the rule of the replacement,
+// or a nil node if call is not migrated.
+// The node is call or the selector of the called function.
+func (m *migration) migrateCall(call *ast.CallExpr) (node ast.Node, replacement, rule, debugID string) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return nil, "", "", ""
+	}
+	pkgPath, name := m.pkgFunc(sel)
+	var errsFunc string
+	switch {
+	case pkgPath == pkgErrorsPath:
+		rule = RulePkgErrors
+		switch name {
+		case "New", "Errorf":
+			errsFunc = name
+		case "WithStack":
+			errsFunc = "WrapWithCallStack"
+		case "Cause":
+			errsFunc = "Root"
+		case "Wrap", "Wrapf", "WithMessage", "WithMessagef":
+			replacement = m.wrapReplacement(call, name)
+			if replacement == "" {
+				return nil, "", "", ""
+			}
+			m.migrated[sel] = true
+			return call, replacement, rule, "errs.Errorf for errors." + name
+		default:
+			return nil, "", "", ""
+		}
+	case pkgPath == "fmt" && name == "Errorf" && m.fmtErrorf && len(call.Args) > 0 && m.hasWrapVerb(call.Args[0]):
+		rule = RuleFmtErrorf
+		errsFunc = "Errorf"
+	default:
+		return nil, "", "", ""
+	}
+	m.migrated[sel] = true
+	return sel, m.errsQualifier + errsFunc, rule, "errs." + errsFunc + " for " + path.Base(pkgPath) + "." + name
+}
+
+// wrapReplacement returns the errs.Errorf call replacing call of the
+// github.com/pkg/errors function name, errors.Wrap or one of its variants,
+// or an empty string if the error argument may be nil.
+func (m *migration) wrapReplacement(call *ast.CallExpr, name string) string {
+	if len(call.Args) < 2 {
+		return ""
+	}
+	if !m.isCheckedNotNil(call, call.Args[0]) {
+		fmt.Fprintf(os.Stderr, "warning: %s: %s not migrated because %s may be nil, errs.Errorf doesn't return nil for a nil error\n",
+			m.fset.Position(call.Pos()), formatNode(m.fset, call.Fun), formatNode(m.fset, call.Args[0]),
+		)
+		return ""
+	}
+	errArg := m.text(call.Args[0])
+	msgArgs := make([]string, len(call.Args)-1)
+	for i, arg := range call.Args[1:] {
+		msgArgs[i] = m.text(arg)
+	}
+	if call.Ellipsis.IsValid() {
+		msgArgs[len(msgArgs)-1] += "..."
+	}
+
+	formatted := strings.HasSuffix(name, "f")
+	var args []string
+	if lit, ok := appendToStringLit(call.Args[1], !formatted, ": %w"); ok && (!formatted || !call.Ellipsis.IsValid()) {
+		// errs.Errorf("message: %w", err) or errs.Errorf("format: %w", args..., err)
+		args = append(append(args, lit), msgArgs[1:]...)
+	} else if formatted {
+		// errs.Errorf("%s: %w", fmt.Sprintf(format, args...), err)
+		args = []string{`"%s: %w"`, m.fmtQualifier() + "Sprintf(" + strings.Join(msgArgs, ", ") + ")"}
+	} else {
+		// errs.Errorf("%s: %w", message, err)
+		args = []string{`"%s: %w"`, msgArgs[0]}
+	}
+	args = append(args, errArg)
+	return m.errsQualifier + "Errorf(" + strings.Join(args, ", ") + ")"
+}
+
+// text returns the source code of node with
+// the calls within it migrated, see migrateCall.
+func (m *migration) text(node ast.Node) string {
+	var b strings.Builder
+	pos := node.Pos()
+	ast.Inspect(node, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		replaced, replacement, _, _ := m.migrateCall(call)
+		if replaced == nil {
+			return true
+		}
+		b.Write(m.source[m.offset(pos):m.offset(replaced.Pos())])
+		b.WriteString(replacement)
+		pos = replaced.End()
+		return replaced != call
+	})
+	b.Write(m.source[m.offset(pos):m.offset(node.End())])
+	return b.String()
+}
+
+// offset returns the offset of pos in the source.
+func (m *migration) offset(pos token.Pos) int {
+	return m.fset.Position(pos).Offset
+}
+
+// pkgFunc returns the import path of the package
+// and the name of the function that sel refers to,
+// or empty strings if sel is not qualified with an imported package.
+func (m *migration) pkgFunc(sel *ast.SelectorExpr) (pkgPath, name string) {
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return "", ""
+	}
+	if m.info != nil {
+		switch obj := m.info.Uses[ident].(type) {
+		case *types.PkgName:
+			return obj.Imported().Path(), sel.Sel.Name
+		case nil:
+			// Unresolved because of type errors
+		default:
+			return "", ""
+		}
+	}
+	for _, imp := range m.file.Imports {
+		if importName(imp) == ident.Name {
+			importPath, _ := strconv.Unquote(imp.Path.Value)
+			return importPath, sel.Sel.Name
+		}
+	}
+	return "", ""
+}
+
+// fmtQualifier returns the prefix for references to the fmt package,
+// adding its import if necessary.
+func (m *migration) fmtQualifier() string {
+	for _, imp := range m.file.Imports {
+		if imp.Path.Value == `"fmt"` && importName(imp) != "_" {
+			if name := importName(imp); name != "." {
+				return name + "."
+			}
+			return ""
+		}
+	}
+	m.imports[`"fmt"`] = struct{}{}
+	return "fmt."
+}
+
+// hasWrapVerb reports whether format is a constant string containing %w.
+func (m *migration) hasWrapVerb(format ast.Expr) bool {
+	if m.info != nil {
+		if tv, ok := m.info.Types[format]; ok && tv.Value != nil && tv.Value.Kind() == constant.String {
+			return strings.Contains(constant.StringVal(tv.Value), "%w")
+		}
+	}
+	lit, ok := format.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return false
+	}
+	value, err := strconv.Unquote(lit.Value)
+	return err == nil && strings.Contains(value, "%w")
+}
+
+// isCheckedNotNil reports whether expr is a variable that an if statement
+// enclosing call within the same function checks to be not nil,
+// like err in if err != nil { return errors.Wrap(err, "message") }.
+func (m *migration) isCheckedNotNil(call *ast.CallExpr, expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	path, _ := astutil.PathEnclosingInterval(m.file, call.Pos(), call.End())
+	for i, node := range path {
+		switch node := node.(type) {
+		case *ast.FuncLit, *ast.FuncDecl:
+			return false
+		case *ast.IfStmt:
+			if i > 0 && path[i-1] == node.Body && m.checksNotNil(node.Cond, ident) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checksNotNil reports whether cond is true only if ident is not nil.
+func (m *migration) checksNotNil(cond ast.Expr, ident *ast.Ident) bool {
+	c, ok := ast.Unparen(cond).(*ast.BinaryExpr)
+	if !ok {
+		return false
+	}
+	switch c.Op {
+	case token.LAND:
+		return m.checksNotNil(c.X, ident) || m.checksNotNil(c.Y, ident)
+	case token.NEQ:
+		return m.isSameVar(c.X, ident) && isNilIdent(c.Y) || isNilIdent(c.X) && m.isSameVar(c.Y, ident)
+	}
+	return false
+}
+
+// isSameVar reports whether expr refers to the same variable as ident.
+func (m *migration) isSameVar(expr ast.Expr, ident *ast.Ident) bool {
+	x, ok := ast.Unparen(expr).(*ast.Ident)
+	if !ok || x.Name != ident.Name {
+		return false
+	}
+	if m.info != nil {
+		if obj := m.info.ObjectOf(x); obj != nil {
+			return obj == m.info.ObjectOf(ident)
+		}
+	}
+	return true
+}
+
+func isNilIdent(expr ast.Expr) bool {
+	ident, ok := ast.Unparen(expr).(*ast.Ident)
+	return ok && ident.Name == "nil"
+}
+
+// appendToStringLit returns the string literal expr with suffix appended
+// and % characters of the literal escaped as %% if escape is true,
+// or ok false if expr is not a string literal.
+func appendToStringLit(expr ast.Expr, escape bool, suffix string) (lit string, ok bool) {
+	basic, ok := expr.(*ast.BasicLit)
+	if !ok || basic.Kind != token.STRING {
+		return "", false
+	}
+	quote, body := basic.Value[:1], basic.Value[1:len(basic.Value)-1]
+	if escape {
+		body = strings.ReplaceAll(body, "%", "%%")
+	}
+	return quote + body + suffix + quote, true
+}
+
+// importName returns the name a package is imported with,
+// the last element of the import path if the import is not named.
+func importName(imp *ast.ImportSpec) string {
+	if imp.Name != nil {
+		return imp.Name.Name
+	}
+	importPath, _ := strconv.Unquote(imp.Path.Value)
+	return path.Base(importPath)
+}
@@ -0,0 +1,265 @@
+package rewrite
+
+import (
+	"errors"
+	"go/ast"
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrate(t *testing.T) {
+	input := `package test
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+func Load(path string) (err error) {
+	err = open(path)
+	if err != nil {
+		return errors.Wrap(err, "can't open 100%")
+	}
+	if err := open(path); err != nil && path != "" {
+		return errors.Wrapf(err, "open %s", errors.Cause(errors.New("x")))
+	}
+	if err != nil {
+		return errors.WithStack(errors.Errorf("nested %d", 1))
+	}
+	if msg := "m"; err != nil {
+		return errors.WithMessage(err, msg)
+	}
+	if err != nil {
+		args := []any{1}
+		return errors.Wrapf(err, "x %d", args...)
+	}
+	if errors.Is(err, fmt.Errorf("unchanged: %w", err)) {
+		return nil
+	}
+	return nil
+}
+
+func open(string) error { return nil }
+`
+	expected := `package test
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/domonda/go-errs"
+)
+
+func Load(path string) (err error) {
+	err = open(path)
+	if err != nil {
+		return errs.Errorf("can't open 100%%: %w", err)
+	}
+	if err := open(path); err != nil && path != "" {
+		return errs.Errorf("open %s: %w", errs.Root(errs.New("x")), err)
+	}
+	if err != nil {
+		return errs.WrapWithCallStack(errs.Errorf("nested %d", 1))
+	}
+	if msg := "m"; err != nil {
+		return errs.Errorf("%s: %w", msg, err)
+	}
+	if err != nil {
+		args := []any{1}
+		return errs.Errorf("%s: %w", fmt.Sprintf("x %d", args...), err)
+	}
+	if errors.Is(err, fmt.Errorf("unchanged: %w", err)) {
+		return nil
+	}
+	return nil
+}
+
+func open(string) error { return nil }
+`
+	tmpDir := t.TempDir()
+	inputFile := filepath.Join(tmpDir, "input.go")
+	require.NoError(t, os.WriteFile(inputFile, []byte(input), 0644))
+
+	err := Migrate(inputFile, Options{})
+	require.NoError(t, err)
+	output, err := os.ReadFile(inputFile)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(output))
+}
+
+func TestMigrateFmtErrorf(t *testing.T) {
+	input := `package test
+
+import "fmt"
+
+const format = "constant: %w"
+
+func F(err error) error {
+	if err != nil {
+		return fmt.Errorf("wrapped: %w", err)
+	}
+	_ = fmt.Errorf(format, err)
+	return fmt.Errorf("not wrapped: %v", err)
+}
+`
+	expected := `package test
+
+import (
+	"fmt"
+
+	"github.com/domonda/go-errs"
+)
+
+const format = "constant: %w"
+
+func F(err error) error {
+	if err != nil {
+		return errs.Errorf("wrapped: %w", err)
+	}
+	_ = errs.Errorf(format, err)
+	return fmt.Errorf("not wrapped: %v", err)
+}
+`
+	tmpDir := t.TempDir()
+	inputFile := filepath.Join(tmpDir, "input.go")
+	require.NoError(t, os.WriteFile(inputFile, []byte(input), 0644))
+
+	err := Migrate(inputFile, Options{})
+	require.NoError(t, err)
+	output, err := os.ReadFile(inputFile)
+	require.NoError(t, err)
+	assert.Equal(t, input, string(output), "fmt.Errorf is only migrated with fmtErrorf")
+
+	err = Migrate(inputFile, Options{FmtErrorf: true})
+	require.NoError(t, err)
+	output, err = os.ReadFile(inputFile)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(output))
+}
+
+func TestMigrateKeepsImport(t *testing.T) {
+	input := `package test
+
+import "github.com/pkg/errors"
+
+func F(err error) error {
+	if errors.Is(err, errors.New("x")) {
+		return errors.Wrap(err, "unchecked")
+	}
+	return nil
+}
+
+// go-errs-wrap:ignore
+func Ignored(err error) error {
+	return errors.WithStack(err)
+}
+`
+	expected := `package test
+
+import (
+	"github.com/domonda/go-errs"
+	"github.com/pkg/errors"
+)
+
+func F(err error) error {
+	if errors.Is(err, errs.New("x")) {
+		return errors.Wrap(err, "unchecked")
+	}
+	return nil
+}
+
+// go-errs-wrap:ignore
+func Ignored(err error) error {
+	return errors.WithStack(err)
+}
+`
+	tmpDir := t.TempDir()
+	inputFile := filepath.Join(tmpDir, "input.go")
+	require.NoError(t, os.WriteFile(inputFile, []byte(input), 0644))
+
+	err := Migrate(inputFile, Options{})
+	require.NoError(t, err)
+	output, err := os.ReadFile(inputFile)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(output), "error that may be nil and ignored function")
+}
+
+func TestMigrateStdlibImport(t *testing.T) {
+	for name, test := range map[string]struct{ input, expected string }{
+		"single": {
+			input:    "package test\n\nimport \"github.com/pkg/errors\"\n\nvar X = errors.Is(nil, nil)\n",
+			expected: "package test\n\nimport \"errors\"\n\nvar X = errors.Is(nil, nil)\n",
+		},
+		"named": {
+			input:    "package test\n\nimport (\n\t\"fmt\"\n\n\tpe \"github.com/pkg/errors\"\n)\n\nvar X = pe.Unwrap(fmt.Errorf(\"x\"))\n",
+			expected: "package test\n\nimport (\n\t\"fmt\"\n\n\tpe \"errors\"\n)\n\nvar X = pe.Unwrap(fmt.Errorf(\"x\"))\n",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			inputFile := filepath.Join(t.TempDir(), "input.go")
+			require.NoError(t, os.WriteFile(inputFile, []byte(test.input), 0644))
+
+			err := Migrate(inputFile, Options{})
+			require.NoError(t, err)
+			output, err := os.ReadFile(inputFile)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, string(output))
+		})
+	}
+}
+
+func TestMigrateValidate(t *testing.T) {
+	input := `package test
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+func F(err error) error {
+	if err != nil {
+		return errors.Wrap(err, "x")
+	}
+	return fmt.Errorf("y: %w", errors.Cause(err))
+}
+`
+	inputFile := filepath.Join(t.TempDir(), "input.go")
+	require.NoError(t, os.WriteFile(inputFile, []byte(input), 0644))
+
+	err := Migrate(inputFile, Options{FmtErrorf: true, Validate: true})
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	require.Len(t, validationErr.Findings, 3)
+	assert.EqualError(t, validationErr, "found 3 issue(s)")
+	assert.Equal(t, RulePkgErrors, validationErr.Findings[0].Rule)
+	assert.Equal(t, "replace errors.Wrap with errs.Errorf", validationErr.Findings[0].Message)
+	assert.Equal(t, `errs.Errorf("x: %w", err)`, validationErr.Findings[0].Replacement)
+	assert.Equal(t, RuleFmtErrorf, validationErr.Findings[1].Rule)
+	assert.Equal(t, "errs.Errorf", validationErr.Findings[1].Replacement)
+	assert.Equal(t, RulePkgErrors, validationErr.Findings[2].Rule)
+	assert.Equal(t, "errs.Root", validationErr.Findings[2].Replacement)
+
+	output, err := os.ReadFile(inputFile)
+	require.NoError(t, err)
+	assert.Equal(t, input, string(output), "validate doesn't modify files")
+}
+
+func TestAppendToStringLit(t *testing.T) {
+	lit, ok := appendToStringLit(&ast.BasicLit{Kind: token.STRING, Value: `"100%\n"`}, true, ": %w")
+	assert.True(t, ok)
+	assert.Equal(t, `"100%%\n: %w"`, lit)
+
+	lit, ok = appendToStringLit(&ast.BasicLit{Kind: token.STRING, Value: "`%d`"}, false, ": %w")
+	assert.True(t, ok)
+	assert.Equal(t, "`%d: %w`", lit)
+
+	_, ok = appendToStringLit(ast.NewIdent("msg"), false, ": %w")
+	assert.False(t, ok)
+}
@@ -0,0 +1,315 @@
+package rewrite
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/ungerik/go-astvisit"
+
+	"github.com/domonda/go-errs"
+)
+
+// Rules of the findings reported in validate mode.
+const (
+	RuleMissingWrap       = "missing-wrap"         // insert: function without defer errs.Wrap statement
+	RuleStaleWrap         = "stale-wrap"           // replace: defer errs.Wrap statement not matching the function
+	RuleWrapMarker        = "wrap-marker"          // replace: //#wrap-result-err marker to replace
+	RuleRemoveWrap        = "remove-wrap"          // remove: defer errs.Wrap statement or marker to remove
+	RuleUnnamedResult     = "unnamed-error-result" // insert and replace with nameResults: anonymous error result
+	RuleMissingKeepSecret = "missing-keepsecret"   // insert and replace with secrets: sensitive parameter without errs.KeepSecret
+	RulePkgErrors         = "pkg-errors"           // migrate: usage of github.com/pkg/errors
+	RuleFmtErrorf         = "fmt-errorf"           // migrate with fmtErrorf: fmt.Errorf wrapping an error
+	RuleVarSentinel       = "var-sentinel"         // sentinels: error variable that can be an errs.Sentinel constant
+)
+
+// ruleDescriptions are the short descriptions of the rules for SARIF reports.
+var ruleDescriptions = map[string]string{
+	RuleMissingWrap:       "Function with a named error result is missing a defer errs.Wrap statement",
+	RuleStaleWrap:         "Deferred errs.Wrap statement doesn't match the function parameters",
+	RuleWrapMarker:        "//#wrap-result-err marker is not replaced with a defer errs.Wrap statement",
+	RuleRemoveWrap:        "Deferred errs.Wrap statement or marker is not removed",
+	RuleUnnamedResult:     "Error result is not named and can't be wrapped",
+	RuleMissingKeepSecret: "Sensitive parameter is passed without errs.KeepSecret",
+	RulePkgErrors:         "github.com/pkg/errors is used instead of go-errs",
+	RuleFmtErrorf:         "fmt.Errorf wraps an error instead of errs.Errorf",
+	RuleVarSentinel:       "Sentinel error is declared as variable instead of errs.Sentinel constant",
+}
+
+// Finding is an issue found in validate mode:
+// a replacement that the command would apply.
+type Finding struct {
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndLine   int    `json:"endLine"`
+	EndColumn int    `json:"endColumn"`
+	Rule      string `json:"rule"`
+	Message   string `json:"message"`
+	// Replacement is the source code that replaces the range
+	// from Line:Column to EndLine:EndColumn, empty for removals.
+	Replacement string `json:"replacement"`
+	// Edits are further edits of the file that the replacements
+	// of its findings need, like the import of go-errs.
+	// They are only set for the first finding of a file.
+	Edits []Edit `json:"edits,omitempty"`
+}
+
+// Edit replaces the range from Line:Column to EndLine:EndColumn
+// of a file with Replacement.
+type Edit struct {
+	Line        int    `json:"line"`
+	Column      int    `json:"column"`
+	EndLine     int    `json:"endLine"`
+	EndColumn   int    `json:"endColumn"`
+	Replacement string `json:"replacement"`
+}
+
+// String returns the finding in the text format
+// of the -validate option: "file:line:column: message".
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", f.File, f.Line, f.Column, f.Message)
+}
+
+// ValidationError is returned in validate mode
+// if any issues were found.
+type ValidationError struct {
+	Findings []Finding
+}
+
+func (e *ValidationError) Error() string {
+	for _, f := range e.Findings {
+		switch f.Rule {
+		case RuleMissingWrap, RuleStaleWrap, RuleWrapMarker, RuleRemoveWrap:
+		default:
+			return fmt.Sprintf("found %d issue(s)", len(e.Findings))
+		}
+	}
+	return fmt.Sprintf("found %d missing error wrapper(s)", len(e.Findings))
+}
+
+// newFinding returns the finding for a replacement in validate mode.
+// It returns an error if the replacement has no known rule.
+func newFinding(fset *token.FileSet, repl replacement) (Finding, error) {
+	if ruleDescriptions[repl.rule] == "" {
+		return Finding{}, errs.Errorf("replacement %q has unknown rule %q", repl.DebugID, repl.rule)
+	}
+	finding := Finding{
+		Rule:    repl.rule,
+		Message: ruleMessage(repl.rule, repl.DebugID),
+	}
+	if repl.Node != nil {
+		start := fset.Position(repl.Node.Pos())
+		end := fset.Position(repl.Node.End())
+		finding.File = start.Filename
+		finding.Line, finding.Column = start.Line, start.Column
+		finding.EndLine, finding.EndColumn = end.Line, end.Column
+	}
+	switch r := repl.Replacement.(type) {
+	case string:
+		finding.Replacement = r
+	case []byte:
+		finding.Replacement = string(r)
+	}
+	return finding, nil
+}
+
+// importEdits returns the edits adding the imports
+// that astFile doesn't have yet after its package clause.
+func importEdits(fset *token.FileSet, astFile *ast.File, imports astvisit.Imports) []Edit {
+	var b strings.Builder
+	for _, path := range slices.Sorted(maps.Keys(imports)) {
+		if !strings.HasPrefix(path, `"`) {
+			path = strconv.Quote(path)
+		}
+		if !slices.ContainsFunc(astFile.Imports, func(imp *ast.ImportSpec) bool { return imp.Path.Value == path }) {
+			b.WriteString("\n\nimport " + path)
+		}
+	}
+	if b.Len() == 0 {
+		return nil
+	}
+	pos := fset.Position(astFile.Name.End())
+	return []Edit{{
+		Line:        pos.Line,
+		Column:      pos.Column,
+		EndLine:     pos.Line,
+		EndColumn:   pos.Column,
+		Replacement: b.String(),
+	}}
+}
+
+// ruleMessage returns the message of a finding
+// for a replacement with debugID of the rule.
+func ruleMessage(rule, debugID string) string {
+	switch rule {
+	case RuleUnnamedResult:
+		return "unnamed error result can't be wrapped"
+	case RulePkgErrors, RuleFmtErrorf, RuleVarSentinel:
+		// The debugIDs are like "errs.Errorf for errors.Wrap"
+		replacement, replaced, _ := strings.Cut(debugID, " for ")
+		return "replace " + replaced + " with " + replacement
+	}
+	return "missing " + debugID
+}
+
+// WriteJSON writes the findings as JSON array to w
+// with file paths relative to the working directory.
+func WriteJSON(w io.Writer, findings []Finding) error {
+	relative := make([]Finding, len(findings))
+	for i, f := range findings {
+		f.File = relativePath(f.File)
+		relative[i] = f
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(relative)
+}
+
+// WriteSARIF writes the findings as SARIF 2.1.0 log to w
+// with file paths relative to the working directory,
+// which should be the root of the repository for code scanning.
+// Replacements are included as fixes.
+func WriteSARIF(w io.Writer, findings []Finding) (err error) {
+	defer errs.WrapWithFuncParams(&err, w, findings)
+
+	type (
+		region struct {
+			StartLine   int `json:"startLine"`
+			StartColumn int `json:"startColumn"`
+			EndLine     int `json:"endLine,omitempty"`
+			EndColumn   int `json:"endColumn,omitempty"`
+		}
+		artifactLocation struct {
+			URI       string `json:"uri"`
+			URIBaseID string `json:"uriBaseId,omitempty"`
+		}
+		message struct {
+			Text string `json:"text"`
+		}
+		replacement struct {
+			DeletedRegion   region   `json:"deletedRegion"`
+			InsertedContent *message `json:"insertedContent,omitempty"`
+		}
+		artifactChange struct {
+			ArtifactLocation artifactLocation `json:"artifactLocation"`
+			Replacements     []replacement    `json:"replacements"`
+		}
+		fix struct {
+			Description     message          `json:"description"`
+			ArtifactChanges []artifactChange `json:"artifactChanges"`
+		}
+		physicalLocation struct {
+			ArtifactLocation artifactLocation `json:"artifactLocation"`
+			Region           region           `json:"region"`
+		}
+		location struct {
+			PhysicalLocation physicalLocation `json:"physicalLocation"`
+		}
+		result struct {
+			RuleID    string     `json:"ruleId"`
+			Level     string     `json:"level"`
+			Message   message    `json:"message"`
+			Locations []location `json:"locations"`
+			Fixes     []fix      `json:"fixes,omitempty"`
+		}
+		rule struct {
+			ID               string  `json:"id"`
+			ShortDescription message `json:"shortDescription"`
+		}
+	)
+
+	rules := make([]rule, 0, len(ruleDescriptions))
+	for _, id := range []string{RuleMissingWrap, RuleStaleWrap, RuleWrapMarker, RuleRemoveWrap, RuleUnnamedResult, RuleMissingKeepSecret, RulePkgErrors, RuleFmtErrorf, RuleVarSentinel} {
+		rules = append(rules, rule{ID: id, ShortDescription: message{Text: ruleDescriptions[id]}})
+	}
+	results := make([]result, 0, len(findings))
+	for _, f := range findings {
+		loc := artifactLocation{URI: filepath.ToSlash(relativePath(f.File)), URIBaseID: "%SRCROOT%"}
+		if filepath.IsAbs(loc.URI) {
+			// File outside of the working directory
+			loc = artifactLocation{URI: "file://" + loc.URI}
+		}
+		reg := region{StartLine: f.Line, StartColumn: f.Column, EndLine: f.EndLine, EndColumn: f.EndColumn}
+		res := result{
+			RuleID:    f.Rule,
+			Level:     "error",
+			Message:   message{Text: f.Message},
+			Locations: []location{{PhysicalLocation: physicalLocation{ArtifactLocation: loc, Region: reg}}},
+		}
+		repl := replacement{DeletedRegion: reg}
+		if f.Replacement != "" {
+			repl.InsertedContent = &message{Text: f.Replacement}
+		}
+		replacements := []replacement{repl}
+		for _, e := range f.Edits {
+			replacements = append(replacements, replacement{
+				DeletedRegion:   region{StartLine: e.Line, StartColumn: e.Column, EndLine: e.EndLine, EndColumn: e.EndColumn},
+				InsertedContent: &message{Text: e.Replacement},
+			})
+		}
+		res.Fixes = []fix{{
+			Description:     message{Text: f.Message},
+			ArtifactChanges: []artifactChange{{ArtifactLocation: loc, Replacements: replacements}},
+		}}
+		results = append(results, res)
+	}
+
+	log := map[string]any{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []any{map[string]any{
+			"tool": map[string]any{
+				"driver": map[string]any{
+					"name":           "go-errs-wrap",
+					"informationUri": "https://pkg.go.dev/github.com/domonda/go-errs/cmd/go-errs-wrap",
+					"rules":          rules,
+				},
+			},
+			"results": results,
+		}},
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(log)
+}
+
+// writeDiff writes the unified diff between the source
+// and rewritten content of the file at path to w,
+// with a/ and b/ prefixed paths relative to the working directory
+// like git diff, so that it can be applied with git apply.
+func writeDiff(w io.Writer, path string, source, rewritten []byte) error {
+	name := filepath.ToSlash(relativePath(path))
+	return difflib.WriteUnifiedDiff(w, difflib.UnifiedDiff{
+		A:        difflib.SplitLines(strings.TrimSuffix(string(source), "\n")),
+		B:        difflib.SplitLines(strings.TrimSuffix(string(rewritten), "\n")),
+		FromFile: "a/" + strings.TrimPrefix(name, "/"),
+		ToFile:   "b/" + strings.TrimPrefix(name, "/"),
+		Context:  3,
+	})
+}
+
+// relativePath returns path relative to the working directory
+// if it is within it, else path unchanged.
+func relativePath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil || !filepath.IsAbs(path) {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || !isInDir(path, wd) {
+		return path
+	}
+	return rel
+}
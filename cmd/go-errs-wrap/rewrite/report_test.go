@@ -0,0 +1,282 @@
+package rewrite
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"go/format"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const reportInputCode = `package test
+
+import "github.com/domonda/go-errs"
+
+func Missing(id string) (err error) {
+	return nil
+}
+
+func Stale(id string, n int) (err error) {
+	defer errs.WrapWith1FuncParam(&err, id)
+
+	return nil
+}
+`
+
+func TestNewFinding_Rule(t *testing.T) {
+	fset := token.NewFileSet()
+	var repls replacements
+	repls.add(RuleMissingWrap, nil, "x", "reworded debug message")
+	repls.add("unknown", nil, "x", "insert defer errs.Wrap")
+
+	finding, err := newFinding(fset, repls[0])
+	require.NoError(t, err)
+	assert.Equal(t, RuleMissingWrap, finding.Rule, "rule doesn't depend on the debugID")
+
+	_, err = newFinding(fset, repls[1])
+	assert.ErrorContains(t, err, `unknown rule "unknown"`)
+}
+
+func TestRuleMessage(t *testing.T) {
+	for _, tc := range []struct {
+		rule, debugID, want string
+	}{
+		{RuleMissingWrap, "insert defer errs.Wrap", "missing insert defer errs.Wrap"},
+		{RuleRemoveWrap, "remove //#wrap-result-err", "missing remove //#wrap-result-err"},
+		{RuleUnnamedResult, "name error result", "unnamed error result can't be wrapped"},
+		{RuleMissingKeepSecret, "errs.KeepSecret for sensitive param a", "missing errs.KeepSecret for sensitive param a"},
+		{RulePkgErrors, "errs.Errorf for errors.Wrap", "replace errors.Wrap with errs.Errorf"},
+		{RulePkgErrors, "errors for github.com/pkg/errors", "replace github.com/pkg/errors with errors"},
+		{RuleFmtErrorf, "errs.Errorf for fmt.Errorf", "replace fmt.Errorf with errs.Errorf"},
+		{RuleVarSentinel, "const errs.Sentinel for var ErrA, ErrB", "replace var ErrA, ErrB with const errs.Sentinel"},
+	} {
+		assert.Equal(t, tc.want, ruleMessage(tc.rule, tc.debugID), tc.debugID)
+		assert.NotEmpty(t, ruleDescriptions[tc.rule], tc.rule)
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputFile := filepath.Join(tmpDir, "input.go")
+	require.NoError(t, os.WriteFile(inputFile, []byte(reportInputCode), 0644))
+
+	err := Replace(inputFile, Options{Validate: true})
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr), "validate returns a *ValidationError")
+	require.Len(t, validationErr.Findings, 1)
+	finding := validationErr.Findings[0]
+	assert.Equal(t, inputFile, finding.File)
+	assert.Equal(t, 10, finding.Line)
+	assert.Equal(t, 2, finding.Column)
+	assert.Equal(t, 10, finding.EndLine)
+	assert.Equal(t, RuleStaleWrap, finding.Rule)
+	assert.Equal(t, "defer errs.WrapWith2FuncParams(&err, id, n)", finding.Replacement)
+	assert.Equal(t, inputFile+":10:2: missing replace defer errs.Wrap", finding.String())
+	assert.EqualError(t, validationErr, "found 1 missing error wrapper(s)")
+
+	err = Insert(inputFile, Options{Validate: true})
+	require.True(t, errors.As(err, &validationErr))
+	require.Len(t, validationErr.Findings, 1)
+	assert.Equal(t, RuleMissingWrap, validationErr.Findings[0].Rule)
+	assert.Equal(t, 6, validationErr.Findings[0].Line)
+
+	output, err := os.ReadFile(inputFile)
+	require.NoError(t, err)
+	assert.Equal(t, reportInputCode, string(output), "validate doesn't modify files")
+}
+
+func TestValidationError_ImportEdit(t *testing.T) {
+	input := "package test\n\nfunc F(id string) (err error) {\n\treturn nil\n}\n\nfunc G() (err error) {\n\treturn nil\n}\n"
+	inputFile := filepath.Join(t.TempDir(), "input.go")
+	require.NoError(t, os.WriteFile(inputFile, []byte(input), 0644))
+
+	err := Insert(inputFile, Options{Validate: true})
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	require.Len(t, validationErr.Findings, 2)
+	assert.Equal(t, []Edit{{Line: 1, Column: 13, EndLine: 1, EndColumn: 13, Replacement: "\n\nimport \"github.com/domonda/go-errs\""}}, validationErr.Findings[0].Edits)
+	assert.Empty(t, validationErr.Findings[1].Edits, "only the first finding of a file adds the import")
+
+	// Applying the replacements and edits in reverse order
+	// results in the source written by insert
+	lines := strings.SplitAfter(input, "\n")
+	offset := func(line, column int) int { return len(strings.Join(lines[:line-1], "")) + column - 1 }
+	type edit struct {
+		start, end  int
+		replacement string
+	}
+	var edits []edit
+	for _, f := range validationErr.Findings {
+		edits = append(edits, edit{offset(f.Line, f.Column), offset(f.EndLine, f.EndColumn), f.Replacement})
+		for _, e := range f.Edits {
+			edits = append(edits, edit{offset(e.Line, e.Column), offset(e.EndLine, e.EndColumn), e.Replacement})
+		}
+	}
+	slices.SortFunc(edits, func(a, b edit) int { return b.start - a.start })
+	applied := input
+	for _, e := range edits {
+		applied = applied[:e.start] + e.replacement + applied[e.end:]
+	}
+	formatted, err := format.Source([]byte(applied))
+	require.NoError(t, err)
+
+	require.NoError(t, Insert(inputFile, Options{}))
+	output, err := os.ReadFile(inputFile)
+	require.NoError(t, err)
+	assert.Equal(t, string(output), string(formatted))
+}
+
+func TestWriteJSON(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	findings := []Finding{{
+		File:        filepath.Join(wd, "pkg", "file.go"),
+		Line:        3,
+		Column:      2,
+		EndLine:     3,
+		EndColumn:   41,
+		Rule:        RuleStaleWrap,
+		Message:     "missing replace defer errs.Wrap",
+		Replacement: "defer errs.WrapWith1FuncParam(&err, id)",
+	}}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteJSON(&buf, findings))
+	var decoded []Finding
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	require.Len(t, decoded, 1)
+	assert.Equal(t, filepath.Join("pkg", "file.go"), decoded[0].File, "relative to the working directory")
+	assert.Equal(t, findings[0].Replacement, decoded[0].Replacement)
+	assert.Contains(t, buf.String(), `"replacement": "defer errs.WrapWith1FuncParam(&err, id)"`)
+
+	buf.Reset()
+	require.NoError(t, WriteJSON(&buf, nil))
+	assert.Equal(t, "[]\n", buf.String(), "empty array without findings")
+}
+
+func TestWriteSARIF(t *testing.T) {
+	findings := []Finding{
+		{File: "file.go", Line: 5, Column: 2, EndLine: 5, EndColumn: 2, Rule: RuleMissingWrap, Message: "missing insert defer errs.Wrap", Replacement: "defer errs.WrapWith0FuncParams(&err)\n\n\t", Edits: []Edit{{Line: 1, Column: 13, EndLine: 1, EndColumn: 13, Replacement: "\n\nimport \"github.com/domonda/go-errs\""}}},
+		{File: "/outside/file.go", Line: 7, Column: 2, EndLine: 8, EndColumn: 1, Rule: RuleRemoveWrap, Message: "missing remove defer errs.Wrap"},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteSARIF(&buf, findings))
+
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string `json:"name"`
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI       string `json:"uri"`
+							URIBaseID string `json:"uriBaseId"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine   int `json:"startLine"`
+							StartColumn int `json:"startColumn"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+				Fixes []struct {
+					ArtifactChanges []struct {
+						Replacements []struct {
+							InsertedContent *struct {
+								Text string `json:"text"`
+							} `json:"insertedContent"`
+						} `json:"replacements"`
+					} `json:"artifactChanges"`
+				} `json:"fixes"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+	assert.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	run := log.Runs[0]
+	assert.Equal(t, "go-errs-wrap", run.Tool.Driver.Name)
+	assert.Len(t, run.Tool.Driver.Rules, len(ruleDescriptions))
+	require.Len(t, run.Results, 2)
+
+	result := run.Results[0]
+	assert.Equal(t, RuleMissingWrap, result.RuleID)
+	loc := result.Locations[0].PhysicalLocation
+	assert.Equal(t, "file.go", loc.ArtifactLocation.URI)
+	assert.Equal(t, "%SRCROOT%", loc.ArtifactLocation.URIBaseID)
+	assert.Equal(t, 5, loc.Region.StartLine)
+	assert.Equal(t, 2, loc.Region.StartColumn)
+	require.NotNil(t, result.Fixes[0].ArtifactChanges[0].Replacements[0].InsertedContent)
+	assert.Equal(t, findings[0].Replacement, result.Fixes[0].ArtifactChanges[0].Replacements[0].InsertedContent.Text)
+	require.Len(t, result.Fixes[0].ArtifactChanges[0].Replacements, 2, "with edits")
+	assert.Equal(t, findings[0].Edits[0].Replacement, result.Fixes[0].ArtifactChanges[0].Replacements[1].InsertedContent.Text)
+
+	result = run.Results[1]
+	assert.Equal(t, RuleRemoveWrap, result.RuleID)
+	assert.Equal(t, "file:///outside/file.go", result.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Empty(t, result.Locations[0].PhysicalLocation.ArtifactLocation.URIBaseID)
+	assert.Nil(t, result.Fixes[0].ArtifactChanges[0].Replacements[0].InsertedContent, "removal without inserted content")
+}
+
+func TestDiff(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputFile := filepath.Join(tmpDir, "input.go")
+	require.NoError(t, os.WriteFile(inputFile, []byte(reportInputCode), 0644))
+	unchangedFile := filepath.Join(tmpDir, "unchanged.go")
+	require.NoError(t, os.WriteFile(unchangedFile, []byte("package test\n"), 0644))
+
+	var diff strings.Builder
+	err := Insert(tmpDir, Options{OutPath: filepath.Join(t.TempDir(), "out"), DiffOut: &diff})
+	require.NoError(t, err)
+
+	name := strings.TrimPrefix(filepath.ToSlash(relativePath(inputFile)), "/")
+	assert.Equal(t, strings.Join([]string{
+		"--- a/" + name,
+		"+++ b/" + name,
+		"@@ -3,6 +3,8 @@",
+		` import "github.com/domonda/go-errs"`,
+		" ",
+		" func Missing(id string) (err error) {",
+		"+\tdefer errs.WrapWith1FuncParam(&err, id)",
+		"+",
+		" \treturn nil",
+		" }",
+		" ",
+		"",
+	}, "\n"), diff.String(), "insert doesn't change stale statements")
+
+	output, err := os.ReadFile(inputFile)
+	require.NoError(t, err)
+	assert.Equal(t, reportInputCode, string(output), "diff doesn't modify files")
+
+	diff.Reset()
+	err = Replace(tmpDir, Options{Validate: true, DiffOut: &diff})
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr), "validate and diff can be combined")
+	assert.Len(t, validationErr.Findings, 1)
+	assert.Contains(t, diff.String(), "-\tdefer errs.WrapWith1FuncParam(&err, id)\n+\tdefer errs.WrapWith2FuncParams(&err, id, n)\n")
+	assert.NotContains(t, diff.String(), "unchanged.go")
+
+	diff.Reset()
+	err = Remove(inputFile, Options{DiffOut: &diff})
+	require.NoError(t, err)
+	assert.Contains(t, diff.String(), "-\tdefer errs.WrapWith1FuncParam(&err, id)\n")
+	assert.Contains(t, diff.String(), `-import "github.com/domonda/go-errs"`)
+}
@@ -7,7 +7,9 @@ import (
 	"bytes"
 	"fmt"
 	"go/ast"
+	"go/format"
 	"go/token"
+	"go/types"
 	"io"
 	"os"
 	"path/filepath"
@@ -23,249 +25,349 @@ import (
 type processMode int
 
 const (
-	modeRemove  processMode = iota // Remove all defer errs.Wrap statements
-	modeReplace                    // Replace existing defer errs.Wrap statements
-	modeInsert                     // Insert new defer errs.Wrap statements where missing
+	modeRemove    processMode = iota // Remove all defer errs.Wrap statements
+	modeReplace                      // Replace existing defer errs.Wrap statements
+	modeInsert                       // Insert new defer errs.Wrap statements where missing
+	modeMigrate                      // Migrate github.com/pkg/errors usages to go-errs
+	modeSentinels                    // Convert error variables to errs.Sentinel constants
 )
 
+// Options of Remove, Replace, Insert, Migrate, ConvertSentinels and CollectStats.
+// The zero value modifies the files in place.
+type Options struct {
+	// OutPath is the file or directory where the results are written
+	// instead of modifying the files in place, which requires
+	// the source path to be a file or directory.
+	// It is ignored with Validate or DiffOut.
+	OutPath string
+
+	// MinVariadic uses the specialized WrapWithNFuncParams functions
+	// instead of preserving existing variadic WrapWithFuncParams calls.
+	// Used by Replace, Insert and CollectStats.
+	MinVariadic bool
+
+	// NameResults is the name of the error result for functions
+	// with anonymous results including an error: if not empty,
+	// their results are renamed to (_ T, err error) with NameResults
+	// as name of the error result, or a variation like errResult
+	// if the name is already used as identifier in the function.
+	// Insert names the results of all such functions, Replace only
+	// of those with a wrap statement. Used by Replace and Insert.
+	NameResults string
+
+	// Secrets wraps matched parameters with errs.KeepSecret,
+	// also in existing statements, may be nil.
+	// Used by Replace, Insert and CollectStats.
+	Secrets *SecretPolicy
+
+	// FmtErrorf also rewrites fmt.Errorf calls with a constant
+	// format containing the %w verb to errs.Errorf.
+	// Used by Migrate.
+	FmtErrorf bool
+
+	// Validate doesn't modify files, instead a *ValidationError
+	// with the findings is returned if any are found.
+	Validate bool
+
+	// DiffOut doesn't modify files if not nil, instead the unified diff
+	// of every file that would change is written to it.
+	DiffOut io.Writer
+
+	// Config may be nil, its settings apply per file: files and
+	// functions it skips are not processed, and MinVariadic or Secrets
+	// enabled by it are used if not enabled by the options.
+	Config *Config
+
+	// VerboseOut receives progress messages if not nil.
+	VerboseOut io.Writer
+}
+
 // Remove removes all defer errs.Wrap statements and //#wrap-result-err
 // marker comments from Go source files at the given path.
 //
-// If outPath is empty, files are modified in place.
-// If outPath is specified, results are written there instead.
-// If recursive is true, subdirectories are processed recursively.
-// If validate is true, no files are modified; instead, any defer errs.Wrap
-// statements or markers found are reported to stderr and the function returns
-// an error if any are found. When validate is true, outPath is ignored.
-func Remove(sourcePath, outPath string, recursive, validate bool, verboseOut io.Writer) (err error) {
-	defer errs.WrapWithFuncParams(&err, sourcePath, outPath, recursive, validate, verboseOut)
-
-	return process(sourcePath, outPath, recursive, false, validate, verboseOut, modeRemove)
+// The sourcePath is a .go file, a directory or a package pattern
+// with the semantics of go list like ./pkg/..., see loadSourceFiles.
+// With Options.Validate the defer errs.Wrap statements
+// and markers are returned as findings.
+func Remove(sourcePath string, options Options) (err error) {
+	defer errs.WrapWithFuncParams(&err, sourcePath, options)
+
+	return process(sourcePath, options, modeRemove)
 }
 
 // Replace replaces all defer errs.Wrap statements and //#wrap-result-err
 // marker comments with properly generated defer errs.WrapWithFuncParams
 // statements in Go source files at the given path.
 //
-// If outPath is empty, files are modified in place.
-// If outPath is specified, results are written there instead.
-// If recursive is true, subdirectories are processed recursively.
-// If minVariadic is true, always use specialized WrapWithNFuncParams functions
-// instead of preserving existing variadic WrapWithFuncParams calls.
-// If validate is true, no files are modified; instead, missing replacements
-// are reported to stderr and the function returns an error if any are found.
-// When validate is true, outPath is ignored.
-func Replace(sourcePath, outPath string, recursive, minVariadic, validate bool, verboseOut io.Writer) (err error) {
-	defer errs.WrapWithFuncParams(&err, sourcePath, outPath, recursive, minVariadic, validate, verboseOut)
-
-	return process(sourcePath, outPath, recursive, minVariadic, validate, verboseOut, modeReplace)
+// The sourcePath is a .go file, a directory or a package pattern
+// with the semantics of go list like ./pkg/..., see loadSourceFiles.
+// With Options.Validate the missing replacements are returned as findings.
+func Replace(sourcePath string, options Options) (err error) {
+	defer errs.WrapWithFuncParams(&err, sourcePath, options)
+
+	return process(sourcePath, options, modeReplace)
 }
 
 // Insert inserts defer errs.WrapWith*FuncParams statements at the first line
 // of every function that has a named error result but doesn't already have
 // a defer errs.Wrap statement. An empty line is added after the inserted statement.
 //
-// If outPath is empty, files are modified in place.
-// If outPath is specified, results are written there instead.
-// If recursive is true, subdirectories are processed recursively.
-// If minVariadic is true, always use specialized WrapWithNFuncParams functions.
-// If validate is true, no files are modified; instead, missing insertions
-// are reported to stderr and the function returns an error if any are found.
-// When validate is true, outPath is ignored.
-func Insert(sourcePath, outPath string, recursive, minVariadic, validate bool, verboseOut io.Writer) (err error) {
-	defer errs.WrapWithFuncParams(&err, sourcePath, outPath, recursive, minVariadic, validate, verboseOut)
-
-	return process(sourcePath, outPath, recursive, minVariadic, validate, verboseOut, modeInsert)
+// The sourcePath is a .go file, a directory or a package pattern
+// with the semantics of go list like ./pkg/..., see loadSourceFiles.
+// With Options.Validate the missing insertions are returned as findings.
+func Insert(sourcePath string, options Options) (err error) {
+	defer errs.WrapWithFuncParams(&err, sourcePath, options)
+
+	return process(sourcePath, options, modeInsert)
 }
 
-func process(sourcePath, outPath string, recursive, minVariadic, validate bool, verboseOut io.Writer, mode processMode) (err error) {
-	defer errs.WrapWithFuncParams(&err, sourcePath, outPath, recursive, minVariadic, validate, verboseOut, mode)
+// processedFile is the source of a processed file
+// and its rewritten source that is written by process.
+type processedFile struct {
+	path      string
+	source    []byte
+	rewritten []byte
+}
 
-	sourcePath, err = filepath.Abs(sourcePath)
-	if err != nil {
-		return err
-	}
+func process(sourcePath string, options Options, mode processMode) (err error) {
+	defer errs.WrapWithFuncParams(&err, sourcePath, options, mode)
 
-	sourceInfo, err := os.Stat(sourcePath)
-	if err != nil {
-		return err
+	pattern := sourcePath
+	if pattern == "..." {
+		pattern = "./..."
 	}
 
-	// Handle output path for directory when not in validate mode
-	if outPath != "" && !validate {
-		outPath, err = filepath.Abs(outPath)
+	// Determine the output path of every source file,
+	// modifying files in place if outFilePath is nil
+	var outFilePath func(filePath string) (string, error)
+	if options.OutPath != "" && !options.Validate && options.DiffOut == nil {
+		outFilePath, err = outputPaths(strings.TrimSuffix(pattern, "/..."), options.OutPath, options.VerboseOut)
 		if err != nil {
 			return err
 		}
+	}
 
-		if sourceInfo.IsDir() {
-			return processDirectoryWithOutput(sourcePath, outPath, recursive, minVariadic, validate, verboseOut, mode)
-		}
-
-		// For single file with output, use custom handling
-		return processSingleFileWithOutput(sourcePath, outPath, minVariadic, validate, verboseOut, mode)
+	files, err := loadSourceFiles(pattern, options.Config.tests(), options.VerboseOut)
+	if err != nil {
+		return err
 	}
 
-	// In-place modification (or validation)
-	// Use "..." suffix for directories to recursively find all packages
-	path := sourcePath
-	if sourceInfo.IsDir() && recursive {
-		path = strings.TrimSuffix(sourcePath, "/") + "/..."
+	// Sentinel variables can only be converted
+	// if no file of the loaded packages prevents it
+	var sentinels sentinelVars
+	if mode == modeSentinels {
+		sentinels = findSentinelVars(files)
 	}
 
-	var validationErrors []string
-	err = astvisit.RewriteWithReplacements(
-		path,
-		verboseOut,
-		nil, // modify in place (or skip in validation mode)
-		false,
-		func(fset *token.FileSet, _ *ast.Package, astFile *ast.File, filePath string, verboseOut io.Writer) (astvisit.NodeReplacements, astvisit.Imports, error) {
-			replacements, imports, err := processFile(fset, astFile, minVariadic, verboseOut, mode)
-			if err != nil {
-				return nil, nil, err
-			}
-
-			// Filter out no-op replacements: a replacement that produces the
-			// exact source text already at its node's position would only
-			// trigger import reordering and other formatting churn without
-			// actually fixing anything. Skipping them here ensures both:
-			//   - validate mode reports only genuine issues, not false
-			//     positives caused by unrelated formatting differences
-			//   - normal mode does not rewrite a file (and reorder its
-			//     imports) when every wrap statement is already correct
-			if len(replacements) > 0 {
-				// #nosec G304 -- filePath comes from astvisit callback and is validated via filepath.Rel
-				source, err := os.ReadFile(filePath)
+	var (
+		findings []Finding
+		outputs  []processedFile
+	)
+	for _, f := range files {
+		settings := options.Config.fileSettings(f.path, f.pkgPath, f.file)
+		if settings.skip != "" {
+			if options.VerboseOut != nil {
+				fmt.Fprintf(options.VerboseOut, "skipping %s (%s)\n", f.path, settings.skip)
+			}
+			if outFilePath != nil {
+				// Complete the output with the unchanged file
+				destPath, err := outFilePath(f.path)
 				if err != nil {
-					return nil, nil, err
+					return err
 				}
-				filtered := replacements[:0]
-				for _, repl := range replacements {
-					if repl.Node != nil && !replacementChangesSource(fset, source, repl) {
-						continue
+				if destPath != "" {
+					if err := copyFile(f.path, destPath); err != nil {
+						return err
 					}
-					filtered = append(filtered, repl)
 				}
-				replacements = filtered
 			}
+			continue
+		}
+		fileSecrets := options.Secrets
+		if fileSecrets == nil {
+			fileSecrets = settings.secrets
+		}
+		if options.VerboseOut != nil {
+			fmt.Fprintf(options.VerboseOut, "parsing file: %s\n", f.path)
+		}
+		// #nosec G304 -- f.path is a file of the loaded packages
+		source, err := os.ReadFile(f.path)
+		if err != nil {
+			return err
+		}
 
-			if validate {
-				for _, repl := range replacements {
-					var pos token.Position
-					if repl.Node != nil {
-						pos = fset.Position(repl.Node.Pos())
-					}
-					desc := repl.DebugID
-					if desc == "" {
-						desc = "error wrapper"
-					}
-					validationErrors = append(validationErrors, fmt.Sprintf("%s: missing %s", pos, desc))
-				}
-
-				// Return nil to prevent file modification in validate mode
-				return nil, nil, nil
+		var (
+			replacements replacements
+			imports      astvisit.Imports
+		)
+		switch mode {
+		case modeMigrate:
+			replacements, imports, err = migrateFile(f.fset, f.file, f.info, source, options.FmtErrorf, settings.skipFunctions, options.VerboseOut)
+		case modeSentinels:
+			replacements, imports, err = convertSentinelsFile(f.fset, f.file, f.info, source, sentinels, options.VerboseOut)
+		default:
+			replacements, imports, err = processFile(f.fset, f.file, f.info, options.MinVariadic || settings.minVariadic, options.NameResults, fileSecrets, options.VerboseOut, mode)
+		}
+		if err != nil {
+			return err
+		}
+		replacements = skipFunctions(f.file, replacements, settings.skipFunctions)
+
+		// Filter out no-op replacements: a replacement that produces the
+		// exact source text already at its node's position would only
+		// trigger import reordering and other formatting churn without
+		// actually fixing anything. Skipping them here ensures both:
+		//   - options.Validate mode reports only genuine issues, not false
+		//     positives caused by unrelated formatting differences
+		//   - normal mode does not rewrite a file (and reorder its
+		//     imports) when every wrap statement is already correct
+		filtered := replacements[:0]
+		for _, repl := range replacements {
+			if repl.Node != nil && !replacementChangesSource(f.fset, source, repl) {
+				continue
 			}
+			filtered = append(filtered, repl)
+		}
+		replacements = filtered
 
-			return replacements, imports, nil
-		},
-	)
-	if err != nil {
-		return err
-	}
+		if options.Validate {
+			for i, repl := range replacements {
+				finding, err := newFinding(f.fset, repl)
+				if err != nil {
+					return err
+				}
+				if i == 0 {
+					finding.Edits = importEdits(f.fset, f.file, imports)
+				}
+				findings = append(findings, finding)
+			}
+			if options.DiffOut == nil {
+				continue
+			}
+		}
 
-	// In validation mode, report errors and fail if any were found
-	if validate && len(validationErrors) > 0 {
-		for _, errMsg := range validationErrors {
-			fmt.Fprintln(os.Stderr, errMsg)
+		rewritten := source
+		if len(replacements) > 0 {
+			rewritten, err = replacements.apply(f.fset, source)
+			if err != nil {
+				return err
+			}
+			switch mode {
+			case modeRemove:
+				// For remove, use goimports to remove unused imports
+				rewritten, err = goimports.Process(f.path, rewritten, nil)
+			case modeMigrate, modeSentinels:
+				// For migrate and sentinels, add the errs import and remove
+				// the imports of github.com/pkg/errors, errors or fmt if unused
+				rewritten, err = astvisit.FormatFileWithImports(f.fset, rewritten, imports)
+				if err == nil {
+					rewritten, err = goimports.Process(f.path, rewritten, nil)
+				}
+			default:
+				// For replace/insert, format with imports to ensure errs is imported
+				rewritten, err = astvisit.FormatFileWithImports(f.fset, rewritten, imports)
+			}
+			if err != nil {
+				return err
+			}
 		}
-		return errs.Errorf("found %d missing error wrapper(s)", len(validationErrors))
-	}
 
-	return nil
-}
+		outputs = append(outputs, processedFile{path: f.path, source: source, rewritten: rewritten})
+	}
 
-func processSingleFileWithOutput(sourcePath, outPath string, minVariadic, validate bool, verboseOut io.Writer, mode processMode) (err error) {
-	defer errs.WrapWithFuncParams(&err, sourcePath, outPath, minVariadic, validate, verboseOut, mode)
-
-	// Determine final output path
-	outInfo, err := os.Stat(outPath)
-	if err == nil && outInfo.IsDir() {
-		// Output is existing directory, add source filename
-		outPath = filepath.Join(outPath, filepath.Base(sourcePath))
-	} else if !strings.HasSuffix(outPath, ".go") {
-		// If outPath doesn't exist and doesn't end with .go,
-		// treat it as a directory to create
-		if err := os.MkdirAll(outPath, 0750); err != nil {
+	// Converted sentinels may break code that
+	// was not recognized by findSentinelVars,
+	// don't change any file in that case
+	if mode == modeSentinels {
+		if err := checkConvertedSentinels(files, outputs, options.Config.tests()); err != nil {
 			return err
 		}
-		outPath = filepath.Join(outPath, filepath.Base(sourcePath))
 	}
 
-	// Process the file
-	return astvisit.RewriteWithReplacements(
-		sourcePath,
-		verboseOut,
-		nil, // We handle output ourselves
-		false,
-		func(fset *token.FileSet, pkg *ast.Package, astFile *ast.File, filePath string, verboseOut io.Writer) (astvisit.NodeReplacements, astvisit.Imports, error) {
-			replacements, imports, err := processFile(fset, astFile, minVariadic, verboseOut, mode)
-			if err != nil {
-				return nil, nil, err
+	for _, out := range outputs {
+		if options.DiffOut != nil {
+			if !bytes.Equal(out.source, out.rewritten) {
+				if err := writeDiff(options.DiffOut, out.path, out.source, out.rewritten); err != nil {
+					return err
+				}
 			}
+			continue
+		}
 
-			// Read original source
-			// #nosec G304 -- filePath comes from astvisit callback and is validated via filepath.Rel
-			source, err := os.ReadFile(filePath)
+		destPath := out.path
+		if outFilePath != nil {
+			destPath, err = outFilePath(out.path)
 			if err != nil {
-				return nil, nil, err
+				return err
 			}
-
-			var rewritten []byte
-			if len(replacements) > 0 {
-				// Apply replacements
-				rewritten, err = replacements.Apply(fset, source)
-				if err != nil {
-					return nil, nil, err
-				}
-
-				if mode == modeRemove {
-					// For remove, use goimports to remove unused imports
-					rewritten, err = goimports.Process(filePath, rewritten, nil)
-					if err != nil {
-						return nil, nil, err
-					}
-				} else {
-					// For replace/insert, format with imports to ensure errs is imported
-					rewritten, err = astvisit.FormatFileWithImports(fset, rewritten, imports)
-					if err != nil {
-						return nil, nil, err
-					}
-				}
-			} else {
-				// No changes, copy original
-				rewritten = source
+			if destPath == "" {
+				continue
 			}
-
-			// Write to output
-			// #nosec G306,G703 -- 0640 is appropriate for generated Go source files, paths are constructed via filepath.Join
-			if err := os.WriteFile(outPath, rewritten, 0640); err != nil {
-				return nil, nil, err
+		} else if bytes.Equal(out.source, out.rewritten) {
+			if options.VerboseOut != nil {
+				fmt.Fprintf(options.VerboseOut, "no changes in file: %s\n", out.path)
 			}
+			continue
+		}
+		if err := writeFile(destPath, out.path, out.rewritten); err != nil {
+			return err
+		}
+		if options.VerboseOut != nil {
+			fmt.Fprintf(options.VerboseOut, "wrote: %s\n", destPath)
+		}
+	}
 
-			if verboseOut != nil {
-				fmt.Fprintf(verboseOut, "wrote: %s\n", outPath)
-			}
+	// In validation mode, fail with the findings
+	if options.Validate && len(findings) > 0 {
+		return &ValidationError{Findings: findings}
+	}
 
-			// Return nil to prevent astvisit from writing
-			return nil, nil, nil
-		},
-	)
+	return nil
 }
 
-func processDirectoryWithOutput(sourcePath, outPath string, recursive, minVariadic, validate bool, verboseOut io.Writer, mode processMode) (err error) {
-	defer errs.WrapWithFuncParams(&err, sourcePath, outPath, recursive, minVariadic, validate, verboseOut, mode)
+// outputPaths prepares outPath as output location for the Go files of
+// the file or directory sourcePath and returns a function mapping
+// the path of a source file to its output path.
+// A directory tree is created at outPath for a directory
+// with copies of all non-Go files.
+func outputPaths(sourcePath, outPath string, verboseOut io.Writer) (outFilePath func(string) (string, error), err error) {
+	defer errs.WrapWithFuncParams(&err, sourcePath, outPath, verboseOut)
+
+	sourcePath, err = filepath.Abs(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+	outPath, err = filepath.Abs(outPath)
+	if err != nil {
+		return nil, err
+	}
+	sourceInfo, err := os.Stat(sourcePath)
+	if err != nil {
+		return nil, errs.Errorf("-out requires a file or directory as source: %w", err)
+	}
+
+	if !sourceInfo.IsDir() {
+		outInfo, err := os.Stat(outPath)
+		if err == nil && outInfo.IsDir() {
+			// Output is existing directory, add source filename
+			outPath = filepath.Join(outPath, filepath.Base(sourcePath))
+		} else if !strings.HasSuffix(outPath, ".go") {
+			// If outPath doesn't exist and doesn't end with .go,
+			// treat it as a directory to create
+			if err := os.MkdirAll(outPath, 0750); err != nil {
+				return nil, err
+			}
+			outPath = filepath.Join(outPath, filepath.Base(sourcePath))
+		}
+		return func(filePath string) (string, error) {
+			if filePath != sourcePath {
+				return "", nil
+			}
+			return outPath, nil
+		}, nil
+	}
 
-	// First, copy non-Go files
+	// Copy non-Go files, Go files are written after processing
 	err = filepath.Walk(sourcePath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -293,85 +395,76 @@ func processDirectoryWithOutput(sourcePath, outPath string, recursive, minVariad
 		return copyFile(path, destPath)
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Process Go files with astvisit
-	return astvisit.RewriteWithReplacements(
-		strings.TrimSuffix(sourcePath, "/")+"/...",
-		verboseOut,
-		nil, // We'll handle output ourselves
-		false,
-		func(fset *token.FileSet, pkg *ast.Package, astFile *ast.File, filePath string, verboseOut io.Writer) (astvisit.NodeReplacements, astvisit.Imports, error) {
-			replacements, imports, err := processFile(fset, astFile, minVariadic, verboseOut, mode)
-			if err != nil {
-				return nil, nil, err
-			}
-
-			// If there are replacements, we need to write to the output path
-			if len(replacements) > 0 {
-				relPath, err := filepath.Rel(sourcePath, filePath)
-				if err != nil {
-					return nil, nil, err
-				}
-				destPath := filepath.Join(outPath, relPath)
-
-				// Ensure directory exists
-				if err := os.MkdirAll(filepath.Dir(destPath), 0750); err != nil {
-					return nil, nil, err
-				}
-
-				// Read original file
-				// #nosec G304 -- filePath comes from astvisit callback and is validated via filepath.Rel at line 318
-				source, err := os.ReadFile(filePath)
-				if err != nil {
-					return nil, nil, err
-				}
-
-				// Apply replacements
-				rewritten, err := replacements.Apply(fset, source)
-				if err != nil {
-					return nil, nil, err
-				}
+	return func(filePath string) (string, error) {
+		relPath, err := filepath.Rel(sourcePath, filePath)
+		if err != nil {
+			return "", err
+		}
+		destPath := filepath.Join(outPath, relPath)
+		// Ensure directory exists
+		if err := os.MkdirAll(filepath.Dir(destPath), 0750); err != nil {
+			return "", err
+		}
+		return destPath, nil
+	}, nil
+}
 
-				if mode == modeRemove {
-					// For remove, use goimports to remove unused imports
-					rewritten, err = goimports.Process(filePath, rewritten, nil)
-					if err != nil {
-						return nil, nil, err
-					}
-				} else {
-					// For replace/insert, format with imports to ensure errs is imported
-					rewritten, err = astvisit.FormatFileWithImports(fset, rewritten, imports)
-					if err != nil {
-						return nil, nil, err
-					}
-				}
+// writeFile writes data to path with the permissions
+// of the file at sourcePath, or 0640 if it doesn't exist.
+func writeFile(path, sourcePath string, data []byte) error {
+	perm := os.FileMode(0640)
+	if info, err := os.Stat(sourcePath); err == nil {
+		perm = info.Mode().Perm()
+	}
+	// #nosec G306,G703 -- paths are from the loaded packages or constructed via filepath.Join
+	return os.WriteFile(path, data, perm)
+}
 
-				// Write to destination
-				// #nosec G306,G703 -- 0640 is appropriate for generated Go source files, paths are constructed via filepath.Join
-				if err := os.WriteFile(destPath, rewritten, 0640); err != nil {
-					return nil, nil, err
-				}
+// processFile returns the replacements for astFile in the given mode
+// and the imports they need.
+// The type information info is optional, if not nil it is used to
+// resolve references to go-errs and the types of function parameters
+// and results.
+func processFile(fset *token.FileSet, astFile *ast.File, info *types.Info, minVariadic bool, nameResults string, secrets *SecretPolicy, verboseOut io.Writer, mode processMode) (replacements replacements, imports astvisit.Imports, err error) {
+	defer errs.WrapWithFuncParams(&err, fset, astFile, info, minVariadic, nameResults, secrets, verboseOut, mode)
 
-				if verboseOut != nil {
-					fmt.Fprintf(verboseOut, "wrote: %s\n", destPath)
+	if IsFileIgnored(astFile) {
+		if verboseOut != nil {
+			fmt.Fprintf(verboseOut, "%s: ignoring file with %s\n", fset.Position(astFile.Package), IgnoreDirective)
+		}
+		return nil, nil, nil
+	}
+	if mode != modeRemove {
+		for _, decl := range astFile.Decls {
+			if fd, ok := decl.(*ast.FuncDecl); ok {
+				for _, name := range FuncDirectives(fd.Doc).UnknownParams(fd.Type) {
+					fmt.Fprintf(os.Stderr, "warning: %s: %s lists unknown parameter %s of function %s\n",
+						fset.Position(fd.Pos()), ParamsDirective, name, fd.Name.Name,
+					)
 				}
 			}
-
-			// Return nil to prevent astvisit from writing
-			return nil, nil, nil
-		},
-	)
-}
-
-func processFile(fset *token.FileSet, astFile *ast.File, minVariadic bool, verboseOut io.Writer, mode processMode) (replacements astvisit.NodeReplacements, imports astvisit.Imports, err error) {
-	defer errs.WrapWithFuncParams(&err, fset, astFile, minVariadic, verboseOut, mode)
+		}
+	}
 
 	imports = make(astvisit.Imports)
+	addErrsImport := func() {
+		if !importsErrs(astFile) {
+			imports[errsImportPath] = struct{}{}
+		}
+	}
+
+	// Resolves calls to go-errs functions like KeepSecret
+	refs := newErrsRefs(astFile, info)
+	// Generated statements use the name go-errs is imported with
+	errsQualifier := qualifier(astFile)
 
-	// Extract all aliases used to import go-errs (for detecting KeepSecret calls)
-	errsAliases := extractErrsAliases(astFile)
+	// For replace mode, track the error result names of functions
+	// whose results were named because of nameResults,
+	// so that multiple wrap statements don't rename them again
+	namedFuncs := make(map[token.Pos]string)
 
 	// For insert mode, track which functions already have defer errs.Wrap
 	funcsWithWrap := make(map[token.Pos]bool)
@@ -381,13 +474,25 @@ func processFile(fset *token.FileSet, astFile *ast.File, minVariadic bool, verbo
 				return true
 			}
 			deferStmt, ok := n.(*ast.DeferStmt)
-			if !ok || !isDeferErrsWrap(deferStmt, errsAliases) {
+			if !ok || !refs.isDeferWrap(deferStmt) {
 				return true
 			}
 			// Find enclosing function and mark it as having a wrap
-			fun := findEnclosingFuncForPos(astFile, deferStmt.Pos())
-			if fun != nil {
-				funcsWithWrap[fun.startPos] = true
+			fun := findEnclosingFuncForPos(astFile, info, deferStmt.Pos())
+			if fun == nil {
+				return true
+			}
+			funcsWithWrap[fun.startPos] = true
+			if fun.ignore {
+				return true
+			}
+
+			// Wrap sensitive parameters of the existing statement
+			for _, arg := range secrets.sensitiveArgs(fun, deferStmt) {
+				if verboseOut != nil {
+					fmt.Fprintf(verboseOut, "%s: wrapping sensitive parameter %s with errs.KeepSecret\n", fset.Position(arg.Pos()), arg.Name)
+				}
+				replacements.add(RuleMissingKeepSecret, arg, qualify("errs.KeepSecret("+arg.Name+")", errsQualifier), "errs.KeepSecret for sensitive parameter "+arg.Name)
 			}
 			return true
 		})
@@ -401,7 +506,16 @@ func processFile(fset *token.FileSet, astFile *ast.File, minVariadic bool, verbo
 			}
 
 			deferStmt, ok := n.(*ast.DeferStmt)
-			if !ok || !isDeferErrsWrap(deferStmt, errsAliases) {
+			if !ok || !refs.isDeferWrap(deferStmt) {
+				return true
+			}
+
+			// Find enclosing function for replacement
+			fun := findEnclosingFuncForPos(astFile, info, deferStmt.Pos())
+			if fun != nil && fun.ignore {
+				if verboseOut != nil {
+					fmt.Fprintf(verboseOut, "%s: ignoring function %s with %s\n", fset.Position(deferStmt.Pos()), fun.funcName, IgnoreDirective)
+				}
 				return true
 			}
 
@@ -409,19 +523,17 @@ func processFile(fset *token.FileSet, astFile *ast.File, minVariadic bool, verbo
 				if verboseOut != nil {
 					fmt.Fprintf(verboseOut, "%s: removing defer errs.Wrap\n", fset.Position(deferStmt.Pos()))
 				}
-				replacements.AddRemoval(deferStmt, "remove defer errs.Wrap")
+				replacements.add(RuleRemoveWrap, deferStmt, nil, "remove defer errs.Wrap")
 				return true
 			}
 
-			// Find enclosing function for replacement
-			fun := findEnclosingFuncForPos(astFile, deferStmt.Pos())
 			if fun == nil {
 				fmt.Fprintf(os.Stderr, "warning: %s: defer statement not inside a function\n",
 					fset.Position(deferStmt.Pos()),
 				)
 				return true
 			}
-			if fun.errorResultName == "" {
+			if !nameReplacedFuncResults(fset, fun, nameResults, namedFuncs, &replacements, verboseOut) {
 				fmt.Fprintf(os.Stderr, "warning: %s: function %s has no named error result, skipping\n",
 					fset.Position(deferStmt.Pos()), fun.funcName,
 				)
@@ -430,21 +542,32 @@ func processFile(fset *token.FileSet, astFile *ast.File, minVariadic bool, verbo
 
 			// Extract KeepSecret-wrapped parameters from the existing defer statement
 			// so they can be preserved in the replacement
-			fun.keepSecretNames = extractKeepSecretParams(deferStmt, errsAliases)
+			fun.setKeepSecretNames(refs.keepSecretParams(deferStmt))
 
 			// If already using variadic WrapWithFuncParams and minVariadic is false, keep it variadic
-			var replacement string
-			if !minVariadic && isVariadicWrapWithFuncParams(deferStmt, errsAliases) {
-				replacement = generateVariadicWrapStatement(fun)
-			} else {
-				replacement = generateWrapStatement(fun)
+			generate := generateWrapStatement
+			if !minVariadic && refs.isVariadicWrapWithFuncParams(deferStmt) {
+				generate = generateVariadicWrapStatement
+			}
+			rule, debugID := RuleStaleWrap, "replace defer errs.Wrap"
+			if unwrapped := secrets.sensitiveArgs(fun, deferStmt); len(unwrapped) > 0 &&
+				qualify(generate(fun), errsQualifier) == formatNode(fset, deferStmt) {
+				// The statement is only missing errs.KeepSecret
+				// for parameters matched by the secret policy
+				names := make([]string, len(unwrapped))
+				for i, arg := range unwrapped {
+					names[i] = arg.Name
+				}
+				rule, debugID = RuleMissingKeepSecret, "errs.KeepSecret for sensitive parameters "+strings.Join(names, ", ")
 			}
+			fun.addKeepSecretNames(secrets.params(fun))
+			replacement := qualify(generate(fun), errsQualifier)
 
 			if verboseOut != nil {
 				fmt.Fprintf(verboseOut, "%s: replacing defer errs.Wrap with %s\n", fset.Position(deferStmt.Pos()), replacement)
 			}
-			replacements.AddReplacement(deferStmt, replacement, "replace defer errs.Wrap")
-			imports[`"github.com/domonda/go-errs"`] = struct{}{}
+			replacements.add(rule, deferStmt, replacement, debugID)
+			addErrsImport()
 
 			return true
 		})
@@ -462,35 +585,43 @@ func processFile(fset *token.FileSet, astFile *ast.File, minVariadic bool, verbo
 					continue
 				}
 
+				// Find enclosing function for this comment
+				ctx := findEnclosingFuncForPos(astFile, info, comment.Pos())
+				if ctx != nil && ctx.ignore {
+					if verboseOut != nil {
+						fmt.Fprintf(verboseOut, "%s: ignoring function %s with %s\n", fset.Position(comment.Pos()), ctx.funcName, IgnoreDirective)
+					}
+					continue
+				}
+
 				if mode == modeRemove {
 					if verboseOut != nil {
 						fmt.Fprintf(verboseOut, "%s: removing //#wrap-result-err\n", fset.Position(comment.Pos()))
 					}
-					replacements.AddRemoval(comment, "remove //#wrap-result-err")
+					replacements.add(RuleRemoveWrap, comment, nil, "remove //#wrap-result-err")
 					continue
 				}
 
-				// Find enclosing function for this comment
-				ctx := findEnclosingFuncForPos(astFile, comment.Pos())
 				if ctx == nil {
 					fmt.Fprintf(os.Stderr, "warning: %s: //#wrap-result-err not inside a function\n",
 						fset.Position(comment.Pos()),
 					)
 					continue
 				}
-				if ctx.errorResultName == "" {
+				if !nameReplacedFuncResults(fset, ctx, nameResults, namedFuncs, &replacements, verboseOut) {
 					fmt.Fprintf(os.Stderr, "warning: %s: function %s has no named error result, skipping\n",
 						fset.Position(comment.Pos()), ctx.funcName,
 					)
 					continue
 				}
 
-				replacement := generateWrapStatement(ctx)
+				ctx.addKeepSecretNames(secrets.params(ctx))
+				replacement := qualify(generateWrapStatement(ctx), errsQualifier)
 				if verboseOut != nil {
 					fmt.Fprintf(verboseOut, "%s: replacing //#wrap-result-err with %s\n", fset.Position(comment.Pos()), replacement)
 				}
-				replacements.AddReplacement(comment, replacement, "replace //#wrap-result-err")
-				imports[`"github.com/domonda/go-errs"`] = struct{}{}
+				replacements.add(RuleWrapMarker, comment, replacement, "replace //#wrap-result-err")
+				addErrsImport()
 			}
 		}
 	}
@@ -506,12 +637,20 @@ func processFile(fset *token.FileSet, astFile *ast.File, minVariadic bool, verbo
 			var funcBody *ast.BlockStmt
 			var funcName string
 			var startPos, endPos token.Pos
+			var directives Directives
 
 			switch node := n.(type) {
 			case *ast.FuncDecl:
 				if node.Body == nil {
 					return true // Skip function declarations without body
 				}
+				directives = FuncDirectives(node.Doc)
+				if directives.Ignore {
+					if verboseOut != nil {
+						fmt.Fprintf(verboseOut, "%s: ignoring function %s with %s\n", fset.Position(node.Pos()), node.Name.Name, IgnoreDirective)
+					}
+					return false // Also skip function literals within
+				}
 				funcType = node.Type
 				funcBody = node.Body
 				funcName = node.Name.Name
@@ -533,13 +672,27 @@ func processFile(fset *token.FileSet, astFile *ast.File, minVariadic bool, verbo
 			}
 
 			// Extract function info
-			fun := extractFuncInfo(funcType, funcName, startPos, endPos)
+			fun := extractFuncInfo(info, funcType, funcName, startPos, endPos)
+			fun.body = funcBody
+			fun.applyDirectives(directives)
 			if fun.errorResultName == "" {
-				return true // Skip functions without named error result
+				if nameResults == "" {
+					return true // Skip functions without named error result
+				}
+				name, renames := nameFuncResults(fun, nameResults)
+				if name == "" {
+					return true // Skip functions without error result
+				}
+				if verboseOut != nil {
+					fmt.Fprintf(verboseOut, "%s: naming error result %s\n", fset.Position(funcType.Pos()), name)
+				}
+				replacements = append(replacements, renames...)
 			}
 
+			fun.addKeepSecretNames(secrets.params(fun))
+
 			// Find the position to insert (after the opening brace of the function body)
-			statement := generateWrapStatement(fun)
+			statement := qualify(generateWrapStatement(fun), errsQualifier)
 			if funcBody == nil || len(funcBody.List) == 0 {
 				// Empty function body - insert after opening brace
 				insertPos := funcBody.Lbrace + 1
@@ -547,8 +700,8 @@ func processFile(fset *token.FileSet, astFile *ast.File, minVariadic bool, verbo
 					fmt.Fprintf(verboseOut, "%s: inserting %s\n", fset.Position(insertPos), statement)
 				}
 				// Use PosNode to create a zero-width insertion point
-				replacements.AddReplacement(astvisit.PosNode(insertPos), "\n\t"+statement+"\n", "insert defer errs.Wrap")
-				imports[`"github.com/domonda/go-errs"`] = struct{}{}
+				replacements.add(RuleMissingWrap, astvisit.PosNode(insertPos), "\n\t"+statement+"\n", "insert defer errs.Wrap")
+				addErrsImport()
 			} else {
 				// Insert before the first statement, with empty line after
 				firstStmt := funcBody.List[0]
@@ -556,8 +709,8 @@ func processFile(fset *token.FileSet, astFile *ast.File, minVariadic bool, verbo
 					fmt.Fprintf(verboseOut, "%s: inserting %s\n", fset.Position(firstStmt.Pos()), statement)
 				}
 				// Use PosNode to create a zero-width insertion point before the first statement
-				replacements.AddReplacement(astvisit.PosNode(firstStmt.Pos()), statement+"\n\n\t", "insert defer errs.Wrap")
-				imports[`"github.com/domonda/go-errs"`] = struct{}{}
+				replacements.add(RuleMissingWrap, astvisit.PosNode(firstStmt.Pos()), statement+"\n\n\t", "insert defer errs.Wrap")
+				addErrsImport()
 			}
 
 			return true
@@ -612,12 +765,50 @@ func isVariadicWrapWithFuncParams(stmt *ast.DeferStmt, errsAliases map[string]bo
 	return call.Sel.Name == "WrapWithFuncParams"
 }
 
+// formatNode returns the formatted source code of node.
+func formatNode(fset *token.FileSet, node ast.Node) string {
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, node); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// replacement is a node replacement together with
+// the rule under which it is reported in validate mode.
+type replacement struct {
+	astvisit.NodeReplacement
+
+	rule string
+}
+
+// replacements are the replacements of a source file.
+type replacements []replacement
+
+// add adds the replacement of node with repl for rule.
+// A nil repl removes node, see astvisit.NodeReplacements.
+func (r *replacements) add(rule string, node ast.Node, repl any, debugID string) {
+	*r = append(*r, replacement{
+		NodeReplacement: astvisit.NodeReplacement{Node: node, Replacement: repl, DebugID: debugID},
+		rule:            rule,
+	})
+}
+
+// apply returns source with the replacements applied.
+func (r replacements) apply(fset *token.FileSet, source []byte) ([]byte, error) {
+	nodeReplacements := make(astvisit.NodeReplacements, len(r))
+	for i, repl := range r {
+		nodeReplacements[i] = repl.NodeReplacement
+	}
+	return nodeReplacements.Apply(fset, source)
+}
+
 // replacementChangesSource reports whether applying repl would actually
 // change the source text at the node's position. Used in validate mode to
 // avoid false positives from whole-file comparisons where unrelated
 // formatting (e.g. import reordering) makes the rewritten file differ
 // even though every replacement is a no-op.
-func replacementChangesSource(fset *token.FileSet, source []byte, repl astvisit.NodeReplacement) bool {
+func replacementChangesSource(fset *token.FileSet, source []byte, repl replacement) bool {
 	start := fset.Position(repl.Node.Pos()).Offset
 	end := fset.Position(repl.Node.End()).Offset
 	if start < 0 || end < start || end > len(source) {
@@ -657,3 +848,31 @@ func copyFile(src, dst string) error {
 
 	return os.WriteFile(dst, source, info.Mode()) // #nosec G703 -- paths are cleaned via filepath.Clean above
 }
+
+// nameReplacedFuncResults makes sure that fun has a named error result
+// for the replace mode by naming its results if nameResults is not empty,
+// see nameFuncResults. Functions already named are tracked in namedFuncs
+// so that their results are only renamed once.
+// Returns false if fun has no named error result afterwards.
+func nameReplacedFuncResults(fset *token.FileSet, fun *funcInfo, nameResults string, namedFuncs map[token.Pos]string, replacements *replacements, verboseOut io.Writer) bool {
+	if fun.errorResultName != "" {
+		return true
+	}
+	if nameResults == "" {
+		return false
+	}
+	if name, ok := namedFuncs[fun.startPos]; ok {
+		fun.errorResultName = name
+		return name != ""
+	}
+	name, renames := nameFuncResults(fun, nameResults)
+	namedFuncs[fun.startPos] = name
+	if name == "" {
+		return false
+	}
+	if verboseOut != nil {
+		fmt.Fprintf(verboseOut, "%s: naming error result %s\n", fset.Position(fun.funcType.Pos()), name)
+	}
+	*replacements = append(*replacements, renames...)
+	return true
+}
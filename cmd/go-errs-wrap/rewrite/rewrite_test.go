@@ -222,7 +222,7 @@ func ProcessWithSpecialized(ctx context.Context) (err error) {
 	require.NoError(t, err)
 
 	// Run replace with minVariadic=false to preserve variadic calls
-	err = Replace(inputFile, outDir, false, false, false, nil)
+	err = Replace(inputFile, Options{OutPath: outDir})
 	require.NoError(t, err)
 
 	// Read output
@@ -264,7 +264,7 @@ func VariadicFunc(ctx any, id string) (err error) {
 	require.NoError(t, err)
 
 	// Run replace with minVariadic=true to convert variadic to specialized
-	err = Replace(inputFile, outDir, false, true, false, nil)
+	err = Replace(inputFile, Options{OutPath: outDir, MinVariadic: true})
 	require.NoError(t, err)
 
 	outputFile := filepath.Join(outDir, "input.go")
@@ -316,7 +316,7 @@ func NoWrap(x int) error {
 	require.NoError(t, err)
 
 	// Run remove
-	err = Remove(inputFile, outDir, false, false, nil)
+	err = Remove(inputFile, Options{OutPath: outDir})
 	require.NoError(t, err)
 
 	// Read output
@@ -375,7 +375,7 @@ func NoParams() (err error) {
 	require.NoError(t, err)
 
 	// Run replace with minVariadic=false to preserve variadic calls
-	err = Replace(inputFile, outDir, false, false, false, nil)
+	err = Replace(inputFile, Options{OutPath: outDir})
 	require.NoError(t, err)
 
 	// Read output
@@ -421,7 +421,7 @@ func NoNamedErr(x int) error {
 	require.NoError(t, err)
 
 	// Run replace (should not error, but should skip the function)
-	err = Replace(inputFile, outDir, false, true, false, nil)
+	err = Replace(inputFile, Options{OutPath: outDir, MinVariadic: true})
 	require.NoError(t, err)
 
 	// Read output - should be unchanged since the function was skipped
@@ -448,7 +448,7 @@ func Outer(a int) (err error) {
 	file, err := parser.ParseFile(fset, "test.go", code, parser.ParseComments)
 	require.NoError(t, err)
 
-	replacements, imports, err := processFile(fset, file, true, nil, modeReplace)
+	replacements, imports, err := processFile(fset, file, nil, true, "", nil, nil, modeReplace)
 	require.NoError(t, err)
 
 	// Should have one replacement for the anonymous function
@@ -561,7 +561,7 @@ func NoError(x int) int {
 	require.NoError(t, err)
 
 	// Run insert
-	err = Insert(inputFile, outDir, false, true, false, nil)
+	err = Insert(inputFile, Options{OutPath: outDir, MinVariadic: true})
 	require.NoError(t, err)
 
 	// Read output
@@ -612,7 +612,7 @@ func NeedsWrap(id string) (err error) {
 	require.NoError(t, err)
 
 	// Run insert
-	err = Insert(inputFile, outDir, false, true, false, nil)
+	err = Insert(inputFile, Options{OutPath: outDir, MinVariadic: true})
 	require.NoError(t, err)
 
 	// Read output
@@ -652,7 +652,7 @@ func EmptyBody() (err error) {
 	require.NoError(t, err)
 
 	// Run insert
-	err = Insert(inputFile, outDir, false, true, false, nil)
+	err = Insert(inputFile, Options{OutPath: outDir, MinVariadic: true})
 	require.NoError(t, err)
 
 	// Read output
@@ -691,7 +691,7 @@ func ProcessData(id string) (err error) {
 	require.NoError(t, err)
 
 	// Run insert
-	err = Insert(inputFile, outDir, false, true, false, nil)
+	err = Insert(inputFile, Options{OutPath: outDir, MinVariadic: true})
 	require.NoError(t, err)
 
 	// Read output
@@ -706,6 +706,167 @@ func ProcessData(id string) (err error) {
 	assert.Contains(t, outputStr, "defer errs.WrapWith1FuncParam(&err, id)\n\n")
 }
 
+func TestInsertNameResults(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	inputCode := `package test
+
+import "strconv"
+
+// Parse parses s
+func Parse(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	return n, err
+}
+
+func Check(s string) error { // check s
+	return nil
+}
+
+func Named(s string) (n int, _ error) {
+	return 0, nil
+}
+
+func NoError(s string) int {
+	return 0
+}
+
+func Closure() {
+	_ = func(x int) (string, error) {
+		return "", nil
+	}
+}
+`
+	expected := `package test
+
+import (
+	"strconv"
+
+	"github.com/domonda/go-errs"
+)
+
+// Parse parses s
+func Parse(s string) (_ int, errResult error) {
+	defer errs.WrapWith1FuncParam(&errResult, s)
+
+	n, err := strconv.Atoi(s)
+	return n, err
+}
+
+func Check(s string) (err error) { // check s
+	defer errs.WrapWith1FuncParam(&err, s)
+
+	return nil
+}
+
+func Named(s string) (n int, err error) {
+	defer errs.WrapWith1FuncParam(&err, s)
+
+	return 0, nil
+}
+
+func NoError(s string) int {
+	return 0
+}
+
+func Closure() {
+	_ = func(x int) (_ string, err error) {
+		defer errs.WrapWith1FuncParam(&err, x)
+
+		return "", nil
+	}
+}
+`
+
+	inputFile := filepath.Join(tmpDir, "input.go")
+	require.NoError(t, os.WriteFile(inputFile, []byte(inputCode), 0644))
+
+	err := Insert(inputFile, Options{MinVariadic: true, NameResults: "err"})
+	require.NoError(t, err)
+
+	output, err := os.ReadFile(inputFile)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(output))
+
+	// Without nameResults the functions are skipped
+	require.NoError(t, os.WriteFile(inputFile, []byte(inputCode), 0644))
+	err = Insert(inputFile, Options{MinVariadic: true})
+	require.NoError(t, err)
+	output, err = os.ReadFile(inputFile)
+	require.NoError(t, err)
+	assert.Equal(t, inputCode, string(output))
+}
+
+func TestInsertNameResultsValidateMode(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	inputCode := `package test
+
+func Check(s string) error {
+	return nil
+}
+`
+	inputFile := filepath.Join(tmpDir, "input.go")
+	require.NoError(t, os.WriteFile(inputFile, []byte(inputCode), 0644))
+
+	err := Insert(inputFile, Options{MinVariadic: true, NameResults: "err", Validate: true})
+	require.Error(t, err)
+
+	err = Insert(inputFile, Options{MinVariadic: true, Validate: true})
+	require.NoError(t, err, "unnamed results are not reported without nameResults")
+}
+
+func TestReplaceNameResults(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	inputCode := `package test
+
+import "github.com/domonda/go-errs"
+
+func Marker(x int) error {
+	//#wrap-result-err
+	return nil
+}
+
+func Stale(x, y int) (string, error) {
+	var err error
+	defer errs.WrapWith1FuncParam(&err, x)
+	defer errs.WrapWith1FuncParam(&err, x)
+	_ = err
+
+	return "", nil
+}
+`
+	expected := `package test
+
+import "github.com/domonda/go-errs"
+
+func Marker(x int) (err error) {
+	defer errs.WrapWith1FuncParam(&err, x)
+	return nil
+}
+
+func Stale(x, y int) (_ string, errResult error) {
+	var err error
+	defer errs.WrapWith2FuncParams(&errResult, x, y)
+	defer errs.WrapWith2FuncParams(&errResult, x, y)
+	_ = err
+
+	return "", nil
+}
+`
+
+	inputFile := filepath.Join(tmpDir, "input.go")
+	require.NoError(t, os.WriteFile(inputFile, []byte(inputCode), 0644))
+
+	err := Replace(inputFile, Options{MinVariadic: true, NameResults: "err"})
+	require.NoError(t, err)
+
+	output, err := os.ReadFile(inputFile)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(output))
+}
+
 func TestExtractErrsAliases(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -877,7 +1038,7 @@ func MultipleSecrets(apiKey, token, userID string) (err error) {
 	require.NoError(t, err)
 
 	// Run replace (preserving variadic)
-	err = Replace(inputFile, outDir, false, false, false, nil)
+	err = Replace(inputFile, Options{OutPath: outDir})
 	require.NoError(t, err)
 
 	// Read output
@@ -925,7 +1086,7 @@ func ProcessWithSecret(ctx any, password string) (err error) {
 	require.NoError(t, err)
 
 	// Run replace with minVariadic=true
-	err = Replace(inputFile, outDir, false, true, false, nil)
+	err = Replace(inputFile, Options{OutPath: outDir, MinVariadic: true})
 	require.NoError(t, err)
 
 	outputFile := filepath.Join(outDir, "input.go")
@@ -1040,7 +1201,7 @@ func LoadFile(path string) (data []byte, err error) {
 	require.NoError(t, err)
 
 	// Run replace with validate=true
-	err = Replace(inputFile, "", false, false, true, nil)
+	err = Replace(inputFile, Options{Validate: true})
 
 	// Should return an error indicating missing replacements
 	require.Error(t, err)
@@ -1079,7 +1240,7 @@ func NoError() {
 	require.NoError(t, err)
 
 	// Run replace with validate=true
-	err = Replace(inputFile, "", false, false, true, nil)
+	err = Replace(inputFile, Options{Validate: true})
 
 	// Should succeed with no errors
 	require.NoError(t, err)
@@ -1110,7 +1271,7 @@ func NoParams() (err error) {
 	err = os.WriteFile(inputFile, []byte(inputCode), 0644)
 	require.NoError(t, err)
 
-	err = Replace(inputFile, "", false, false, true, nil)
+	err = Replace(inputFile, Options{Validate: true})
 	require.NoError(t, err)
 
 	content, readErr := os.ReadFile(inputFile)
@@ -1141,7 +1302,7 @@ func LoadFile(path string) (data []byte, err error) {
 	require.NoError(t, err)
 
 	// Run insert with validate=true
-	err = Insert(inputFile, "", false, true, true, nil)
+	err = Insert(inputFile, Options{MinVariadic: true, Validate: true})
 
 	// Should return an error indicating missing insertions
 	require.Error(t, err)
@@ -1186,7 +1347,7 @@ func NoError() int {
 	require.NoError(t, err)
 
 	// Run insert with validate=true
-	err = Insert(inputFile, "", false, true, true, nil)
+	err = Insert(inputFile, Options{MinVariadic: true, Validate: true})
 
 	// Should succeed with no errors
 	require.NoError(t, err)
@@ -1216,15 +1377,15 @@ func F() (err error) { return nil }
 	require.NoError(t, err)
 
 	// Run remove with validate=true and outPath set (should ignore outPath and succeed - nothing to remove)
-	err = Remove(inputFile, outDir, false, true, nil)
+	err = Remove(inputFile, Options{OutPath: outDir, Validate: true})
 	require.NoError(t, err)
 
 	// Run replace with validate=true and outPath set (should ignore outPath and succeed - nothing to replace)
-	err = Replace(inputFile, outDir, false, false, true, nil)
+	err = Replace(inputFile, Options{OutPath: outDir, Validate: true})
 	require.NoError(t, err)
 
 	// Run insert with validate=true and outPath set (should ignore outPath but FAIL - missing wrapper)
-	err = Insert(inputFile, outDir, false, false, true, nil)
+	err = Insert(inputFile, Options{OutPath: outDir, Validate: true})
 	require.Error(t, err, "insert validation should fail when wrappers are missing")
 	assert.Contains(t, err.Error(), "missing error wrapper")
 
@@ -1263,7 +1424,7 @@ func LoadFile(path string) (data []byte, err error) {
 	require.NoError(t, err)
 
 	// Run remove with validate=true
-	err = Remove(inputFile, "", false, true, nil)
+	err = Remove(inputFile, Options{Validate: true})
 
 	// Should return an error indicating defer statements exist
 	require.Error(t, err)
@@ -1298,7 +1459,7 @@ func NoError() {
 	require.NoError(t, err)
 
 	// Run remove with validate=true
-	err = Remove(inputFile, "", false, true, nil)
+	err = Remove(inputFile, Options{Validate: true})
 
 	// Should succeed with no errors
 	require.NoError(t, err)
@@ -1348,7 +1509,7 @@ func ProcessData(ctx context.Context, id string) (err error) {
 	require.NoError(t, err)
 
 	// Validate should succeed: the defer statement itself is already correct.
-	err = Replace(inputFile, "", false, false, true, nil)
+	err = Replace(inputFile, Options{Validate: true})
 	require.NoError(t, err, "unsorted imports alone must not trigger a missing-wrapper error")
 
 	// File must not be modified in validate mode.
@@ -1394,7 +1555,7 @@ func ProcessData(ctx context.Context, id string) (err error) {
 	require.NoError(t, err)
 
 	// Run replace in normal (non-validate) mode.
-	err = Replace(inputFile, "", false, false, false, nil)
+	err = Replace(inputFile, Options{})
 	require.NoError(t, err)
 
 	// File must be byte-for-byte unchanged: no replacements were needed,
@@ -1445,7 +1606,7 @@ func Outdated(ctx context.Context, id string) (err error) {
 	err = os.WriteFile(inputFile, []byte(inputCode), 0644)
 	require.NoError(t, err)
 
-	err = Replace(inputFile, "", false, false, true, nil)
+	err = Replace(inputFile, Options{Validate: true})
 	require.Error(t, err)
 	// Exactly one missing wrapper, not two.
 	assert.Contains(t, err.Error(), "found 1 missing error wrapper")
@@ -0,0 +1,140 @@
+package rewrite
+
+import (
+	"go/ast"
+	"go/types"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/domonda/go-errs"
+)
+
+// SecretPolicy selects the function parameters that Insert and Replace
+// wrap with errs.KeepSecret in generated statements, so that sensitive
+// values don't show up in error messages and logs without
+// every parameter having to be wrapped by hand.
+//
+// A policy matches parameters:
+//   - whose names end with the words of one of the Names patterns
+//   - of one of the Types, also when referenced by a pointer
+//   - of struct types with fields tagged `errs:"secret"` if Tagged is true
+//
+// Types and struct tags can only be matched for packages
+// that were loaded with type information.
+// Parameters of types implementing errs.Secret are never wrapped.
+// A nil *SecretPolicy matches no parameters.
+type SecretPolicy struct {
+	// Names are parameter name patterns matched by whole words,
+	// case-insensitive and ignoring '_' and '-' characters
+	// like the field names of errs.RedactionPolicy,
+	// so "key" matches the parameters key and apiKey but not keyID or monkey.
+	Names []string
+
+	// Types are package path qualified type names
+	// like "crypto/rsa.PrivateKey" or "example.com/auth.Credentials".
+	// A leading '*' is ignored because pointers to the types also match.
+	Types []string
+
+	// Tagged matches parameters of struct types with fields
+	// tagged with `errs:"secret"`, see errs.RedactionStructTag.
+	// The errs.Redaction policy already redacts those fields
+	// when the parameters are formatted, so Tagged is only needed
+	// if Redaction is disabled or the whole struct should be hidden.
+	Tagged bool
+}
+
+// DefaultSecretPolicy returns the policy used by the -keepsecret option.
+// It matches the names of errs.DefaultRedactionFieldNames, the name key,
+// and the private key types of the standard library.
+// The name key is not one of errs.DefaultRedactionFieldNames
+// because struct fields named Key often hold map or database keys.
+// Tagged structs are not matched because their tagged fields
+// are redacted by errs.Redaction.
+func DefaultSecretPolicy() *SecretPolicy {
+	return &SecretPolicy{
+		Names: append(slices.Clone(errs.DefaultRedactionFieldNames), "key"),
+		Types: []string{
+			"crypto/rsa.PrivateKey",
+			"crypto/ecdsa.PrivateKey",
+			"crypto/ed25519.PrivateKey",
+			"crypto/ecdh.PrivateKey",
+		},
+	}
+}
+
+// params returns the names of the parameters of fun matched by the policy,
+// not including parameters that already implement errs.Secret.
+func (p *SecretPolicy) params(fun *funcInfo) map[string]bool {
+	if p == nil || fun.funcType.Params == nil {
+		return nil
+	}
+	names := errs.NewRedactionPolicy().WithFieldNames(p.Names...)
+	var result map[string]bool
+	for _, field := range fun.funcType.Params.List {
+		for _, name := range field.Names {
+			if name.Name == "_" || fun.secretNames[name.Name] || !p.matches(fun.info, names, name.Name, field.Type) {
+				continue
+			}
+			if result == nil {
+				result = make(map[string]bool)
+			}
+			result[name.Name] = true
+		}
+	}
+	return result
+}
+
+// matches reports whether the parameter is matched by the policy
+// using names to match the parameter name by the Names patterns.
+func (p *SecretPolicy) matches(info *types.Info, names *errs.RedactionPolicy, name string, typeExpr ast.Expr) bool {
+	if names.RedactsField(reflect.StructField{Name: name}) {
+		return true
+	}
+	t := typeOf(info, typeExpr)
+	if t == nil {
+		return false
+	}
+	for {
+		t = types.Unalias(t)
+		if named, ok := t.(*types.Named); ok && named.Obj().Pkg() != nil {
+			qualified := named.Obj().Pkg().Path() + "." + named.Obj().Name()
+			if slices.ContainsFunc(p.Types, func(typeName string) bool {
+				return strings.TrimLeft(typeName, "*") == qualified
+			}) {
+				return true
+			}
+		}
+		ptr, ok := t.(*types.Pointer)
+		if !ok {
+			break
+		}
+		t = ptr.Elem()
+	}
+	if st, ok := t.Underlying().(*types.Struct); ok && p.Tagged {
+		for i := range st.NumFields() {
+			tag := reflect.StructTag(st.Tag(i)).Get(errs.RedactionStructTag)
+			if slices.Contains(strings.Split(tag, ","), "secret") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sensitiveArgs returns the arguments of the existing
+// defer statement that pass parameters matched by the policy
+// without wrapping them with errs.KeepSecret.
+func (p *SecretPolicy) sensitiveArgs(fun *funcInfo, stmt *ast.DeferStmt) []*ast.Ident {
+	sensitive := p.params(fun)
+	if len(sensitive) == 0 {
+		return nil
+	}
+	var result []*ast.Ident
+	for i, arg := range stmt.Call.Args {
+		if ident, ok := arg.(*ast.Ident); ok && i > 0 && sensitive[ident.Name] {
+			result = append(result, ident)
+		}
+	}
+	return result
+}
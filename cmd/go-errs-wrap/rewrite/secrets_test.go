@@ -0,0 +1,186 @@
+package rewrite
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/domonda/go-errs"
+)
+
+func TestInsertKeepSecret(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	inputCode := `package test
+
+import "github.com/domonda/go-errs"
+
+func Login(user, password string) (err error) {
+	return nil
+}
+
+func Existing(ctx context.Context, apiToken string) (err error) {
+	defer errs.WrapWith2FuncParams(&err, ctx, apiToken)
+
+	return nil
+}
+
+func Kept(userPassword string) (err error) {
+	defer errs.WrapWith1FuncParam(&err, errs.KeepSecret(userPassword))
+
+	return nil
+}
+`
+	expected := `package test
+
+import "github.com/domonda/go-errs"
+
+func Login(user, password string) (err error) {
+	defer errs.WrapWith2FuncParams(&err, user, errs.KeepSecret(password))
+
+	return nil
+}
+
+func Existing(ctx context.Context, apiToken string) (err error) {
+	defer errs.WrapWith2FuncParams(&err, ctx, errs.KeepSecret(apiToken))
+
+	return nil
+}
+
+func Kept(userPassword string) (err error) {
+	defer errs.WrapWith1FuncParam(&err, errs.KeepSecret(userPassword))
+
+	return nil
+}
+`
+	inputFile := filepath.Join(tmpDir, "input.go")
+	require.NoError(t, os.WriteFile(inputFile, []byte(inputCode), 0644))
+
+	err := Insert(inputFile, Options{Secrets: DefaultSecretPolicy(), Validate: true})
+	require.Error(t, err, "validate reports unwrapped sensitive parameters")
+
+	err = Insert(inputFile, Options{Secrets: DefaultSecretPolicy()})
+	require.NoError(t, err)
+
+	output, err := os.ReadFile(inputFile)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(output))
+
+	err = Insert(inputFile, Options{Secrets: DefaultSecretPolicy(), Validate: true})
+	require.NoError(t, err)
+	err = Replace(inputFile, Options{Secrets: DefaultSecretPolicy(), Validate: true})
+	require.NoError(t, err)
+}
+
+func TestReplaceKeepSecretDebugID(t *testing.T) {
+	code := `package test
+
+func UpToDate(user, password string) (err error) {
+	defer errs.WrapWith2FuncParams(&err, user, password)
+	return nil
+}
+
+func Stale(user, password string) (err error) {
+	defer errs.WrapWith1FuncParam(&err, password)
+	return nil
+}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "test.go", code, parser.ParseComments)
+	require.NoError(t, err)
+
+	replacements, _, err := processFile(fset, file, nil, false, "", DefaultSecretPolicy(), nil, modeReplace)
+	require.NoError(t, err)
+	require.Len(t, replacements, 2)
+	assert.Equal(t, "errs.KeepSecret for sensitive parameters password", replacements[0].DebugID)
+	assert.Equal(t, "defer errs.WrapWith2FuncParams(&err, user, errs.KeepSecret(password))", replacements[0].Replacement)
+	assert.Equal(t, "replace defer errs.Wrap", replacements[1].DebugID)
+	assert.Equal(t, "defer errs.WrapWith2FuncParams(&err, user, errs.KeepSecret(password))", replacements[1].Replacement)
+}
+
+func TestKeepSecretTypes(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"auth.go": `package m
+
+import "crypto/rsa"
+
+type Credentials struct {
+	User     string
+	Password string
+}
+
+type Account struct {
+	ID  int
+	PIN string ` + "`errs:\"secret\"`" + `
+}
+
+func Sign(key *rsa.PrivateKey, data []byte) (err error) {
+	return nil
+}
+
+func Connect(creds *Credentials, account Account, host string) (err error) {
+	return nil
+}
+`,
+	})
+
+	policy := DefaultSecretPolicy()
+	policy.Types = append(policy.Types, "*example.com/m.Credentials")
+	err := Insert(dir, Options{Secrets: policy})
+	require.NoError(t, err)
+
+	output, err := os.ReadFile(filepath.Join(dir, "auth.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(output), "defer errs.WrapWith2FuncParams(&err, errs.KeepSecret(key), data)\n")
+	assert.Contains(t, string(output), "defer errs.WrapWith3FuncParams(&err, errs.KeepSecret(creds), account, host)\n")
+
+	// errs.Redaction already redacts the tagged fields of account
+	policy.Tagged = true
+	err = Replace(dir, Options{Secrets: policy})
+	require.NoError(t, err)
+
+	output, err = os.ReadFile(filepath.Join(dir, "auth.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(output), "defer errs.WrapWith3FuncParams(&err, errs.KeepSecret(creds), errs.KeepSecret(account), host)\n")
+}
+
+func TestSecretPolicyNames(t *testing.T) {
+	policy := &SecretPolicy{Names: []string{"apiKey", "pass_word"}}
+	names := errs.NewRedactionPolicy().WithFieldNames(policy.Names...)
+	for name, want := range map[string]bool{
+		"apiKey":      true,
+		"userAPIKey":  true,
+		"api_key":     true,
+		"password":    true,
+		"newPassword": true,
+		"APIKeyID":    false,
+		"key":         false,
+		"passphrase":  false,
+	} {
+		assert.Equal(t, want, policy.matches(nil, names, name, nil), name)
+	}
+	assert.Nil(t, (*SecretPolicy)(nil).params(&funcInfo{}), "nil policy")
+}
+
+func TestDefaultSecretPolicyNames(t *testing.T) {
+	policy := DefaultSecretPolicy()
+	names := errs.NewRedactionPolicy().WithFieldNames(policy.Names...)
+	for name, want := range map[string]bool{
+		"key":        true,
+		"signingKey": true,
+		"apiKey":     true,
+		"token":      true,
+		"secret":     true,
+		"keyID":      false,
+		"monkey":     false,
+		"tokenCount": false,
+		"secretary":  false,
+	} {
+		assert.Equal(t, want, policy.matches(nil, names, name, nil), name)
+	}
+}
@@ -0,0 +1,620 @@
+package rewrite
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"io"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ungerik/go-astvisit"
+
+	"github.com/domonda/go-errs"
+)
+
+// ConvertSentinels converts package level error variables
+// in Go source files at the given path to errs.Sentinel constants:
+//
+//	var ErrNotFound = errors.New("not found")
+//
+// becomes
+//
+//	const ErrNotFound errs.Sentinel = "not found"
+//
+// Variables initialized with errors.New or errs.New and a constant message
+// are converted if the type information of all loaded packages shows
+// that they are never assigned, their address is never taken,
+// they are not type asserted, not compared with nil, not passed
+// as argument of a type parameter and not used to declare variables
+// with an inferred type that would change from error to errs.Sentinel.
+// Because sentinels are compared by their message instead of by pointer,
+// variables with the same message as another sentinel are kept.
+// Code outside of the loaded packages is not checked, so sourcePath
+// should cover all packages using exported variables.
+// Files without type information are not changed,
+// neither are the variables of files where errs doesn't refer
+// to the go-errs package and the package doesn't import it.
+// Before writing any file the changed packages are type-checked,
+// if the conversion causes new type errors no file is changed
+// and an error is returned.
+//
+// The sourcePath is used like by Replace.
+func ConvertSentinels(sourcePath string, options Options) (err error) {
+	defer errs.WrapWithFuncParams(&err, sourcePath, options)
+
+	return process(sourcePath, options, modeSentinels)
+}
+
+// sentinelVar is a package level error variable
+// that is a candidate for an errs.Sentinel constant.
+type sentinelVar struct {
+	name string
+	pos  token.Position
+	msg  string
+	// keep is the reason why the variable can't be converted,
+	// empty if it can be converted
+	keep string
+}
+
+// sentinelVars are the sentinelVar candidates by sentinelKey.
+type sentinelVars map[string]*sentinelVar
+
+// sentinelKey returns the key of the package level object obj
+// that is the same for its definition and uses in all loaded packages.
+func sentinelKey(obj types.Object) string {
+	if obj == nil || obj.Pkg() == nil {
+		return ""
+	}
+	return obj.Pkg().Path() + "." + obj.Name()
+}
+
+// findSentinelVars returns the package level error variables
+// declared in files that can be converted to errs.Sentinel constants
+// and the reasons why the others have to be kept, see ConvertSentinels.
+func findSentinelVars(files []*sourceFile) sentinelVars {
+	vars := make(sentinelVars)
+	// Messages of the errs.Sentinel constants by their key
+	constMsgs := make(map[string]string)
+	for _, f := range files {
+		if f.info == nil {
+			continue
+		}
+		for _, decl := range f.file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.CONST && gen.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				spec := spec.(*ast.ValueSpec)
+				for i, name := range spec.Names {
+					obj := f.info.Defs[name]
+					switch {
+					case gen.Tok == token.CONST && isSentinelType(obj):
+						if c, ok := obj.(*types.Const); ok && c.Val().Kind() == constant.String {
+							constMsgs[sentinelKey(obj)] = constant.StringVal(c.Val())
+						}
+					case gen.Tok == token.VAR && name.Name != "_" && len(spec.Values) == len(spec.Names):
+						msg, ok := newErrorMsg(f.info, spec.Values[i])
+						if !ok || spec.Type != nil && !isErrorTypeOf(f.info, spec.Type) {
+							continue
+						}
+						v := &sentinelVar{
+							name: name.Name,
+							pos:  f.fset.Position(name.Pos()),
+							msg:  msg,
+						}
+						if errsQualifierTaken(f.file, obj.Pkg()) {
+							v.keep = "errs doesn't refer to " + errsPkgPath
+						}
+						vars[sentinelKey(obj)] = v
+					}
+				}
+			}
+		}
+	}
+	if len(vars) == 0 {
+		return vars
+	}
+
+	// Keep variables used in ways that a constant can't be used
+	for _, f := range files {
+		var stack []ast.Node
+		ast.Inspect(f.file, func(n ast.Node) bool {
+			if n == nil {
+				stack = stack[:len(stack)-1]
+				return false
+			}
+			stack = append(stack, n)
+			ident, ok := n.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			var candidates []*sentinelVar
+			if f.info != nil {
+				if v := vars[sentinelKey(f.info.Uses[ident])]; v != nil {
+					candidates = append(candidates, v)
+				}
+			} else {
+				// Without type information every variable
+				// with the name of the identifier could be used
+				for _, v := range vars {
+					if v.name == ident.Name {
+						candidates = append(candidates, v)
+					}
+				}
+			}
+			if len(candidates) == 0 {
+				return true
+			}
+			reason := SentinelUseReason(f.info, stack)
+			if reason == "" {
+				return true
+			}
+			for _, v := range candidates {
+				if v.keep == "" {
+					v.keep = fmt.Sprintf("%s at %s", reason, f.fset.Position(ident.Pos()))
+				}
+			}
+			return true
+		})
+	}
+
+	// Keep variables that would become equal to another sentinel
+	keysByMsg := make(map[string][]string)
+	for key, v := range vars {
+		if v.keep == "" {
+			keysByMsg[v.msg] = append(keysByMsg[v.msg], key)
+		}
+	}
+	for key, msg := range constMsgs {
+		if keys := keysByMsg[msg]; len(keys) > 0 {
+			keysByMsg[msg] = append(keys, key)
+		}
+	}
+	for _, keys := range keysByMsg {
+		slices.Sort(keys)
+		for _, key := range keys {
+			if v := vars[key]; v != nil && len(keys) > 1 {
+				others := slices.DeleteFunc(slices.Clone(keys), func(k string) bool { return k == key })
+				v.keep = "same message as " + strings.Join(others, ", ")
+			}
+		}
+	}
+	return vars
+}
+
+// errsQualifierTaken reports whether the errs qualifier used by
+// the converted constants of file doesn't refer to the go-errs package
+// because file doesn't import it and errs is declared in pkg
+// or imported with that name from another package.
+func errsQualifierTaken(file *ast.File, pkg *types.Package) bool {
+	if importsErrs(file) {
+		return false
+	}
+	for _, imp := range file.Imports {
+		name := ""
+		if imp.Name != nil {
+			name = imp.Name.Name
+		} else if path, err := strconv.Unquote(imp.Path.Value); err == nil {
+			name = path[strings.LastIndex(path, "/")+1:]
+		}
+		if name == "errs" {
+			return true
+		}
+	}
+	return pkg != nil && pkg.Scope().Lookup("errs") != nil
+}
+
+// SentinelUseReason returns why the identifier at the end of stack,
+// a use of a sentinel variable, prevents converting it to a constant,
+// or an empty string if a constant can be used there.
+// The info of the file may be nil.
+func SentinelUseReason(info *types.Info, stack []ast.Node) string {
+	node := stack[len(stack)-1]
+	for i := len(stack) - 2; i >= 0; i-- {
+		switch parent := stack[i].(type) {
+		case *ast.SelectorExpr:
+			if parent.Sel != node {
+				return ""
+			}
+		case *ast.ParenExpr:
+		default:
+			return useReason(info, parent, node)
+		}
+		node = stack[i]
+	}
+	return ""
+}
+
+// useReason returns why node can't be a constant
+// as child of parent, see SentinelUseReason.
+func useReason(info *types.Info, parent, node ast.Node) string {
+	switch parent := parent.(type) {
+	case *ast.AssignStmt:
+		if slices.Contains(parent.Lhs, node.(ast.Expr)) {
+			return "assigned"
+		}
+		if parent.Tok == token.DEFINE {
+			return "declares a variable with inferred type"
+		}
+	case *ast.ValueSpec:
+		if parent.Type == nil && slices.Contains(parent.Values, node.(ast.Expr)) {
+			return "declares a variable with inferred type"
+		}
+	case *ast.UnaryExpr:
+		if parent.Op == token.AND {
+			return "address taken"
+		}
+	case *ast.TypeAssertExpr:
+		if parent.X == node {
+			return "type asserted"
+		}
+	case *ast.BinaryExpr:
+		other := parent.X
+		if other == node {
+			other = parent.Y
+		}
+		if ident, ok := ast.Unparen(other).(*ast.Ident); ok && ident.Name == "nil" {
+			return "compared with nil"
+		}
+	case *ast.CallExpr:
+		if i := slices.Index(parent.Args, node.(ast.Expr)); i >= 0 && isTypeParamArg(info, parent, i) {
+			return "passed as argument of a type parameter"
+		}
+	}
+	return ""
+}
+
+// isTypeParamArg reports whether the type of the parameter
+// for argument i of the call of a generic function
+// depends on a type parameter inferred from the argument.
+func isTypeParamArg(info *types.Info, call *ast.CallExpr, i int) bool {
+	if info == nil {
+		return false
+	}
+	var ident *ast.Ident
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		ident = fun
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	case *ast.IndexExpr, *ast.IndexListExpr:
+		return false // Explicitly instantiated
+	default:
+		return false
+	}
+	if _, ok := info.Instances[ident]; !ok {
+		return false
+	}
+	fn, ok := info.Uses[ident].(*types.Func)
+	if !ok {
+		return false
+	}
+	params := fn.Origin().Signature().Params()
+	if params.Len() == 0 {
+		return false
+	}
+	param := params.At(min(i, params.Len()-1)).Type()
+	if fn.Signature().Variadic() && i >= params.Len()-1 {
+		param = param.(*types.Slice).Elem()
+	}
+	return hasTypeParam(param)
+}
+
+// hasTypeParam reports whether t is or contains a type parameter.
+func hasTypeParam(t types.Type) bool {
+	switch t := t.(type) {
+	case *types.TypeParam:
+		return true
+	case *types.Pointer:
+		return hasTypeParam(t.Elem())
+	case *types.Slice:
+		return hasTypeParam(t.Elem())
+	case *types.Array:
+		return hasTypeParam(t.Elem())
+	case *types.Chan:
+		return hasTypeParam(t.Elem())
+	case *types.Map:
+		return hasTypeParam(t.Key()) || hasTypeParam(t.Elem())
+	case *types.Named:
+		for arg := range t.TypeArgs().Types() {
+			if hasTypeParam(arg) {
+				return true
+			}
+		}
+	case *types.Signature:
+		for v := range t.Params().Variables() {
+			if hasTypeParam(v.Type()) {
+				return true
+			}
+		}
+		for v := range t.Results().Variables() {
+			if hasTypeParam(v.Type()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// newErrorMsg returns the constant message of expr
+// if it is a call of errors.New or errs.New.
+func newErrorMsg(info *types.Info, expr ast.Expr) (msg string, ok bool) {
+	arg, ok := newErrorArg(info, expr)
+	if !ok {
+		return "", false
+	}
+	tv := info.Types[arg]
+	if tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
+
+// newErrorArg returns the message argument of expr
+// if it is a call of errors.New or errs.New.
+func newErrorArg(info *types.Info, expr ast.Expr) (arg ast.Expr, ok bool) {
+	call, ok := ast.Unparen(expr).(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 || call.Ellipsis.IsValid() {
+		return nil, false
+	}
+	var ident *ast.Ident
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		ident = fun // dot-imported
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	default:
+		return nil, false
+	}
+	fn, ok := info.Uses[ident].(*types.Func)
+	if !ok || fn.Name() != "New" || fn.Pkg() == nil || fn.Pkg().Path() != "errors" && !isErrsPkgPath(fn.Pkg().Path()) {
+		return nil, false
+	}
+	return call.Args[0], true
+}
+
+// isSentinelType reports whether obj has the type errs.Sentinel.
+func isSentinelType(obj types.Object) bool {
+	if obj == nil {
+		return false
+	}
+	named, ok := obj.Type().(*types.Named)
+	return ok && named.Obj().Name() == "Sentinel" && named.Obj().Pkg() != nil && isErrsPkgPath(named.Obj().Pkg().Path())
+}
+
+// convertSentinelsFile returns the replacements converting the var specs
+// of astFile that declare only convertible vars to errs.Sentinel constants
+// and the imports they need.
+// Declarations that also declare other variables are split
+// into a var and a const declaration.
+func convertSentinelsFile(fset *token.FileSet, astFile *ast.File, info *types.Info, source []byte, vars sentinelVars, verboseOut io.Writer) (replacements replacements, imports astvisit.Imports, err error) {
+	defer errs.WrapWithFuncParams(&err, fset, astFile, info, source, vars, verboseOut)
+
+	if IsFileIgnored(astFile) {
+		if verboseOut != nil {
+			fmt.Fprintf(verboseOut, "%s: ignoring file with %s\n", fset.Position(astFile.Package), IgnoreDirective)
+		}
+		return nil, nil, nil
+	}
+	if info == nil {
+		return nil, nil, nil
+	}
+
+	q := qualifier(astFile)
+	offset := func(pos token.Pos) int { return fset.Position(pos).Offset }
+	for _, decl := range astFile.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.VAR {
+			continue
+		}
+		var converted []*ast.ValueSpec
+		for _, spec := range gen.Specs {
+			spec := spec.(*ast.ValueSpec)
+			convert := true
+			for _, name := range spec.Names {
+				v := vars[sentinelKey(info.Defs[name])]
+				if v == nil {
+					convert = false
+					continue
+				}
+				if v.keep != "" {
+					if verboseOut != nil {
+						fmt.Fprintf(verboseOut, "%s: keeping var %s: %s\n", v.pos, v.name, v.keep)
+					}
+					convert = false
+				}
+			}
+			if convert {
+				converted = append(converted, spec)
+			}
+		}
+		if len(converted) == 0 {
+			continue
+		}
+
+		// Replace the declaration from the var keyword to its end
+		// with its source, the converted specs replaced
+		var (
+			b     strings.Builder
+			pos   = offset(gen.TokPos) + len("var")
+			names []string
+		)
+		if len(converted) == len(gen.Specs) {
+			b.WriteString("const")
+			for _, spec := range converted {
+				b.Write(source[pos:offset(spec.Pos())])
+				b.WriteString(sentinelSpec(info, source, offset, q, spec))
+				pos = offset(spec.End())
+				for _, name := range spec.Names {
+					names = append(names, name.Name)
+				}
+			}
+			b.Write(source[pos:offset(gen.End())])
+		} else {
+			// Move the converted specs with their comments
+			// out of the var block into a const declaration
+			var docs, consts []string
+			b.WriteString("var")
+			for _, spec := range converted {
+				start, end := spec.Pos(), spec.End()
+				if spec.Doc != nil {
+					start = spec.Doc.Pos()
+				}
+				if spec.Comment != nil {
+					end = spec.Comment.End()
+				}
+				// Remove the whole lines of the spec
+				startOffset, endOffset := offset(start), offset(end)
+				for startOffset > pos && (source[startOffset-1] == ' ' || source[startOffset-1] == '\t') {
+					startOffset--
+				}
+				if endOffset < len(source) && source[endOffset] == '\n' {
+					endOffset++
+				}
+				b.Write(source[pos:startOffset])
+				pos = endOffset
+
+				doc, constSpec := "", sentinelSpec(info, source, offset, q, spec)
+				if spec.Doc != nil {
+					doc = string(source[offset(spec.Doc.Pos()):offset(spec.Doc.End())]) + "\n"
+				}
+				if spec.Comment != nil {
+					constSpec += " " + string(source[offset(spec.Comment.Pos()):offset(spec.Comment.End())])
+				}
+				docs = append(docs, doc)
+				consts = append(consts, constSpec)
+				for _, name := range spec.Names {
+					names = append(names, name.Name)
+				}
+			}
+			b.Write(source[pos:offset(gen.End())])
+			b.WriteString("\n\n")
+			if len(consts) == 1 {
+				b.WriteString(docs[0] + "const " + consts[0])
+			} else {
+				b.WriteString("const (\n")
+				for i := range consts {
+					if i > 0 && docs[i] != "" {
+						b.WriteString("\n")
+					}
+					b.WriteString(docs[i] + consts[i] + "\n")
+				}
+				b.WriteString(")")
+			}
+		}
+
+		replacement := b.String()
+		if verboseOut != nil {
+			fmt.Fprintf(verboseOut, "%s: converting var %s to errs.Sentinel constants\n", fset.Position(gen.Pos()), strings.Join(names, ", "))
+		}
+		replacements.add(RuleVarSentinel, gen, replacement, "const errs.Sentinel for var "+strings.Join(names, ", "))
+	}
+	if len(replacements) == 0 {
+		return nil, nil, nil
+	}
+	imports = make(astvisit.Imports)
+	if !importsErrs(astFile) {
+		imports[errsImportPath] = struct{}{}
+	}
+	return replacements, imports, nil
+}
+
+// checkConvertedSentinels type-checks the packages of the files
+// that have type information with the rewritten sources of outputs
+// and returns an error if that causes type errors
+// the packages don't have with their sources.
+func checkConvertedSentinels(files []*sourceFile, outputs []processedFile, tests bool) (err error) {
+	defer errs.WrapWithFuncParams(&err, files, outputs, tests)
+
+	overlay := make(map[string][]byte)
+	for _, out := range outputs {
+		if !bytes.Equal(out.source, out.rewritten) {
+			overlay[out.path] = out.rewritten
+		}
+	}
+	if len(overlay) == 0 {
+		return nil
+	}
+	var dirs []string
+	for _, f := range files {
+		if dir := filepath.Dir(f.path); f.info != nil && !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	before, err := loadTypeErrors(dirs, nil, tests)
+	if err != nil {
+		return err
+	}
+	after, err := loadTypeErrors(dirs, overlay, tests)
+	if err != nil {
+		return err
+	}
+	// Positions change with the sources, compare the messages
+	existing := make(map[string]int)
+	for _, e := range before {
+		existing[e.Msg]++
+	}
+	var newErrs []string
+	for _, e := range after {
+		if existing[e.Msg] > 0 {
+			existing[e.Msg]--
+			continue
+		}
+		newErrs = append(newErrs, e.Error())
+	}
+	if len(newErrs) > 0 {
+		return errs.Errorf("converted sentinels don't compile, no files changed:\n%s", strings.Join(newErrs, "\n"))
+	}
+	return nil
+}
+
+// sentinelSpec returns the errs.Sentinel constant spec replacing
+// the var spec that initializes its names with errors.New or errs.New.
+// Untyped message arguments are kept like in the source,
+// others are replaced with their constant value.
+func sentinelSpec(info *types.Info, source []byte, offset func(token.Pos) int, qualifier string, spec *ast.ValueSpec) string {
+	names := make([]string, len(spec.Names))
+	values := make([]string, len(spec.Values))
+	for i, name := range spec.Names {
+		names[i] = name.Name
+		arg, _ := newErrorArg(info, spec.Values[i])
+		if isUntypedConst(info, arg) {
+			values[i] = string(source[offset(arg.Pos()):offset(arg.End())])
+		} else {
+			values[i] = strconv.Quote(constant.StringVal(info.Types[arg].Value))
+		}
+	}
+	return strings.Join(names, ", ") + " " + qualifier + "Sentinel = " + strings.Join(values, ", ")
+}
+
+// isUntypedConst reports whether expr is a literal,
+// an untyped constant or a concatenation of them
+// that can be assigned to an errs.Sentinel constant.
+func isUntypedConst(info *types.Info, expr ast.Expr) bool {
+	var ident *ast.Ident
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.BasicLit:
+		return true
+	case *ast.BinaryExpr:
+		return isUntypedConst(info, e.X) && isUntypedConst(info, e.Y)
+	case *ast.Ident:
+		ident = e
+	case *ast.SelectorExpr:
+		ident = e.Sel
+	default:
+		return false
+	}
+	c, ok := info.Uses[ident].(*types.Const)
+	if !ok {
+		return false
+	}
+	basic, ok := c.Type().(*types.Basic)
+	return ok && basic.Info()&types.IsUntyped != 0
+}
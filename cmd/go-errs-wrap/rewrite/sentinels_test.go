@@ -0,0 +1,331 @@
+package rewrite
+
+import (
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertSentinels(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"errors.go": `package m
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/domonda/go-errs"
+)
+
+// ErrNotFound is returned if nothing was found.
+var ErrNotFound = errors.New("not found")
+
+var (
+	// ErrAssigned is assigned by package sub
+	ErrAssigned = errors.New("assigned")
+
+	ErrA, ErrB = errs.New("a"), errors.New(msgB) // a and b
+
+	counter = 1
+
+	// ErrC is c
+	ErrC = errors.New("c")
+
+	ErrDup1 = errors.New("dup")
+	ErrDup2 = errors.New("dup")
+	ErrSame = errors.New("existing")
+)
+
+const msgB = "b"
+
+const ErrExisting errs.Sentinel = "existing"
+
+var ErrTyped error = errors.New("typed")
+
+var ErrInferred = errors.New("inferred")
+
+var ErrDynamic = errors.New(fmt.Sprint("dynamic"))
+
+func F() error {
+	x := ErrInferred
+	_ = x
+	counter++
+	return ErrNotFound
+}
+`,
+		"only.go": `package m
+
+import "errors"
+
+var ErrOnly = errors.New("only")
+
+var ErrAddr = errors.New("addr")
+
+var ptr = &ErrAddr
+`,
+		"sub/sub.go": `package sub
+
+import "example.com/m"
+
+func init() {
+	m.ErrAssigned = nil
+}
+`,
+	})
+	read := func(name string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	var verbose strings.Builder
+	err := ConvertSentinels(filepath.Join(dir, "..."), Options{VerboseOut: &verbose})
+	require.NoError(t, err)
+
+	assert.Equal(t, `package m
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/domonda/go-errs"
+)
+
+// ErrNotFound is returned if nothing was found.
+const ErrNotFound errs.Sentinel = "not found"
+
+var (
+	// ErrAssigned is assigned by package sub
+	ErrAssigned = errors.New("assigned")
+
+	counter = 1
+
+	ErrDup1 = errors.New("dup")
+	ErrDup2 = errors.New("dup")
+	ErrSame = errors.New("existing")
+)
+
+const (
+	ErrA, ErrB errs.Sentinel = "a", msgB // a and b
+
+	// ErrC is c
+	ErrC errs.Sentinel = "c"
+)
+
+const msgB = "b"
+
+const ErrExisting errs.Sentinel = "existing"
+
+const ErrTyped errs.Sentinel = "typed"
+
+var ErrInferred = errors.New("inferred")
+
+var ErrDynamic = errors.New(fmt.Sprint("dynamic"))
+
+func F() error {
+	x := ErrInferred
+	_ = x
+	counter++
+	return ErrNotFound
+}
+`, read("errors.go"))
+	assert.Equal(t, `package m
+
+import (
+	"errors"
+
+	"github.com/domonda/go-errs"
+)
+
+const ErrOnly errs.Sentinel = "only"
+
+var ErrAddr = errors.New("addr")
+
+var ptr = &ErrAddr
+`, read("only.go"))
+
+	for _, reason := range []string{
+		"keeping var ErrAssigned: assigned at " + filepath.Join(dir, "sub", "sub.go") + ":6:4",
+		"keeping var ErrDup1: same message as example.com/m.ErrDup2",
+		"keeping var ErrSame: same message as example.com/m.ErrExisting",
+		"keeping var ErrInferred: declares a variable with inferred type",
+		"keeping var ErrAddr: address taken",
+	} {
+		assert.Contains(t, verbose.String(), reason)
+	}
+}
+
+func TestConvertSentinelsKeep(t *testing.T) {
+	files := map[string]string{
+		"errors.go": `package m
+
+import "errors"
+
+var ErrNil = errors.New("nil")
+
+var ErrGeneric = errors.New("generic")
+
+var ErrArg = errors.New("arg")
+
+func Ptr[T any](v T) *T { return &v }
+
+func Is(err, target error) bool { return err == target }
+
+func F(err error) bool {
+	p := Ptr(ErrGeneric)
+	*p = nil
+	return ErrNil != nil && Is(err, ErrArg)
+}
+`,
+		"taken/taken.go": `package taken
+
+import "errors"
+
+var errs = 1
+
+var ErrTaken = errors.New("taken")
+`,
+		"imported/imported.go": `package imported
+
+import (
+	"errors"
+
+	errs "example.com/m/taken"
+)
+
+var ErrImported = errors.New("imported")
+
+var _ = errs.ErrTaken
+`,
+	}
+	dir := writeTestModule(t, files)
+
+	var verbose strings.Builder
+	err := ConvertSentinels(filepath.Join(dir, "..."), Options{VerboseOut: &verbose})
+	require.NoError(t, err)
+
+	output, err := os.ReadFile(filepath.Join(dir, "errors.go"))
+	require.NoError(t, err)
+	assert.Equal(t, strings.Replace(files["errors.go"], `var ErrArg = errors.New("arg")`, `const ErrArg errs.Sentinel = "arg"`, 1), strings.Replace(string(output), "import (\n\t\"errors\"\n\n\t\"github.com/domonda/go-errs\"\n)", `import "errors"`, 1))
+	for _, name := range []string{"taken/taken.go", "imported/imported.go"} {
+		output, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		assert.Equal(t, files[name], string(output), name)
+	}
+	for _, reason := range []string{
+		"keeping var ErrNil: compared with nil",
+		"keeping var ErrGeneric: passed as argument of a type parameter",
+		"keeping var ErrTaken: errs doesn't refer to github.com/domonda/go-errs",
+		"keeping var ErrImported: errs doesn't refer to github.com/domonda/go-errs",
+	} {
+		assert.Contains(t, verbose.String(), reason)
+	}
+}
+
+func TestConvertSentinelsTypeCheck(t *testing.T) {
+	code := `package m
+
+import "errors"
+
+var ErrSwitch = errors.New("switch")
+
+func F() bool {
+	switch ErrSwitch {
+	case nil:
+		return false
+	}
+	return true
+}
+`
+	dir := writeTestModule(t, map[string]string{"errors.go": code})
+
+	err := ConvertSentinels(dir, Options{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "converted sentinels don't compile, no files changed")
+
+	output, err := os.ReadFile(filepath.Join(dir, "errors.go"))
+	require.NoError(t, err)
+	assert.Equal(t, code, string(output), "no files changed")
+}
+
+func TestConvertSentinelsValidate(t *testing.T) {
+	code := `package m
+
+import "errors"
+
+var ErrNotFound = errors.New("not found")
+`
+	dir := writeTestModule(t, map[string]string{"errors.go": code})
+
+	err := ConvertSentinels(dir, Options{Validate: true})
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	require.Len(t, validationErr.Findings, 1)
+	assert.Equal(t, RuleVarSentinel, validationErr.Findings[0].Rule)
+	assert.Equal(t, `const ErrNotFound errs.Sentinel = "not found"`, validationErr.Findings[0].Replacement)
+
+	output, err := os.ReadFile(filepath.Join(dir, "errors.go"))
+	require.NoError(t, err)
+	assert.Equal(t, code, string(output), "validate doesn't modify files")
+}
+
+func TestSentinelUseReason(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "test.go", `package test
+
+func F() {
+	ErrX = nil
+	(ErrX) = nil
+	x := ErrX
+	var y = ErrX
+	var z error = ErrX
+	p := &pkg.ErrX
+	_ = ErrX.(interface{ Timeout() bool })
+	_ = err == ErrX
+	f(ErrX)
+	_ = ErrX.Error()
+	_ = ErrX != nil
+	_ = nil == (ErrX)
+}
+`, 0)
+	require.NoError(t, err)
+
+	var (
+		stack   []ast.Node
+		reasons []string
+	)
+	ast.Inspect(file, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return false
+		}
+		stack = append(stack, n)
+		if ident, ok := n.(*ast.Ident); ok && ident.Name == "ErrX" {
+			reasons = append(reasons, SentinelUseReason(nil, stack))
+		}
+		return true
+	})
+	assert.Equal(t, []string{
+		"assigned",
+		"assigned",
+		"declares a variable with inferred type",
+		"declares a variable with inferred type",
+		"",
+		"address taken",
+		"type asserted",
+		"",
+		"",
+		"",
+		"compared with nil",
+		"compared with nil",
+	}, reasons)
+}
@@ -0,0 +1,315 @@
+package rewrite
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/domonda/go-errs"
+)
+
+// Stats are the error wrapping statistics of a package
+// or of all packages of a StatsReport.
+type Stats struct {
+	// Package is the import path of the package,
+	// or its directory if the import path is not known.
+	// Empty for the total of a StatsReport.
+	Package string `json:"package,omitempty"`
+
+	// ErrorFuncs is the number of functions with an error result,
+	// the sum of Wrapped, Missing and Unnamed.
+	// Function literals are counted as ErrorFuncLits instead.
+	ErrorFuncs int `json:"errorFuncs"`
+	// Wrapped is the number of ErrorFuncs with a defer errs.Wrap statement.
+	Wrapped int `json:"wrapped"`
+	// Missing is the number of ErrorFuncs with a named error result
+	// but without defer errs.Wrap statement, insert would add them.
+	Missing int `json:"missing"`
+	// Unnamed is the number of ErrorFuncs with an anonymous error result
+	// and without defer errs.Wrap statement, see Insert for naming them.
+	Unnamed int `json:"unnamed"`
+	// ErrorFuncLits is the number of function literals with an error result.
+	// They are not part of Coverage because closures like
+	// f := func() error {...} usually have anonymous results.
+	ErrorFuncLits int `json:"errorFuncLits"`
+	// Stale is the number of defer errs.Wrap statements and
+	// //#wrap-result-err markers that replace would change.
+	Stale int `json:"stale"`
+
+	// Variadic is the number of errs.WrapWithFuncParams statements.
+	Variadic int `json:"variadic"`
+	// Specialized is the number of errs.WrapWithNFuncParams statements.
+	Specialized int `json:"specialized"`
+	// KeepSecretParams is the number of parameters passed
+	// wrapped with errs.KeepSecret to defer errs.Wrap statements.
+	KeepSecretParams int `json:"keepSecretParams"`
+
+	// Coverage is the percentage of ErrorFuncs that are Wrapped,
+	// 100 if there are no ErrorFuncs.
+	Coverage float64 `json:"coverage"`
+}
+
+// add adds the counts of other to s.
+func (s *Stats) add(other *Stats) {
+	s.ErrorFuncs += other.ErrorFuncs
+	s.Wrapped += other.Wrapped
+	s.Missing += other.Missing
+	s.Unnamed += other.Unnamed
+	s.ErrorFuncLits += other.ErrorFuncLits
+	s.Stale += other.Stale
+	s.Variadic += other.Variadic
+	s.Specialized += other.Specialized
+	s.KeepSecretParams += other.KeepSecretParams
+}
+
+// updateCoverage calculates Coverage from the counts.
+func (s *Stats) updateCoverage() {
+	s.Coverage = 100
+	if s.ErrorFuncs > 0 {
+		s.Coverage = float64(s.Wrapped) * 100 / float64(s.ErrorFuncs)
+	}
+}
+
+// StatsReport is the result of CollectStats.
+type StatsReport struct {
+	// Packages are sorted by Package.
+	Packages []*Stats `json:"packages"`
+	// Total of all Packages.
+	Total Stats `json:"total"`
+}
+
+// CollectStats returns the error wrapping statistics per package
+// of the Go source files at sourcePath without modifying them.
+//
+// The sourcePath and Options.Config select the files like for Insert.
+// Options.MinVariadic and Options.Secrets are used like by Replace
+// to decide which wrap statements are stale, the other options are ignored.
+func CollectStats(sourcePath string, options Options) (report *StatsReport, err error) {
+	defer errs.WrapWithFuncParams(&err, sourcePath, options)
+
+	pattern := sourcePath
+	if pattern == "..." {
+		pattern = "./..."
+	}
+
+	config, verboseOut := options.Config, options.VerboseOut
+	files, err := loadSourceFiles(pattern, config.tests(), verboseOut)
+	if err != nil {
+		return nil, err
+	}
+
+	packages := make(map[string]*Stats)
+	for _, f := range files {
+		settings := config.fileSettings(f.path, f.pkgPath, f.file)
+		if settings.skip != "" {
+			if verboseOut != nil {
+				fmt.Fprintf(verboseOut, "skipping %s (%s)\n", f.path, settings.skip)
+			}
+			continue
+		}
+		if IsFileIgnored(f.file) {
+			if verboseOut != nil {
+				fmt.Fprintf(verboseOut, "%s: ignoring file with %s\n", f.fset.Position(f.file.Package), IgnoreDirective)
+			}
+			continue
+		}
+		fileSecrets := options.Secrets
+		if fileSecrets == nil {
+			fileSecrets = settings.secrets
+		}
+		if verboseOut != nil {
+			fmt.Fprintf(verboseOut, "parsing file: %s\n", f.path)
+		}
+
+		pkg := strings.TrimSuffix(f.pkgPath, "_test")
+		if pkg == "" {
+			pkg = filepath.Dir(f.path)
+		}
+		stats := packages[pkg]
+		if stats == nil {
+			stats = &Stats{Package: pkg}
+			packages[pkg] = stats
+		}
+		addFuncStats(stats, f.file, f.info, settings.skipFunctions)
+
+		// Count the changes that replace would make
+		replacements, _, err := processFile(f.fset, f.file, f.info, options.MinVariadic || settings.minVariadic, "", fileSecrets, nil, modeReplace)
+		if err != nil {
+			return nil, err
+		}
+		replacements = skipFunctions(f.file, replacements, settings.skipFunctions)
+		if len(replacements) == 0 {
+			continue
+		}
+		// #nosec G304 -- f.path is a file of the loaded packages
+		source, err := os.ReadFile(f.path)
+		if err != nil {
+			return nil, err
+		}
+		for _, repl := range replacements {
+			if repl.Node == nil || replacementChangesSource(f.fset, source, repl) {
+				stats.Stale++
+			}
+		}
+	}
+
+	report = &StatsReport{Packages: make([]*Stats, 0, len(packages))}
+	for _, stats := range packages {
+		stats.updateCoverage()
+		report.Packages = append(report.Packages, stats)
+		report.Total.add(stats)
+	}
+	report.Total.updateCoverage()
+	slices.SortFunc(report.Packages, func(a, b *Stats) int {
+		return strings.Compare(a.Package, b.Package)
+	})
+	return report, nil
+}
+
+// addFuncStats adds the counts of the functions and function literals
+// of file to stats, except for functions ignored by IgnoreDirective
+// or matching the skipFunctions patterns, see skipFunctions.
+func addFuncStats(stats *Stats, file *ast.File, info *types.Info, skipFunctions []string) {
+	refs := newErrsRefs(file, info)
+	for _, decl := range file.Decls {
+		if fd, ok := decl.(*ast.FuncDecl); ok {
+			if FuncDirectives(fd.Doc).Ignore || matchFuncName(skipFunctions, funcDeclName(fd)) {
+				continue // Also skips the function literals within
+			}
+		}
+
+		// Functions in pre-order, so that the last function
+		// containing a wrap statement is the innermost one
+		var (
+			funcs []ast.Node
+			wraps = make(map[ast.Node][]*ast.DeferStmt)
+		)
+		ast.Inspect(decl, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.FuncDecl:
+				if node.Body != nil {
+					funcs = append(funcs, node)
+				}
+			case *ast.FuncLit:
+				funcs = append(funcs, node)
+			case *ast.DeferStmt:
+				if !refs.isDeferWrap(node) {
+					return true
+				}
+				var enclosing ast.Node
+				for _, fn := range funcs {
+					if fn.Pos() <= node.Pos() && node.End() <= fn.End() {
+						enclosing = fn
+					}
+				}
+				wraps[enclosing] = append(wraps[enclosing], node)
+			}
+			return true
+		})
+
+		for _, fn := range funcs {
+			var funcType *ast.FuncType
+			switch node := fn.(type) {
+			case *ast.FuncDecl:
+				funcType = node.Type
+			case *ast.FuncLit:
+				funcType = node.Type
+			}
+			if !hasErrorResult(info, funcType) {
+				continue
+			}
+			if _, ok := fn.(*ast.FuncLit); ok {
+				stats.ErrorFuncLits++
+			} else {
+				stats.ErrorFuncs++
+				switch {
+				case len(wraps[fn]) > 0:
+					stats.Wrapped++
+				case extractFuncInfo(info, funcType, "", fn.Pos(), fn.End()).errorResultName != "":
+					stats.Missing++
+				default:
+					stats.Unnamed++
+				}
+			}
+			for _, stmt := range wraps[fn] {
+				switch name := refs.wrapFuncName(stmt); {
+				case name == "WrapWithFuncParams":
+					stats.Variadic++
+				case isSpecializedWrapFunc(name):
+					stats.Specialized++
+				}
+				stats.KeepSecretParams += len(refs.keepSecretParams(stmt))
+			}
+		}
+	}
+}
+
+// hasErrorResult reports whether funcType has a named or anonymous error result.
+func hasErrorResult(info *types.Info, funcType *ast.FuncType) bool {
+	return funcType.Results != nil && slices.ContainsFunc(funcType.Results.List, func(field *ast.Field) bool {
+		return isErrorTypeOf(info, field.Type)
+	})
+}
+
+// wrapFuncName returns the name of the go-errs function
+// called by the defer errs.Wrap statement stmt.
+func (r *errsRefs) wrapFuncName(stmt *ast.DeferStmt) string {
+	if name, ok := r.resolve(stmt.Call.Fun); ok {
+		return name
+	}
+	if sel, ok := stmt.Call.Fun.(*ast.SelectorExpr); ok {
+		return sel.Sel.Name
+	}
+	return ""
+}
+
+// isSpecializedWrapFunc reports whether name is one of the specialized
+// WrapWith0FuncParams, WrapWith1FuncParam ... WrapWith10FuncParams functions.
+func isSpecializedWrapFunc(name string) bool {
+	if name == "WrapWith1FuncParam" {
+		return true
+	}
+	num, ok := strings.CutPrefix(name, "WrapWith")
+	if !ok {
+		return false
+	}
+	num, ok = strings.CutSuffix(num, "FuncParams")
+	if !ok {
+		return false
+	}
+	n, err := strconv.Atoi(num)
+	return err == nil && n >= 0 && n != 1 && n <= 10
+}
+
+// WriteText writes the report as table to w with one line
+// per package and the total, the package in the last column.
+func (r *StatsReport) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "FUNCS\tWRAPPED\tMISSING\tUNNAMED\tFUNCLITS\tSTALE\tVARIADIC\tSPECIALIZED\tKEEPSECRET\tCOVERAGE\t  PACKAGE")
+	writeLine := func(s *Stats, name string) {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%.1f%%\t  %s\n",
+			s.ErrorFuncs, s.Wrapped, s.Missing, s.Unnamed, s.ErrorFuncLits, s.Stale,
+			s.Variadic, s.Specialized, s.KeepSecretParams, s.Coverage, name,
+		)
+	}
+	for _, s := range r.Packages {
+		writeLine(s, s.Package)
+	}
+	writeLine(&r.Total, "total")
+	return tw.Flush()
+}
+
+// WriteJSON writes the report as JSON object to w.
+func (r *StatsReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
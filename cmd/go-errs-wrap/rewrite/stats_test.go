@@ -0,0 +1,157 @@
+package rewrite
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectStats(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	code := `package test
+
+import (
+	"errors"
+
+	"github.com/domonda/go-errs"
+)
+
+func Variadic(a, b int) (err error) {
+	defer errs.WrapWithFuncParams(&err, a, b)
+
+	f := func() error { return nil }
+	return f()
+}
+
+func Specialized(token string) (n int, err error) {
+	defer errs.WrapWith1FuncParam(&err, errs.KeepSecret(token))
+
+	return 0, errors.New("x")
+}
+
+func Stale(a, b int) (err error) {
+	defer errs.WrapWith1FuncParam(&err, a)
+
+	return nil
+}
+
+func Missing(id string) (err error) {
+	return nil
+}
+
+func Marker(id string) (err error) {
+	//#wrap-result-err
+
+	return nil
+}
+
+// go-errs-wrap:ignore
+func Ignored(id string) (err error) {
+	g := func() (err error) { return nil }
+	return g()
+}
+
+func Skipped(id string) (err error) {
+	return nil
+}
+
+func NoError() int { return 1 }
+`
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "stats.go"), []byte(code), 0644))
+	subDir := filepath.Join(tmpDir, "sub")
+	require.NoError(t, os.Mkdir(subDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(subDir, "sub.go"), []byte("package sub\n\nfunc F() (err error) { return nil }\n"), 0644))
+
+	config := &Config{dir: tmpDir}
+	config.Skip.Functions = []string{"Skipped"}
+
+	report, err := CollectStats(filepath.Join(tmpDir, "..."), Options{Config: config})
+	require.NoError(t, err)
+	require.Len(t, report.Packages, 2)
+
+	assert.Equal(t, &Stats{
+		Package:          tmpDir,
+		ErrorFuncs:       5,
+		Wrapped:          3,
+		Missing:          2,
+		ErrorFuncLits:    1,
+		Stale:            2,
+		Variadic:         1,
+		Specialized:      2,
+		KeepSecretParams: 1,
+		Coverage:         60,
+	}, report.Packages[0], "Variadic, Specialized, Stale, Missing and Marker, f not part of the coverage")
+	assert.Equal(t, &Stats{Package: subDir, ErrorFuncs: 1, Missing: 1}, report.Packages[1])
+	assert.Equal(t, Stats{
+		ErrorFuncs:       6,
+		Wrapped:          3,
+		Missing:          3,
+		ErrorFuncLits:    1,
+		Stale:            2,
+		Variadic:         1,
+		Specialized:      2,
+		KeepSecretParams: 1,
+		Coverage:         50,
+	}, report.Total)
+
+	report, err = CollectStats(filepath.Join(tmpDir, "..."), Options{MinVariadic: true, Config: config})
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Total.Stale, "minVariadic replaces the variadic statement")
+
+	report, err = CollectStats(filepath.Join(tmpDir, "stats.go"), Options{Secrets: DefaultSecretPolicy()})
+	require.NoError(t, err)
+	require.Len(t, report.Packages, 1)
+	assert.Equal(t, 6, report.Total.ErrorFuncs, "Skipped without config")
+	assert.Equal(t, 2, report.Total.Stale, "token is already wrapped with errs.KeepSecret")
+}
+
+func TestCollectStatsEmpty(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "empty.go"), []byte("package test\n\nfunc F() {}\n"), 0644))
+
+	report, err := CollectStats(tmpDir, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, report.Total.Coverage, "full coverage without functions returning errors")
+}
+
+func TestIsSpecializedWrapFunc(t *testing.T) {
+	for _, name := range []string{"WrapWith0FuncParams", "WrapWith1FuncParam", "WrapWith2FuncParams", "WrapWith10FuncParams"} {
+		assert.True(t, isSpecializedWrapFunc(name), name)
+	}
+	for _, name := range []string{"WrapWithFuncParams", "WrapWith1FuncParams", "WrapWith11FuncParams", "WrapWith-1FuncParams", "Wrap", "WrapWithContext"} {
+		assert.False(t, isSpecializedWrapFunc(name), name)
+	}
+}
+
+func TestStatsReportWrite(t *testing.T) {
+	report := &StatsReport{
+		Packages: []*Stats{
+			{Package: "example.com/a", ErrorFuncs: 4, Wrapped: 3, Missing: 1, ErrorFuncLits: 2, Specialized: 3, Coverage: 75},
+			{Package: "example.com/b", ErrorFuncs: 0, Coverage: 100},
+		},
+		Total: Stats{ErrorFuncs: 4, Wrapped: 3, Missing: 1, ErrorFuncLits: 2, Specialized: 3, Coverage: 75},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteText(&buf))
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 4)
+	assert.Equal(t, "  FUNCS  WRAPPED  MISSING  UNNAMED  FUNCLITS  STALE  VARIADIC  SPECIALIZED  KEEPSECRET  COVERAGE  PACKAGE", lines[0])
+	assert.Equal(t, "      4        3        1        0         2      0         0            3           0     75.0%  example.com/a", lines[1])
+	assert.Equal(t, "      0        0        0        0         0      0         0            0           0    100.0%  example.com/b", lines[2])
+	assert.Equal(t, "      4        3        1        0         2      0         0            3           0     75.0%  total", lines[3])
+
+	buf.Reset()
+	require.NoError(t, report.WriteJSON(&buf))
+	var decoded StatsReport
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, *report, decoded)
+	assert.NotContains(t, buf.String(), `"package": ""`, "total without package")
+}
@@ -0,0 +1,193 @@
+package rewrite
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// errsPkgPath is the package path of go-errs.
+const errsPkgPath = "github.com/domonda/go-errs"
+
+// isErrsPkgPath reports whether path is the package path of go-errs,
+// including a copy of it in a vendor directory.
+func isErrsPkgPath(path string) bool {
+	return path == errsPkgPath || strings.HasSuffix(path, "/vendor/"+errsPkgPath)
+}
+
+var errorType = types.Universe.Lookup("error").Type()
+
+// secretInterface has the method set of the errs.Secret interface,
+// declared here so that it can be checked without go-errs being
+// imported by the package of the checked type.
+var secretInterface = types.NewInterfaceType(
+	[]*types.Func{
+		types.NewFunc(token.NoPos, nil, "Secret", types.NewSignatureType(nil, nil, nil, nil,
+			types.NewTuple(types.NewParam(token.NoPos, nil, "", types.Universe.Lookup("any").Type())),
+			false,
+		)),
+		types.NewFunc(token.NoPos, nil, "String", types.NewSignatureType(nil, nil, nil, nil,
+			types.NewTuple(types.NewParam(token.NoPos, nil, "", types.Typ[types.String])),
+			false,
+		)),
+	},
+	nil,
+).Complete()
+
+// typeOf returns the type of expr from info,
+// or nil if info is nil or the type is unknown
+// because the package has type errors.
+func typeOf(info *types.Info, expr ast.Expr) types.Type {
+	if info == nil {
+		return nil
+	}
+	t := info.TypeOf(expr)
+	if t == nil || t == types.Typ[types.Invalid] {
+		return nil
+	}
+	return t
+}
+
+// isErrorTypeOf checks if the type expression denotes the error type
+// using type information, so that aliases like type Error = error
+// are recognized. Falls back to isErrorType without type information.
+func isErrorTypeOf(info *types.Info, expr ast.Expr) bool {
+	if t := typeOf(info, expr); t != nil {
+		return types.Identical(t, errorType)
+	}
+	return isErrorType(expr)
+}
+
+// isSecretType reports whether values of the type expression
+// implement the errs.Secret interface.
+// Always false without type information.
+func isSecretType(info *types.Info, expr ast.Expr) bool {
+	t := typeOf(info, expr)
+	return t != nil && types.Implements(t, secretInterface)
+}
+
+// errsRefs identifies references to functions of go-errs in a file.
+// Calls are resolved with type information if available,
+// which covers dot-imports and vendored copies of go-errs,
+// else by the names used to import go-errs.
+type errsRefs struct {
+	info    *types.Info
+	aliases map[string]bool
+}
+
+func newErrsRefs(file *ast.File, info *types.Info) *errsRefs {
+	return &errsRefs{info: info, aliases: extractErrsAliases(file)}
+}
+
+// resolve returns the name of the go-errs function that fun refers to
+// using type information, or an empty name if fun refers to something else.
+// The result ok is false if fun can't be resolved because
+// there is no type information or the package has type errors.
+func (r *errsRefs) resolve(fun ast.Expr) (name string, ok bool) {
+	if r.info == nil {
+		return "", false
+	}
+	var ident *ast.Ident
+	switch x := fun.(type) {
+	case *ast.SelectorExpr:
+		ident = x.Sel
+	case *ast.Ident:
+		ident = x
+	default:
+		return "", true
+	}
+	obj := r.info.Uses[ident]
+	if obj == nil {
+		return "", false
+	}
+	if f, ok := obj.(*types.Func); ok && f.Pkg() != nil && isErrsPkgPath(f.Pkg().Path()) {
+		return f.Name(), true
+	}
+	return "", true
+}
+
+// isDeferWrap checks if a defer statement is calling an errs.Wrap* function,
+// see isDeferErrsWrap.
+func (r *errsRefs) isDeferWrap(stmt *ast.DeferStmt) bool {
+	if name, ok := r.resolve(stmt.Call.Fun); ok {
+		return strings.HasPrefix(name, "Wrap")
+	}
+	return isDeferErrsWrap(stmt, r.aliases)
+}
+
+// isVariadicWrapWithFuncParams checks if a defer statement is calling
+// the variadic errs.WrapWithFuncParams function,
+// see isVariadicWrapWithFuncParams.
+func (r *errsRefs) isVariadicWrapWithFuncParams(stmt *ast.DeferStmt) bool {
+	if name, ok := r.resolve(stmt.Call.Fun); ok {
+		return name == "WrapWithFuncParams"
+	}
+	return isVariadicWrapWithFuncParams(stmt, r.aliases)
+}
+
+// keepSecretParams returns the names of the parameters passed
+// wrapped with errs.KeepSecret to the defer statement,
+// see extractKeepSecretParams.
+func (r *errsRefs) keepSecretParams(stmt *ast.DeferStmt) map[string]bool {
+	result := extractKeepSecretParams(stmt, r.aliases)
+	if r.info == nil {
+		return result
+	}
+	for i, arg := range stmt.Call.Args {
+		call, ok := arg.(*ast.CallExpr)
+		if i == 0 || !ok || len(call.Args) != 1 {
+			continue
+		}
+		paramIdent, ok := call.Args[0].(*ast.Ident)
+		if !ok {
+			continue
+		}
+		if name, ok := r.resolve(call.Fun); ok {
+			if name == "KeepSecret" {
+				result[paramIdent.Name] = true
+			} else {
+				delete(result, paramIdent.Name)
+			}
+		}
+	}
+	return result
+}
+
+// qualifier returns the prefix for references to go-errs
+// in generated code: "errs." if go-errs is not imported
+// or imported with its package name, the import name
+// followed by a dot for named imports,
+// and an empty string for a dot-import.
+func qualifier(file *ast.File) string {
+	for _, imp := range file.Imports {
+		if imp.Path.Value != errsImportPath || imp.Name == nil || imp.Name.Name == "_" {
+			continue
+		}
+		if imp.Name.Name == "." {
+			return ""
+		}
+		return imp.Name.Name + "."
+	}
+	return "errs."
+}
+
+// importsErrs reports whether the file imports go-errs
+// so that it can be referenced by generated code.
+func importsErrs(file *ast.File) bool {
+	for _, imp := range file.Imports {
+		if imp.Path.Value == errsImportPath && (imp.Name == nil || imp.Name.Name != "_") {
+			return true
+		}
+	}
+	return false
+}
+
+// qualify replaces the "errs." references of the generated
+// statement with the qualifier of the file.
+func qualify(stmt, qualifier string) string {
+	if qualifier == "errs." {
+		return stmt
+	}
+	return strings.ReplaceAll(stmt, "errs.", qualifier)
+}
@@ -1,10 +1,28 @@
 package errs
 
 import (
+	"slices"
+	"strings"
+	"sync/atomic"
+
 	"github.com/domonda/go-pretty"
 )
 
+const (
+	defaultMaxCallStackFrames    = 32
+	defaultMaxInternedCallStacks = 4096
+	defaultFrameCacheCapacity    = 4096
+	defaultFormatParamMaxLen     = 5000
+)
+
 // Configuration variables
+//
+// The variables are not synchronized and should only be changed
+// at program start before errors are created.
+// All of them are ignored once a Config was installed with SetDefaultConfig,
+// which is safe for concurrent use and should be preferred.
+// Use the ConfigOption with the name of a variable
+// to change its setting of a Config.
 var (
 	// TrimFilePathPrefix will be trimmed from the
 	// beginning of every call-stack file-path.
@@ -21,29 +39,61 @@ var (
 	TrimFilePathPrefix = ""
 
 	// MaxCallStackFrames is the maximum number of frames to include in the call stack.
-	MaxCallStackFrames = 32
+	MaxCallStackFrames = defaultMaxCallStackFrames
+
+	// InternCallStacks enables sharing identical captured call stacks
+	// between errors instead of allocating a slice per error.
+	//
+	// Captured call stacks are looked up by a hash of their program counters
+	// and errors created at the same call site with the same callers
+	// reference one shared immutable copy. This reduces memory for
+	// error-heavy code paths that keep many errors around,
+	// like batch validation, and avoids allocating a slice
+	// of MaxCallStackFrames for every captured call stack.
+	//
+	// At most MaxInternedCallStacks distinct call stacks are interned,
+	// call stacks captured after that limit is reached are not shared.
+	//
+	// Default: false
+	InternCallStacks = false
+
+	// MaxInternedCallStacks is the maximum number of distinct
+	// call stacks shared when InternCallStacks is enabled.
+	//
+	// Default: 4096
+	MaxInternedCallStacks = defaultMaxInternedCallStacks
+
+	// FrameCacheCapacity is the maximum number of program counters
+	// kept in the global cache of resolved call stack frames
+	// used to format errors and by ResolveCallStack.
+	// When the cache is full, arbitrary entries are evicted.
+	// Use ReadFrameCacheStats to check if the capacity fits
+	// the number of distinct call sites of a program.
+	// A value of zero or less disables caching.
+	//
+	// Default: 4096
+	FrameCacheCapacity = defaultFrameCacheCapacity
 
 	// Printer is the pretty.Printer used to format function parameters
-	// in error call stacks. It can be configured to customize formatting,
-	// mask secrets, or adapt types that don't implement pretty.Printable.
+	// in error call stacks. It can be configured to customize formatting
+	// or adapt types that don't implement pretty.Printable.
 	//
-	// Example - Masking sensitive data:
+	// Use the Redaction policy instead of a custom PrintFuncFor hook
+	// to mask sensitive struct fields and types.
+	//
+	// Example - Adapting fmt.Stringer types:
 	//
 	//	func init() {
 	//	    errs.Printer = errs.Printer.WithPrintFuncFor(func(v reflect.Value) pretty.PrintFunc {
-	//	        if v.Kind() == reflect.String && strings.Contains(v.String(), "secret") {
+	//	        if s, ok := v.Interface().(fmt.Stringer); ok {
 	//	            return func(w io.Writer) (int, error) {
-	//	                return fmt.Fprint(w, "`***REDACTED***`")
+	//	                return io.WriteString(w, s.String())
 	//	            }
 	//	        }
 	//	        return pretty.PrintFuncForPrintable(v) // Use default
 	//	    })
 	//	}
-	Printer = &pretty.Printer{
-		MaxStringLength: pretty.DefaultPrinter.MaxStringLength,
-		MaxErrorLength:  pretty.DefaultPrinter.MaxErrorLength,
-		MaxSliceLength:  pretty.DefaultPrinter.MaxSliceLength,
-	}
+	Printer = newPrinter()
 
 	// FormatParamMaxLen is the maximum length in bytes for a single formatted
 	// parameter value in error call stacks. When a parameter's formatted
@@ -65,5 +115,468 @@ var (
 	//	    // ProcessData("first 100 bytes of data…(TRUNCATED)")
 	//	    return validateData(data)
 	//	}
-	FormatParamMaxLen = 5000
+	FormatParamMaxLen = defaultFormatParamMaxLen
+
+	// SnapshotFuncParams enables formatting function parameters
+	// at the time an error is wrapped by WrapWithFuncParams
+	// and its variants instead of when Error() is called.
+	//
+	// By default the parameter values are kept and formatted lazily,
+	// which is cheaper for errors that are never rendered, but means that
+	// pointer parameters show their state at the time the error is rendered
+	// instead of the time of the failure, and that all parameter values
+	// are kept in memory as long as the error is referenced.
+	//
+	// With SnapshotFuncParams enabled the parameters are formatted
+	// using Printer, Redaction and FormatParamMaxLen (secrets stay redacted)
+	// and only the formatted strings are kept.
+	// Later changes of these variables don't affect already wrapped errors.
+	//
+	// Default: false
+	SnapshotFuncParams = false
+
+	// Redaction is the RedactionPolicy applied by FormatFunctionCall
+	// on top of Printer to redact function parameters.
+	//
+	// The default policy redacts struct fields tagged with `errs:"secret"`
+	// and struct fields whose names match DefaultRedactionFieldNames
+	// at any nesting depth. Set it to nil to disable policy based redaction,
+	// values wrapped with KeepSecret are redacted in any case.
+	//
+	// Like all configuration variables Redaction is not synchronized
+	// and must only be changed at program start.
+	// To change the policy while errors are formatted concurrently
+	// install a Config with WithRedaction using SetDefaultConfig.
+	//
+	// Example - Redacting additional field names and types:
+	//
+	//	func init() {
+	//	    errs.Redaction = errs.Redaction.
+	//	        WithFieldNames("pin", "cvv").
+	//	        WithTypes(reflect.TypeFor[*rsa.PrivateKey]())
+	//	}
+	//
+	// Example - Changing the policy at runtime:
+	//
+	//	errs.SetDefaultConfig(errs.DefaultConfig().With(
+	//	    errs.WithRedaction(errs.DefaultConfig().Redaction().WithFieldNames("pin")),
+	//	))
+	Redaction = newDefaultRedactionPolicy()
+
+	// MessageScrubbers are applied to the complete text rendered
+	// by the Error method of errors wrapped by this package
+	// and by LogFunctionCall, to remove secrets that are part of
+	// error messages instead of function parameters.
+	//
+	// The default scrubbers redact URL credentials, DSN passwords,
+	// JSON Web Tokens and Authorization headers.
+	// Set it to nil to disable scrubbing.
+	//
+	// Example - Adding a custom rule:
+	//
+	//	func init() {
+	//	    errs.MessageScrubbers = append(errs.MessageScrubbers, &errs.RegexpScrubber{
+	//	        Regexp:      regexp.MustCompile(`sk_live_[0-9a-zA-Z]+`),
+	//	        Replacement: "sk_live_***REDACTED***",
+	//	    })
+	//	}
+	MessageScrubbers = slices.Clone(DefaultScrubbers)
+
+	// ErrorOutputFormat is the layout of the text returned
+	// by the Error method of errors wrapped by this package.
+	// Can be set with the GOERRS_FORMAT environment variable.
+	//
+	// Default: OutputText
+	ErrorOutputFormat = OutputText
+
+	// FrameFilter filters the function calls shown in formatted errors.
+	// Calls of wrappers for which it returns false are omitted,
+	// nil shows all calls.
+	// Can be set with the GOERRS_FRAME_FILTER environment variable.
+	//
+	// Example - Hide calls of a middleware package:
+	//
+	//	errs.FrameFilter = errs.ExcludeFunctionPrefixes("github.com/my/middleware.")
+	//
+	// Default: nil
+	FrameFilter func(Frame) bool
 )
+
+func newPrinter() *pretty.Printer {
+	return &pretty.Printer{
+		MaxStringLength: pretty.DefaultPrinter.MaxStringLength,
+		MaxErrorLength:  pretty.DefaultPrinter.MaxErrorLength,
+		MaxSliceLength:  pretty.DefaultPrinter.MaxSliceLength,
+	}
+}
+
+func newDefaultRedactionPolicy() *RedactionPolicy {
+	return NewRedactionPolicy().WithFieldNames(DefaultRedactionFieldNames...)
+}
+
+// Config is an immutable configuration for formatting errors
+// and capturing call stacks as an alternative
+// to the unsynchronized configuration variables.
+//
+// A Config can be installed as process wide default with SetDefaultConfig
+// or used to format errors with its own settings via its methods,
+// for example by a library that must not depend on
+// or change the configuration of the program using it.
+//
+// Use NewConfig or the With method of an existing Config
+// to create a Config with changed settings.
+type Config struct {
+	trimFilePathPrefix string
+	maxCallStackFrames int
+	printer            *pretty.Printer
+	formatParamMaxLen  int
+	formatFunctionCall func(function string, params ...any) string
+	redaction          *RedactionPolicy
+	messageScrubbers   []Scrubber
+	outputFormat       OutputFormat
+	frameFilter        func(Frame) bool
+
+	internCallStacks      bool
+	maxInternedCallStacks int
+	frameCacheCapacity    int
+	snapshotFuncParams    bool
+
+	// formatFunctionCallVar is true for the Config of DefaultConfig
+	// formatting function calls with the FormatFunctionCall variable
+	formatFunctionCallVar bool
+
+	// frameFilterVar is true for the Config of DefaultConfig
+	// filtering frames with the FrameFilter variable
+	frameFilterVar bool
+}
+
+// ConfigOption changes a setting of a Config
+// created by NewConfig or Config.With.
+type ConfigOption func(*Config)
+
+// NewConfig returns a Config with the default settings
+// of the configuration variables changed by the passed options.
+func NewConfig(options ...ConfigOption) *Config {
+	c := &Config{
+		maxCallStackFrames: defaultMaxCallStackFrames,
+		printer:            newPrinter(),
+		formatParamMaxLen:  defaultFormatParamMaxLen,
+		redaction:          newDefaultRedactionPolicy(),
+		messageScrubbers:   slices.Clone(DefaultScrubbers),
+
+		maxInternedCallStacks: defaultMaxInternedCallStacks,
+		frameCacheCapacity:    defaultFrameCacheCapacity,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// With returns a copy of the Config with the options applied.
+//
+// A copy of the Config returned by DefaultConfig without installed Config
+// formats function calls with the FormatFunctionCall variable
+// unless the options change the printer, redaction or
+// parameter length used to format them.
+func (c *Config) With(options ...ConfigOption) *Config {
+	clone := *c
+	if clone.frameFilterVar {
+		clone.frameFilter = FrameFilter
+		clone.frameFilterVar = false
+	}
+	for _, option := range options {
+		option(&clone)
+	}
+	if clone.printer != c.printer || clone.redaction != c.redaction || clone.formatParamMaxLen != c.formatParamMaxLen {
+		clone.formatFunctionCallVar = false
+	}
+	return &clone
+}
+
+// WithTrimFilePathPrefix sets the prefix trimmed from call-stack file-paths,
+// see the TrimFilePathPrefix variable.
+func WithTrimFilePathPrefix(prefix string) ConfigOption {
+	return func(c *Config) { c.trimFilePathPrefix = prefix }
+}
+
+// WithMaxCallStackFrames sets the maximum number of captured
+// call stack frames, see the MaxCallStackFrames variable.
+func WithMaxCallStackFrames(n int) ConfigOption {
+	return func(c *Config) { c.maxCallStackFrames = n }
+}
+
+// WithPrinter sets the pretty.Printer used to format function parameters,
+// see the Printer variable.
+// The printer must not be modified after it was passed.
+func WithPrinter(printer *pretty.Printer) ConfigOption {
+	return func(c *Config) { c.printer = printer }
+}
+
+// WithFormatParamMaxLen sets the maximum length in bytes
+// of a formatted function parameter, see the FormatParamMaxLen variable.
+func WithFormatParamMaxLen(n int) ConfigOption {
+	return func(c *Config) { c.formatParamMaxLen = n }
+}
+
+// WithFormatFunctionCall sets a custom function to format function calls,
+// see the FormatFunctionCall variable.
+// Pass nil to use the default implementation
+// with the Printer, Redaction and FormatParamMaxLen of the Config.
+func WithFormatFunctionCall(format func(function string, params ...any) string) ConfigOption {
+	return func(c *Config) { c.formatFunctionCall = format }
+}
+
+// WithRedaction sets the RedactionPolicy applied to function parameters,
+// see the Redaction variable.
+func WithRedaction(policy *RedactionPolicy) ConfigOption {
+	return func(c *Config) { c.redaction = policy }
+}
+
+// WithMessageScrubbers sets the scrubbers applied to formatted errors,
+// see the MessageScrubbers variable.
+func WithMessageScrubbers(scrubbers ...Scrubber) ConfigOption {
+	return func(c *Config) { c.messageScrubbers = slices.Clone(scrubbers) }
+}
+
+// WithOutputFormat sets the layout of formatted errors,
+// see the ErrorOutputFormat variable.
+func WithOutputFormat(format OutputFormat) ConfigOption {
+	return func(c *Config) { c.outputFormat = format }
+}
+
+// WithFrameFilter sets a filter for the function calls shown in formatted errors,
+// see the FrameFilter variable.
+// Calls of wrappers for which filter returns false are omitted,
+// pass nil to show all calls.
+//
+// Example:
+//
+//	errs.WithFrameFilter(errs.ExcludeFunctionPrefixes("github.com/my/middleware."))
+func WithFrameFilter(filter func(Frame) bool) ConfigOption {
+	return func(c *Config) { c.frameFilter = filter }
+}
+
+// WithInternCallStacks enables sharing identical captured call stacks,
+// see the InternCallStacks variable.
+// Call stacks are only captured with the DefaultConfig.
+func WithInternCallStacks(intern bool) ConfigOption {
+	return func(c *Config) { c.internCallStacks = intern }
+}
+
+// WithMaxInternedCallStacks sets the maximum number of distinct
+// interned call stacks, see the MaxInternedCallStacks variable.
+func WithMaxInternedCallStacks(n int) ConfigOption {
+	return func(c *Config) { c.maxInternedCallStacks = n }
+}
+
+// WithFrameCacheCapacity sets the maximum number of program counters
+// kept in the process wide cache of resolved call stack frames,
+// see the FrameCacheCapacity variable.
+// Only the capacity of the DefaultConfig is used.
+func WithFrameCacheCapacity(n int) ConfigOption {
+	return func(c *Config) { c.frameCacheCapacity = n }
+}
+
+// WithSnapshotFuncParams enables formatting function parameters
+// when an error is wrapped, see the SnapshotFuncParams variable.
+// Errors are only wrapped with the DefaultConfig.
+func WithSnapshotFuncParams(snapshot bool) ConfigOption {
+	return func(c *Config) { c.snapshotFuncParams = snapshot }
+}
+
+// ExcludeFunctionPrefixes returns a frame filter for WithFrameFilter
+// that excludes frames of functions starting with any of the prefixes.
+func ExcludeFunctionPrefixes(prefixes ...string) func(Frame) bool {
+	prefixes = slices.Clone(prefixes)
+	return func(frame Frame) bool {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(frame.Function, prefix) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// TrimFilePathPrefix returns the prefix trimmed from call-stack file-paths.
+func (c *Config) TrimFilePathPrefix() string { return c.trimFilePathPrefix }
+
+// MaxCallStackFrames returns the maximum number of captured call stack frames.
+func (c *Config) MaxCallStackFrames() int { return c.maxCallStackFrames }
+
+// Printer returns the pretty.Printer used to format function parameters.
+func (c *Config) Printer() *pretty.Printer { return c.printer }
+
+// FormatParamMaxLen returns the maximum length in bytes of a formatted function parameter.
+func (c *Config) FormatParamMaxLen() int { return c.formatParamMaxLen }
+
+// Redaction returns the RedactionPolicy applied to function parameters.
+func (c *Config) Redaction() *RedactionPolicy { return c.redaction }
+
+// MessageScrubbers returns a copy of the scrubbers applied to formatted errors.
+func (c *Config) MessageScrubbers() []Scrubber { return slices.Clone(c.messageScrubbers) }
+
+// OutputFormat returns the layout of formatted errors.
+func (c *Config) OutputFormat() OutputFormat { return c.outputFormat }
+
+// InternCallStacks returns if identical captured call stacks are shared.
+func (c *Config) InternCallStacks() bool { return c.internCallStacks }
+
+// MaxInternedCallStacks returns the maximum number of distinct interned call stacks.
+func (c *Config) MaxInternedCallStacks() int { return c.maxInternedCallStacks }
+
+// FrameCacheCapacity returns the maximum number of program counters
+// kept in the cache of resolved call stack frames.
+func (c *Config) FrameCacheCapacity() int { return c.frameCacheCapacity }
+
+// SnapshotFuncParams returns if function parameters
+// are formatted when an error is wrapped.
+func (c *Config) SnapshotFuncParams() bool { return c.snapshotFuncParams }
+
+// FormatError formats err with its call stack and function parameters
+// like the Error method of errors wrapped by this package,
+// but using the settings of the Config instead of the default configuration
+// and without caching the result.
+// Returns an empty string for a nil error.
+func (c *Config) FormatError(err error) string {
+	if err == nil {
+		return ""
+	}
+	return c.formatError(err)
+}
+
+// FormatFunctionCall formats a function call with parameters
+// like the FormatFunctionCall variable but using the settings of the Config.
+func (c *Config) FormatFunctionCall(function string, params ...any) string {
+	switch {
+	case c.formatFunctionCall != nil:
+		return c.formatFunctionCall(function, params...)
+	case c.formatFunctionCallVar && FormatFunctionCall != nil:
+		return FormatFunctionCall(function, params...)
+	}
+	return formatFunctionCall(c.redaction.Printer(c.printer), c.formatParamMaxLen, function, params)
+}
+
+// ScrubMessage applies the message scrubbers of the Config to s.
+func (c *Config) ScrubMessage(s string) string {
+	return scrub(s, c.messageScrubbers)
+}
+
+var defaultConfig atomic.Pointer[Config]
+
+// SetDefaultConfig installs config as default configuration
+// used by all errors of this package created or formatted after the call
+// instead of the configuration variables, whose changes
+// are ignored while a Config is installed.
+// It is safe to call concurrently.
+//
+// Passing nil uninstalls a previously set Config
+// so that the configuration variables are used again.
+//
+// Example:
+//
+//	errs.SetDefaultConfig(errs.DefaultConfig().With(
+//	    errs.WithMaxCallStackFrames(64),
+//	    errs.WithFormatParamMaxLen(1000),
+//	))
+func SetDefaultConfig(config *Config) {
+	defaultConfig.Store(config)
+}
+
+// variablesConfig caches the Config of the configuration variables
+// returned by DefaultConfig while no Config is installed.
+var variablesConfig atomic.Pointer[Config]
+
+// DefaultConfig returns the Config installed with SetDefaultConfig
+// or a Config with the current values of the configuration variables.
+// The Config of the variables is cached until one of them is changed.
+func DefaultConfig() *Config {
+	if c := defaultConfig.Load(); c != nil {
+		return c
+	}
+	if c := variablesConfig.Load(); c != nil && c.hasVariables() {
+		return c
+	}
+	c := &Config{
+		trimFilePathPrefix: TrimFilePathPrefix,
+		maxCallStackFrames: MaxCallStackFrames,
+		printer:            Printer,
+		formatParamMaxLen:  FormatParamMaxLen,
+		redaction:          Redaction,
+		messageScrubbers:   MessageScrubbers,
+		outputFormat:       ErrorOutputFormat,
+
+		internCallStacks:      InternCallStacks,
+		maxInternedCallStacks: MaxInternedCallStacks,
+		frameCacheCapacity:    FrameCacheCapacity,
+		snapshotFuncParams:    SnapshotFuncParams,
+
+		formatFunctionCallVar: true,
+		frameFilterVar:        true,
+	}
+	variablesConfig.Store(c)
+	return c
+}
+
+// hasVariables reports whether the Config cached by DefaultConfig
+// has the current values of the configuration variables.
+// The function variables FormatFunctionCall and FrameFilter
+// can't be compared and are used directly instead.
+// The MessageScrubbers slice is compared by identity
+// so that changed elements are used too.
+func (c *Config) hasVariables() bool {
+	return c.trimFilePathPrefix == TrimFilePathPrefix &&
+		c.maxCallStackFrames == MaxCallStackFrames &&
+		c.printer == Printer &&
+		c.formatParamMaxLen == FormatParamMaxLen &&
+		c.redaction == Redaction &&
+		sameSlice(c.messageScrubbers, MessageScrubbers) &&
+		c.outputFormat == ErrorOutputFormat &&
+		c.internCallStacks == InternCallStacks &&
+		c.maxInternedCallStacks == MaxInternedCallStacks &&
+		c.frameCacheCapacity == FrameCacheCapacity &&
+		c.snapshotFuncParams == SnapshotFuncParams
+}
+
+// sameSlice reports whether a and b are
+// the same elements of the same array.
+func sameSlice[T any](a, b []T) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
+
+// currentTrimFilePathPrefix returns the TrimFilePathPrefix
+// of the DefaultConfig without allocating.
+func currentTrimFilePathPrefix() string {
+	if c := defaultConfig.Load(); c != nil {
+		return c.trimFilePathPrefix
+	}
+	return TrimFilePathPrefix
+}
+
+// currentMaxCallStackFrames returns the MaxCallStackFrames
+// of the DefaultConfig without allocating.
+func currentMaxCallStackFrames() int {
+	if c := defaultConfig.Load(); c != nil {
+		return c.maxCallStackFrames
+	}
+	return MaxCallStackFrames
+}
+
+// currentInternCallStacks returns the InternCallStacks
+// of the DefaultConfig without allocating.
+func currentInternCallStacks() bool {
+	if c := defaultConfig.Load(); c != nil {
+		return c.internCallStacks
+	}
+	return InternCallStacks
+}
+
+// currentSnapshotFuncParams returns the SnapshotFuncParams
+// of the DefaultConfig without allocating.
+func currentSnapshotFuncParams() bool {
+	if c := defaultConfig.Load(); c != nil {
+		return c.snapshotFuncParams
+	}
+	return SnapshotFuncParams
+}
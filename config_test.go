@@ -1,14 +1,217 @@
 package errs
 
 import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestTrimFilePathPrefix_DefaultEmpty(t *testing.T) {
 	// Empty by default: call-stack file-paths are shown in the
-	// checkout-independent import-path form (see callStackFilePath), so the
+	// checkout-independent import-path form (see cachedFrame.frame), so the
 	// output no longer depends on where the module is checked out.
 	require.Equal(t, "", TrimFilePathPrefix, "TrimFilePathPrefix default")
 }
+
+func configTestFunc(s string, password string) (err error) {
+	defer WrapWith2FuncParams(&err, s, password)
+
+	return New("config test")
+}
+
+func TestNewConfig_Defaults(t *testing.T) {
+	config := NewConfig()
+	assert.Equal(t, "", config.TrimFilePathPrefix())
+	assert.Equal(t, MaxCallStackFrames, config.MaxCallStackFrames())
+	assert.Equal(t, FormatParamMaxLen, config.FormatParamMaxLen())
+	assert.Equal(t, *Printer, *config.Printer())
+	assert.NotSame(t, Printer, config.Printer())
+	assert.Len(t, config.MessageScrubbers(), len(DefaultScrubbers))
+	assert.NotSame(t, &DefaultScrubbers[0], &config.messageScrubbers[0], "not aliasing DefaultScrubbers")
+	assert.True(t, config.Redaction().RedactsField(reflect.StructField{Name: "Password"}))
+
+	err := configTestFunc("a", "b")
+	assert.Equal(t, err.Error(), config.FormatError(err))
+	assert.Equal(t, "", config.FormatError(nil))
+}
+
+func TestConfig_With(t *testing.T) {
+	config := NewConfig()
+	changed := config.With(
+		WithFormatParamMaxLen(3),
+		WithRedaction(nil),
+		WithMessageScrubbers(),
+	)
+	assert.Equal(t, defaultFormatParamMaxLen, config.FormatParamMaxLen(), "original unchanged")
+	assert.Equal(t, 3, changed.FormatParamMaxLen())
+	assert.Nil(t, changed.Redaction())
+	assert.Empty(t, changed.MessageScrubbers())
+
+	assert.Equal(t, "f(`ab…(TRUNCATED), `ab…(TRUNCATED))", changed.FormatFunctionCall("f", "abcd", "abcde"))
+	assert.Equal(t, "f(`abcd`)", config.FormatFunctionCall("f", "abcd"))
+
+	custom := config.With(WithFormatFunctionCall(func(function string, params ...any) string {
+		return function + "!"
+	}))
+	assert.Equal(t, "f!", custom.FormatFunctionCall("f", 1))
+}
+
+func TestConfig_FormatError(t *testing.T) {
+	skipWithoutCallStacks(t)
+	err := configTestFunc("abcdef", "secret")
+	want := err.Error()
+
+	config := NewConfig(
+		WithFormatParamMaxLen(2),
+		WithFormatFunctionCall(nil),
+		WithMessageScrubbers(ScrubberFunc(func(s string) string {
+			return strings.ReplaceAll(s, "config test", "scrubbed")
+		})),
+	)
+	formatted := config.FormatError(err)
+	assert.Contains(t, formatted, "scrubbed\n")
+	assert.Contains(t, formatted, "configTestFunc(`a…(TRUNCATED), `s…(TRUNCATED))")
+	assert.Equal(t, want, err.Error(), "default configuration unchanged")
+
+	// The file-path prefix of the Config is used
+	frame, ok := firstFrame(err.(*withCallStackFuncParams).CallStack(), "")
+	require.True(t, ok)
+	raw, _ := runtime.CallersFrames([]uintptr{frame.PC}).Next()
+	config = NewConfig(WithTrimFilePathPrefix(filepath.Dir(raw.File) + "/"))
+	assert.Contains(t, config.FormatError(err), "\n    config_test.go:")
+}
+
+func TestSetDefaultConfig(t *testing.T) {
+	skipWithoutCallStacks(t)
+	t.Cleanup(func() { SetDefaultConfig(nil) })
+
+	// Without installed Config the variables are used
+	defer func(maxLen int) { FormatParamMaxLen = maxLen }(FormatParamMaxLen)
+	FormatParamMaxLen = 1
+	assert.Equal(t, 1, DefaultConfig().FormatParamMaxLen())
+	assert.Equal(t, "f(`…(TRUNCATED))", FormatFunctionCall("f", "abc"))
+
+	SetDefaultConfig(NewConfig(
+		WithFormatParamMaxLen(2),
+		WithMaxCallStackFrames(1),
+	))
+	assert.Equal(t, 2, DefaultConfig().FormatParamMaxLen())
+	assert.Equal(t, "f(`a…(TRUNCATED))", FormatFunctionCall("f", "abc"))
+	assert.Len(t, New("x").(*withCallStack).CallStack(), 1)
+	assert.Contains(t, configTestFunc("abc", "").Error(), "configTestFunc(`a…(TRUNCATED), ``)")
+
+	SetDefaultConfig(nil)
+	assert.Equal(t, 1, DefaultConfig().FormatParamMaxLen())
+	assert.Greater(t, len(New("x").(*withCallStack).CallStack()), 1)
+}
+
+func TestDefaultConfig_FormatFunctionCallVariable(t *testing.T) {
+	skipWithoutCallStacks(t)
+	defer func(f func(string, ...any) string) { FormatFunctionCall = f }(FormatFunctionCall)
+	FormatFunctionCall = func(function string, params ...any) string {
+		return "custom " + function
+	}
+
+	assert.Equal(t, "custom f", DefaultConfig().FormatFunctionCall("f"))
+	assert.Contains(t, configTestFunc("a", "b").Error(), "custom github.com/domonda/go-errs.configTestFunc\n")
+	assert.Equal(t, "custom f", DefaultConfig().With(WithMaxCallStackFrames(1)).FormatFunctionCall("f"))
+	assert.Equal(t, "f(`…(TRUNCATED))", DefaultConfig().With(WithFormatParamMaxLen(1)).FormatFunctionCall("f", "abc"), "own formatting settings")
+}
+
+func TestFormatFunctionCall_Decorator(t *testing.T) {
+	skipWithoutCallStacks(t)
+	defer func(f func(string, ...any) string) { FormatFunctionCall = f }(FormatFunctionCall)
+	orig := FormatFunctionCall
+	FormatFunctionCall = func(function string, params ...any) string {
+		return "[" + orig(function, params...) + "]"
+	}
+
+	assert.Equal(t, "[f(`a`)]", FormatFunctionCall("f", "a"))
+	assert.Contains(t, configTestFunc("a", "b").Error(), "[github.com/domonda/go-errs.configTestFunc(`a`, `b`)]\n")
+
+	t.Cleanup(func() { SetDefaultConfig(nil) })
+	SetDefaultConfig(DefaultConfig().With(WithMaxCallStackFrames(10)))
+	assert.Contains(t, configTestFunc("a", "b").Error(), "[github.com/domonda/go-errs.configTestFunc(`a`, `b`)]\n", "installed copy of DefaultConfig")
+}
+
+func TestDefaultConfig_Cached(t *testing.T) {
+	config := DefaultConfig()
+	assert.Same(t, config, DefaultConfig(), "cached while the variables are unchanged")
+	assert.Zero(t, testing.AllocsPerRun(10, func() { DefaultConfig() }))
+
+	defer func(maxLen int) { FormatParamMaxLen = maxLen }(FormatParamMaxLen)
+	FormatParamMaxLen = 1
+	changed := DefaultConfig()
+	assert.NotSame(t, config, changed)
+	assert.Equal(t, 1, changed.FormatParamMaxLen())
+
+	defer func(s []Scrubber) { MessageScrubbers = s }(MessageScrubbers)
+	MessageScrubbers = slices.Clone(MessageScrubbers)
+	assert.NotSame(t, changed, DefaultConfig(), "scrubbers compared by identity")
+}
+
+func TestDefaultConfig_FrameFilterVariable(t *testing.T) {
+	defer func(f func(Frame) bool) { FrameFilter = f }(FrameFilter)
+	config := DefaultConfig()
+	copied := config.With()
+
+	FrameFilter = func(Frame) bool { return false }
+	assert.False(t, config.showFrame(Frame{}, true), "variable used by DefaultConfig")
+	assert.True(t, copied.showFrame(Frame{}, true), "variable copied by With")
+}
+
+func TestConfig_CaptureSettings(t *testing.T) {
+	skipWithoutCallStacks(t)
+	config := NewConfig()
+	assert.False(t, config.InternCallStacks())
+	assert.Equal(t, MaxInternedCallStacks, config.MaxInternedCallStacks())
+	assert.Equal(t, FrameCacheCapacity, config.FrameCacheCapacity())
+	assert.False(t, config.SnapshotFuncParams())
+
+	t.Cleanup(func() {
+		SetDefaultConfig(nil)
+		internedCallStacks.reset()
+	})
+	internedCallStacks.reset()
+	SetDefaultConfig(config.With(
+		WithInternCallStacks(true),
+		WithMaxInternedCallStacks(10),
+		WithSnapshotFuncParams(true),
+	))
+	var stacks [][]uintptr
+	for range 2 {
+		stacks = append(stacks, internTestError().(*withCallStack).callStack)
+	}
+	assert.Same(t, &stacks[0][0], &stacks[1][0], "interned")
+
+	param := []string{"before"}
+	err := errors.New("snapshot")
+	WrapWithFuncParams(&err, param)
+	param[0] = "after"
+	assert.Contains(t, err.Error(), "before")
+}
+
+func TestConfig_Concurrent(t *testing.T) {
+	err := configTestFunc("abcdef", "b")
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			config := NewConfig(WithFormatParamMaxLen(i%4 + 1))
+			for range 10 {
+				assert.Contains(t, config.FormatError(err), "TRUNCATED")
+			}
+		}()
+	}
+	wg.Wait()
+}
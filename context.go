@@ -3,10 +3,14 @@ package errs
 import (
 	"context"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
 )
 
 // IsContextCanceled checks if the context Done channel is closed
 // and if the context error unwraps to context.Canceled.
+// Use IsContextCause to check the cause of the cancellation.
 func IsContextCanceled(ctx context.Context) bool {
 	select {
 	case <-ctx.Done():
@@ -18,6 +22,7 @@ func IsContextCanceled(ctx context.Context) bool {
 
 // IsContextDeadlineExceeded checks if the context Done channel is closed
 // and if the context error unwraps to context.DeadlineExceeded.
+// Use IsContextCause to check a cause set by context.WithDeadlineCause.
 func IsContextDeadlineExceeded(ctx context.Context) bool {
 	select {
 	case <-ctx.Done():
@@ -38,8 +43,153 @@ func IsContextDone(ctx context.Context) bool {
 }
 
 // IsContextError returns true if err unwraps to
-// context.Canceled or context.DeadlineExceeded.
+// context.Canceled or context.DeadlineExceeded,
+// which includes the errors returned by ContextErr
+// and the causes set by WithCancelCause.
 func IsContextError(err error) bool {
 	return err != nil &&
 		(errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded))
 }
+
+// ContextCause returns context.Cause(ctx) if the context Done channel
+// is closed or nil if the context is not done.
+//
+// The cause is the error passed to the cancel function of
+// context.WithCancelCause, WithCancelCause, or set by
+// context.WithDeadlineCause and context.WithTimeoutCause.
+// Without such a cause it is the same as ctx.Err().
+func ContextCause(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	default:
+		return nil
+	}
+}
+
+// IsContextCause checks if the context Done channel is closed
+// and if the cause of the context unwraps to target.
+// Use it to classify why a context was canceled:
+//
+//	if errs.IsContextCause(ctx, ErrShutdown) {
+//	    // ...
+//	}
+func IsContextCause(ctx context.Context, target error) bool {
+	cause := ContextCause(ctx)
+	return cause != nil && errors.Is(cause, target)
+}
+
+// ContextErr returns nil if the context Done channel is not closed,
+// or else ctx.Err() combined with the cause of the context.
+//
+// If the cause is the same as ctx.Err() or already unwraps to it,
+// like the causes of WithCancelCause, then the cause is returned.
+// Otherwise the returned error shows the message of ctx.Err()
+// followed by the message of the cause and unwraps
+// to both errors, so IsContextError is true for it
+// and errors.Is finds the cause.
+func ContextErr(ctx context.Context) error {
+	err := ctx.Err()
+	if err == nil {
+		return nil
+	}
+	cause := context.Cause(ctx)
+	if cause == nil || errors.Is(cause, err) {
+		return cause
+	}
+	return contextCauseError{err: err, cause: cause}
+}
+
+// contextCauseError combines a context error with its cause.
+type contextCauseError struct {
+	err   error
+	cause error
+}
+
+func (e contextCauseError) Error() string {
+	return e.err.Error() + ": " + e.cause.Error()
+}
+
+// Unwrap returns both errors for errors.Is and errors.As.
+// errors.Unwrap returns nil so that the call stacks
+// of the cause are not formatted again by wrapping errors.
+func (e contextCauseError) Unwrap() []error {
+	return []error{e.err, e.cause}
+}
+
+// WithCancelCause is like context.WithCancelCause but the cancel function
+// captures the call stack of its caller together with the passed cause,
+// so that errors of the canceled context tell who canceled it.
+// Canceling with a nil cause captures no call stack
+// and sets the cause context.Canceled like the standard library.
+//
+// The cause of the returned context unwraps to context.Canceled
+// and to the error passed to cancel, and its message names
+// the function and source location that canceled the context:
+//
+//	context canceled by main.(*Server).Shutdown at github.com/my/app/server.go:42: shutdown
+//
+// Use ContextErr or ContextCause to get the cause
+// and CallStackFrames to get the call stack of the cancellation.
+//
+// Example:
+//
+//	ctx, cancel := errs.WithCancelCause(ctx)
+//	defer cancel(nil)
+//	go func() {
+//	    if err := watch(ctx); err != nil {
+//	        cancel(err)
+//	    }
+//	}()
+//	// ...
+//	return errs.ContextErr(ctx)
+func WithCancelCause(parent context.Context) (ctx context.Context, cancel context.CancelCauseFunc) {
+	ctx, cancelCause := context.WithCancelCause(parent)
+	return ctx, func(cause error) {
+		if cause == nil {
+			// Like a deferred cancel(nil) cleaning up
+			// after the work is done, not worth a call stack
+			cancelCause(nil)
+			return
+		}
+		if ctx.Err() != nil {
+			// Already canceled, the cause can't change anymore
+			return
+		}
+		cancelCause(canceledError{
+			cause:     cause,
+			callStack: callStack(1),
+		})
+	}
+}
+
+// canceledError is the cause set by the cancel function
+// of WithCancelCause for a non nil cause.
+type canceledError struct {
+	cause     error
+	callStack []uintptr
+}
+
+func (e canceledError) Error() string {
+	var b strings.Builder
+	b.WriteString(context.Canceled.Error())
+	if frame, ok := firstFrame(e.callStack, currentTrimFilePathPrefix()); ok {
+		fmt.Fprintf(&b, " by %s at %s:%d", frame.Function, frame.File, frame.Line)
+	}
+	b.WriteString(": ")
+	b.WriteString(e.cause.Error())
+	return b.String()
+}
+
+// Unwrap returns context.Canceled and the cause for errors.Is and errors.As.
+// errors.Unwrap returns nil so that the call stacks
+// of the cause are not formatted again by wrapping errors.
+func (e canceledError) Unwrap() []error {
+	return []error{context.Canceled, e.cause}
+}
+
+// StackTrace returns the call stack of the caller of the cancel function,
+// see withCallStack.StackTrace.
+func (e canceledError) StackTrace() []uintptr {
+	return slices.Clone(e.callStack)
+}
@@ -2,8 +2,12 @@ package errs
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestIsContextDone(t *testing.T) {
@@ -89,3 +93,99 @@ func TestIsContextDeadlineExceeded(t *testing.T) {
 	futureDeadlineCtxCancel()   // to avoid lint warnings
 	cancelLater()               // to avoid lint warnings
 }
+
+var errTestShutdown = errors.New("shutdown")
+
+func TestContextCause(t *testing.T) {
+	assert.Nil(t, ContextCause(context.Background()))
+	assert.Nil(t, ContextErr(context.Background()))
+	assert.False(t, IsContextCause(context.Background(), context.Canceled))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, ContextCause(ctx))
+	assert.Equal(t, context.Canceled, ContextErr(ctx))
+	assert.True(t, IsContextCause(ctx, context.Canceled))
+
+	ctx, cancelCause := context.WithCancelCause(context.Background())
+	cancelCause(errTestShutdown)
+	assert.Equal(t, errTestShutdown, ContextCause(ctx))
+	assert.True(t, IsContextCause(ctx, errTestShutdown))
+	assert.True(t, IsContextCanceled(ctx))
+	err := ContextErr(ctx)
+	assert.EqualError(t, err, "context canceled: shutdown")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, err, errTestShutdown)
+	assert.True(t, IsContextError(err))
+
+	ctx, cancelTimeout := context.WithTimeoutCause(context.Background(), time.Nanosecond, errTestShutdown)
+	defer cancelTimeout()
+	<-ctx.Done()
+	err = ContextErr(ctx)
+	assert.EqualError(t, err, "context deadline exceeded: shutdown")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.True(t, IsContextDeadlineExceeded(ctx))
+	assert.True(t, IsContextCause(ctx, errTestShutdown))
+}
+
+func cancelForTest(cancel context.CancelCauseFunc, cause error) {
+	cancel(cause)
+}
+
+func TestWithCancelCause(t *testing.T) {
+	skipWithoutCallStacks(t)
+	ctx, cancel := WithCancelCause(context.Background())
+	assert.Nil(t, ContextErr(ctx))
+	cancelForTest(cancel, errTestShutdown)
+	cancel(errors.New("ignored second cancel"))
+
+	err := ContextErr(ctx)
+	require.Error(t, err)
+	assert.Equal(t, context.Cause(ctx), err)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, err, errTestShutdown)
+	assert.True(t, IsContextError(err))
+	assert.True(t, IsContextCause(ctx, errTestShutdown))
+	assert.Equal(t, context.Canceled, ctx.Err())
+
+	frames := CallStackFrames(err)
+	require.NotEmpty(t, frames)
+	assert.Equal(t, "github.com/domonda/go-errs.cancelForTest", frames[0].Function)
+	assert.Equal(t, frames[0].PC, err.(interface{ StackTrace() []uintptr }).StackTrace()[0])
+	assert.Regexp(t, ""+
+		`^context canceled by github\.com/domonda/go-errs\.cancelForTest`+
+		` at github\.com/domonda/go-errs/context_test\.go:\d+: shutdown$`,
+		err.Error(),
+	)
+
+	// Wrapping shows the cause chain
+	WrapWithFuncParams(&err, "param")
+	assert.Regexp(t, ""+
+		`^context canceled by github\.com/domonda/go-errs\.cancelForTest at github\.com/domonda/go-errs/context_test\.go:\d+: shutdown\n`+
+		"github\\.com/domonda/go-errs\\.TestWithCancelCause\\(`param`\\)\n"+
+		`    github\.com/domonda/go-errs/context_test\.go:\d+\n$`,
+		err.Error(),
+	)
+	assert.ErrorIs(t, err, errTestShutdown)
+}
+
+func TestWithCancelCause_NilCause(t *testing.T) {
+	ctx, cancel := WithCancelCause(context.Background())
+	cancel(nil)
+
+	err := ContextErr(ctx)
+	assert.Equal(t, context.Canceled, err, "no call stack captured")
+	assert.Equal(t, context.Canceled, context.Cause(ctx))
+
+	// The cause can't be changed anymore
+	cancel(errTestShutdown)
+	assert.Equal(t, context.Canceled, context.Cause(ctx))
+}
+
+func TestWithCancelCause_CaptureOff(t *testing.T) {
+	withCaptureMode(t, CaptureOff, 1)
+	ctx, cancel := WithCancelCause(context.Background())
+	cancel(errTestShutdown)
+
+	assert.EqualError(t, ContextErr(ctx), "context canceled: shutdown")
+}
@@ -0,0 +1,190 @@
+package errs
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// ContextDeadline holds timing diagnostics
+// of a context error wrapped by WrapContextErr.
+type ContextDeadline struct {
+	// Deadline of the context or zero if it has none.
+	Deadline time.Time
+	// SetAt is the time the deadline was set by WithDeadline or WithTimeout,
+	// or zero if the deadline was set by other means.
+	SetAt time.Time
+	// WrappedAt is the time the error was wrapped by WrapContextErr.
+	WrappedAt time.Time
+	// Function is the function that called WrapContextErr,
+	// meaning the function that was executing when the context ended.
+	// Empty if no call stack was captured, see CaptureMode.
+	Function string
+}
+
+// Elapsed returns the time from SetAt to WrappedAt
+// or zero if SetAt is unknown.
+func (d ContextDeadline) Elapsed() time.Duration {
+	if d.SetAt.IsZero() {
+		return 0
+	}
+	return d.WrappedAt.Sub(d.SetAt)
+}
+
+// Timeout returns the time from SetAt to Deadline
+// or zero if SetAt or Deadline is unknown.
+func (d ContextDeadline) Timeout() time.Duration {
+	if d.SetAt.IsZero() || d.Deadline.IsZero() {
+		return 0
+	}
+	return d.Deadline.Sub(d.SetAt)
+}
+
+// attrs returns the diagnostics as attributes
+// rendered after the context attributes of WrapWithContext.
+func (d ContextDeadline) attrs() []slog.Attr {
+	var attrs []slog.Attr
+	if !d.Deadline.IsZero() {
+		attrs = append(attrs, slog.String("deadline", d.Deadline.Format(time.RFC3339Nano)))
+	}
+	if !d.SetAt.IsZero() {
+		attrs = append(attrs,
+			slog.String("deadline_set", d.SetAt.Format(time.RFC3339Nano)),
+			slog.Duration("timeout", d.Timeout()),
+			slog.Duration("elapsed", d.Elapsed()),
+		)
+	}
+	if !d.Deadline.IsZero() && d.WrappedAt.After(d.Deadline) {
+		attrs = append(attrs, slog.Duration("exceeded_by", d.WrappedAt.Sub(d.Deadline)))
+	}
+	return attrs
+}
+
+// deadlineSetKey is the context key for the deadlineSet of WithDeadline
+type deadlineSetKey struct{}
+
+type deadlineSet struct {
+	deadline time.Time
+	setAt    time.Time
+}
+
+// WithDeadline is like context.WithDeadline but records the time
+// the deadline was set for the diagnostics of WrapContextErr.
+func WithDeadline(parent context.Context, deadline time.Time) (context.Context, context.CancelFunc) {
+	ctx := context.WithValue(parent, deadlineSetKey{}, deadlineSet{deadline: deadline, setAt: time.Now()})
+	return context.WithDeadline(ctx, deadline)
+}
+
+// WithTimeout is like context.WithTimeout but records the time
+// the deadline was set for the diagnostics of WrapContextErr.
+func WithTimeout(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	now := time.Now()
+	deadline := now.Add(timeout)
+	ctx := context.WithValue(parent, deadlineSetKey{}, deadlineSet{deadline: deadline, setAt: now})
+	return context.WithDeadline(ctx, deadline)
+}
+
+// WrapContextErr wraps the error referenced by resultVar
+// with the current call stack, the function parameters
+// and the timing diagnostics of ctx if the error
+// is a context error according to IsContextError.
+// Other errors and nil are not changed.
+//
+// The diagnostics contain the deadline of ctx, when it was set
+// if that was done by WithDeadline or WithTimeout,
+// the elapsed time, and the wrapping function.
+// They are rendered by the Error method after the attributes
+// of WrapWithContext and returned by ContextDeadlineOf,
+// but not by ContextAttrs.
+// The wrapped error still satisfies IsContextError.
+//
+// Example:
+//
+//	func Query(ctx context.Context, sql string) (err error) {
+//	    defer errs.WrapContextErr(ctx, &err, sql)
+//	    // ...
+//	}
+//
+// Renders as:
+//
+//	context deadline exceeded
+//	main.Query(`SELECT 1`)
+//	    github.com/my/app/query.go:12
+//	    deadline=2026-10-18T10:00:02Z deadline_set=2026-10-18T10:00:00Z timeout=2s elapsed=2.0004s exceeded_by=400µs
+func WrapContextErr(ctx context.Context, resultVar *error, params ...any) {
+	if IsContextError(*resultVar) {
+		*resultVar = wrapContextErrSkip(1, ctx, *resultVar, params...)
+	}
+}
+
+func wrapContextErrSkip(skip int, ctx context.Context, err error, params ...any) error {
+	wrapped := newWithFuncParamsSkip(1+skip, err, params...)
+	deadline := ContextDeadline{WrappedAt: time.Now()}
+	if frame, ok := firstFrame(wrapped.callStack, ""); ok {
+		deadline.Function = frame.Function
+	}
+	if ctx != nil {
+		deadline.Deadline, _ = ctx.Deadline()
+		if set, ok := ctx.Value(deadlineSetKey{}).(deadlineSet); ok && set.deadline.Equal(deadline.Deadline) {
+			// Only if the deadline was not shortened by another context
+			deadline.SetAt = set.setAt
+		}
+	}
+	if hasContextAttrs(err) {
+		// Log the context attributes of the wrapped chain
+		w := newWithContextAttrs(wrapped, nil)
+		w.deadline = &deadline
+		return w
+	}
+	return &withContextDeadline{
+		withCallStackFuncParams: withCallStackFuncParams{
+			withCallStack: withCallStack{
+				err:        wrapped.err,
+				callStack:  wrapped.callStack,
+				callerOnly: wrapped.callerOnly,
+			},
+			params: wrapped.params,
+		},
+		deadline: deadline,
+	}
+}
+
+var (
+	_ error                   = &withContextDeadline{}
+	_ callStackParamsProvider = &withContextDeadline{}
+)
+
+// withContextDeadline is a withCallStackFuncParams
+// with the timing diagnostics of WrapContextErr.
+// For wrapped error chains with context attributes
+// a withContextAttrs with a deadline is used instead.
+type withContextDeadline struct {
+	withCallStackFuncParams
+
+	deadline ContextDeadline
+}
+
+// Error returns the error message followed by the call stack
+// including the function parameters and timing diagnostics.
+//
+// The message is rendered by the first call and cached
+// for all further calls, see withCallStack.Error.
+func (w *withContextDeadline) Error() string {
+	return w.memoizeError(w)
+}
+
+func (w *withContextDeadline) contextDeadline() *ContextDeadline {
+	return &w.deadline
+}
+
+// ContextDeadlineOf returns the timing diagnostics of the outermost
+// context error wrapped by WrapContextErr in the error chain of err.
+func ContextDeadlineOf(err error) (ContextDeadline, bool) {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if w, ok := err.(interface{ contextDeadline() *ContextDeadline }); ok && w.contextDeadline() != nil {
+			return *w.contextDeadline(), true
+		}
+	}
+	return ContextDeadline{}, false
+}
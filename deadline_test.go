@@ -0,0 +1,151 @@
+package errs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func deadlineTestQuery(ctx context.Context) (err error) {
+	defer WrapContextErr(ctx, &err, "SELECT 1")
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestWrapContextErr(t *testing.T) {
+	skipWithoutCallStacks(t)
+	start := time.Now()
+	ctx, cancel := WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := deadlineTestQuery(ctx)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.True(t, IsContextError(err))
+
+	deadline, ok := ContextDeadlineOf(err)
+	require.True(t, ok)
+	ctxDeadline, _ := ctx.Deadline()
+	assert.Equal(t, ctxDeadline, deadline.Deadline)
+	assert.False(t, deadline.SetAt.Before(start))
+	assert.Equal(t, 10*time.Millisecond, deadline.Timeout())
+	assert.GreaterOrEqual(t, deadline.Elapsed(), 10*time.Millisecond)
+	assert.Equal(t, "github.com/domonda/go-errs.deadlineTestQuery", deadline.Function)
+
+	assert.Regexp(t, ""+
+		"^context deadline exceeded\n"+
+		`github\.com/domonda/go-errs\.deadlineTestQuery\(`+"`SELECT 1`"+`\)\n`+
+		`    github\.com/domonda/go-errs/deadline_test\.go:\d+\n`+
+		`    deadline=\S+ deadline_set=\S+ timeout=10ms elapsed=\S+ exceeded_by=\S+\n$`,
+		err.Error(),
+	)
+
+	// Deadline diagnostics are no context attributes
+	assert.Empty(t, ContextAttrs(err))
+
+	// Programmatic access through other wrappers
+	WrapWithFuncParams(&err, "outer")
+	_, ok = ContextDeadlineOf(err)
+	assert.True(t, ok)
+}
+
+func TestWrapContextErr_ContextAttrs(t *testing.T) {
+	skipWithoutCallStacks(t)
+	withTestContextExtractors(t)
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "req-1")
+	ctx, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+
+	err := ctx.Err()
+	WrapWithContext(ctx, &err)
+	WrapContextErr(ctx, &err)
+	_, ok := ContextDeadlineOf(err)
+	assert.True(t, ok)
+	assert.Equal(t, []slog.Attr{slog.String("request_id", "req-1")}, ContextAttrs(err))
+	assert.Regexp(t, `\n    request_id=req-1\n`, err.Error())
+	assert.Regexp(t, `\n    deadline=\S+ exceeded_by=\S+\n$`, err.Error())
+
+	var buf bytes.Buffer
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("msg", "err", err)
+	assert.Contains(t, buf.String(), "err.request_id=req-1")
+}
+
+func TestWrapContextErr_JSON(t *testing.T) {
+	skipWithoutCallStacks(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	err := deadlineTestQuery(ctx)
+	var out struct {
+		Calls []struct {
+			Context  map[string]any `json:"context"`
+			Deadline map[string]any `json:"deadline"`
+		} `json:"calls"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(NewConfig(WithOutputFormat(OutputJSON)).FormatError(err)), &out))
+	require.Len(t, out.Calls, 1)
+	assert.Nil(t, out.Calls[0].Context)
+	assert.Contains(t, out.Calls[0].Deadline, "exceeded_by")
+}
+
+func TestWrapContextErr_StandardContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	err := deadlineTestQuery(ctx)
+	deadline, ok := ContextDeadlineOf(err)
+	require.True(t, ok)
+	assert.False(t, deadline.Deadline.IsZero())
+	assert.True(t, deadline.SetAt.IsZero(), "unknown when the deadline was set")
+	assert.Zero(t, deadline.Elapsed())
+	assert.Regexp(t, `\n    deadline=\S+ exceeded_by=\S+\n$`, err.Error())
+}
+
+func TestWrapContextErr_ShortenedDeadline(t *testing.T) {
+	ctx, cancel := WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	ctx, cancelShort := context.WithTimeout(ctx, time.Millisecond)
+	defer cancelShort()
+
+	deadline, ok := ContextDeadlineOf(deadlineTestQuery(ctx))
+	require.True(t, ok)
+	assert.True(t, deadline.SetAt.IsZero(), "deadline of WithTimeout doesn't apply")
+}
+
+func TestWrapContextErr_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := deadlineTestQuery(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	deadline, ok := ContextDeadlineOf(err)
+	require.True(t, ok)
+	assert.True(t, deadline.Deadline.IsZero())
+	assert.Equal(t, "context canceled\n", err.Error()[:len("context canceled\n")])
+}
+
+func TestWrapContextErr_OtherErrors(t *testing.T) {
+	ctx, cancel := WithDeadline(context.Background(), time.Now().Add(time.Hour))
+	defer cancel()
+
+	var err error
+	WrapContextErr(ctx, &err)
+	assert.NoError(t, err)
+
+	other := errors.New("other")
+	err = other
+	WrapContextErr(ctx, &err)
+	assert.Equal(t, other, err)
+
+	_, ok := ContextDeadlineOf(other)
+	assert.False(t, ok)
+}
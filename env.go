@@ -0,0 +1,180 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Environment variables read by ConfigureFromEnv
+const (
+	// EnvMaxFrames sets the maximum number of captured call stack frames
+	// as positive integer, see WithMaxCallStackFrames.
+	EnvMaxFrames = "GOERRS_MAX_FRAMES"
+
+	// EnvParamMaxLen sets the maximum length in bytes of a formatted
+	// function parameter as positive integer, see WithFormatParamMaxLen.
+	EnvParamMaxLen = "GOERRS_PARAM_MAX_LEN"
+
+	// EnvFormat sets the OutputFormat of formatted errors
+	// as "text", "compact" or "json", see WithOutputFormat.
+	EnvFormat = "GOERRS_FORMAT"
+
+	// EnvFrameFilter sets a comma separated list of function name prefixes
+	// whose calls are omitted from formatted errors,
+	// see ExcludeFunctionPrefixes.
+	EnvFrameFilter = "GOERRS_FRAME_FILTER"
+
+	// EnvCapture sets the CaptureMode as "full", "off", "sampled"
+	// or "sampled:N" to capture the call stack of one in N errors,
+	// see SetCaptureMode and SetCaptureSampleRate.
+	EnvCapture = "GOERRS_CAPTURE"
+)
+
+// envInitErr is the error of ConfigureFromEnv at package initialization
+var envInitErr error
+
+func init() {
+	// A program can't handle errors of init, see EnvInitError
+	envInitErr = ConfigureFromEnv()
+}
+
+// EnvInitError returns the error of ConfigureFromEnv at package
+// initialization naming the ignored environment variables
+// with invalid values, or nil if there were none.
+// Programs can log it after setting up their logging:
+//
+//	if err := errs.EnvInitError(); err != nil {
+//	    log.Printf("ignoring invalid environment variables: %s", err)
+//	}
+func EnvInitError() error {
+	return envInitErr
+}
+
+// ConfigureFromEnv configures the package from the environment variables
+// GOERRS_MAX_FRAMES, GOERRS_PARAM_MAX_LEN, GOERRS_FORMAT,
+// GOERRS_FRAME_FILTER and GOERRS_CAPTURE so that operators
+// can tune error output without rebuilding a program.
+//
+// It is called at package initialization, where its error
+// is returned by EnvInitError, and can be called again after
+// changing the environment or to check the variables for errors.
+//
+// The variables are applied to the DefaultConfig with the options
+// WithMaxCallStackFrames, WithFormatParamMaxLen, WithOutputFormat
+// and WithFrameFilter, and the result is installed with SetDefaultConfig,
+// so later changes of the configuration variables are ignored.
+// GOERRS_CAPTURE is applied with SetCaptureMode.
+// Unset variables don't change the configuration.
+//
+// Variables with invalid values are ignored and
+// reported as error naming the variable, the other
+// variables are still applied.
+func ConfigureFromEnv() error {
+	var (
+		options []ConfigOption
+		errs    []error
+	)
+	if value, ok := lookupEnv(EnvMaxFrames); ok {
+		n, err := parsePositiveInt(value)
+		if err != nil {
+			errs = append(errs, envError(EnvMaxFrames, value, err))
+		} else {
+			options = append(options, WithMaxCallStackFrames(n))
+		}
+	}
+	if value, ok := lookupEnv(EnvParamMaxLen); ok {
+		n, err := parsePositiveInt(value)
+		if err != nil {
+			errs = append(errs, envError(EnvParamMaxLen, value, err))
+		} else {
+			options = append(options, WithFormatParamMaxLen(n))
+		}
+	}
+	if value, ok := lookupEnv(EnvFormat); ok {
+		format, err := ParseOutputFormat(value)
+		if err != nil {
+			errs = append(errs, envError(EnvFormat, value, err))
+		} else {
+			options = append(options, WithOutputFormat(format))
+		}
+	}
+	if value, ok := lookupEnv(EnvFrameFilter); ok {
+		var prefixes []string
+		for prefix := range strings.SplitSeq(value, ",") {
+			if prefix = strings.TrimSpace(prefix); prefix != "" {
+				prefixes = append(prefixes, prefix)
+			}
+		}
+		if len(prefixes) == 0 {
+			errs = append(errs, envError(EnvFrameFilter, value, errors.New("expected comma separated function name prefixes")))
+		} else {
+			options = append(options, WithFrameFilter(ExcludeFunctionPrefixes(prefixes...)))
+		}
+	}
+	if value, ok := lookupEnv(EnvCapture); ok {
+		mode, sampleRate, err := parseCaptureMode(value)
+		if err != nil {
+			errs = append(errs, envError(EnvCapture, value, err))
+		} else {
+			if sampleRate > 0 {
+				SetCaptureSampleRate(sampleRate)
+			}
+			SetCaptureMode(mode)
+		}
+	}
+
+	if len(options) > 0 {
+		SetDefaultConfig(DefaultConfig().With(options...))
+	}
+	return errors.Join(errs...)
+}
+
+// lookupEnv returns the trimmed value of the environment variable key
+// and if it is set to a non empty value.
+func lookupEnv(key string) (string, bool) {
+	value := strings.TrimSpace(os.Getenv(key))
+	return value, value != ""
+}
+
+func envError(key, value string, err error) error {
+	return fmt.Errorf("%s=%q: %w", key, value, err)
+}
+
+func parsePositiveInt(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, errors.New("expected a positive integer")
+	}
+	return n, nil
+}
+
+// parseCaptureMode parses "full", "off", "sampled" and "sampled:N"
+// returning a sample rate of zero if none was specified.
+func parseCaptureMode(s string) (CaptureMode, int, error) {
+	name, rate, hasRate := strings.Cut(strings.ToLower(s), ":")
+	var mode CaptureMode
+	switch name {
+	case "full":
+		mode = CaptureFull
+	case "off":
+		mode = CaptureOff
+	case "sampled":
+		if !hasRate {
+			return CaptureSampled, 0, nil
+		}
+		sampleRate, err := parsePositiveInt(rate)
+		if err != nil {
+			return 0, 0, errors.New("expected sample rate as positive integer after sampled:")
+		}
+		return CaptureSampled, sampleRate, nil
+	default:
+		return 0, 0, errors.New("expected full, sampled, sampled:N or off")
+	}
+	if hasRate {
+		return 0, 0, errors.New("sample rate only valid for sampled")
+	}
+	return mode, 0, nil
+}
@@ -0,0 +1,131 @@
+package errs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// restoreEnvConfig restores the configuration
+// that ConfigureFromEnv can change after the test.
+func restoreEnvConfig(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() {
+		SetDefaultConfig(nil)
+		SetCaptureMode(CaptureFull)
+		SetCaptureSampleRate(defaultCaptureSampleRate)
+	})
+}
+
+func TestConfigureFromEnv(t *testing.T) {
+	restoreEnvConfig(t)
+	t.Setenv(EnvMaxFrames, "64")
+	t.Setenv(EnvParamMaxLen, " 100 ")
+	t.Setenv(EnvFormat, "JSON")
+	t.Setenv(EnvFrameFilter, "github.com/my/middleware., ,runtime.")
+	t.Setenv(EnvCapture, "sampled:10")
+
+	require.NoError(t, ConfigureFromEnv())
+	config := defaultConfig.Load()
+	require.NotNil(t, config, "installed with SetDefaultConfig")
+	assert.Equal(t, 64, config.MaxCallStackFrames())
+	assert.Equal(t, 100, config.FormatParamMaxLen())
+	assert.Equal(t, OutputJSON, config.OutputFormat())
+	assert.False(t, config.showFrame(Frame{Function: "runtime.main"}, true))
+	assert.False(t, config.showFrame(Frame{Function: "github.com/my/middleware.Handler"}, true))
+	assert.True(t, config.showFrame(Frame{Function: "github.com/my/app.main"}, true))
+	assert.Equal(t, CaptureSampled, CaptureMode(captureMode.Load()))
+	assert.Equal(t, int64(10), captureSampleRate.Load())
+
+	// The configuration variables are not written
+	assert.Equal(t, defaultMaxCallStackFrames, MaxCallStackFrames)
+	assert.Equal(t, OutputText, ErrorOutputFormat)
+	assert.Nil(t, FrameFilter)
+}
+
+func TestConfigureFromEnv_InstalledConfig(t *testing.T) {
+	restoreEnvConfig(t)
+	SetDefaultConfig(NewConfig(WithTrimFilePathPrefix("/src/")))
+	t.Setenv(EnvFormat, "compact")
+
+	require.NoError(t, ConfigureFromEnv())
+	assert.Equal(t, OutputCompact, DefaultConfig().OutputFormat())
+	assert.Equal(t, "/src/", DefaultConfig().TrimFilePathPrefix())
+}
+
+func TestConfigureFromEnv_Unset(t *testing.T) {
+	restoreEnvConfig(t)
+	for _, key := range []string{EnvMaxFrames, EnvParamMaxLen, EnvFormat, EnvFrameFilter, EnvCapture} {
+		t.Setenv(key, "")
+	}
+
+	require.NoError(t, ConfigureFromEnv())
+	assert.Equal(t, defaultMaxCallStackFrames, MaxCallStackFrames)
+	assert.Equal(t, OutputText, ErrorOutputFormat)
+	assert.Nil(t, defaultConfig.Load())
+	assert.Equal(t, CaptureFull, CaptureMode(captureMode.Load()))
+}
+
+func TestConfigureFromEnv_Invalid(t *testing.T) {
+	restoreEnvConfig(t)
+	t.Setenv(EnvMaxFrames, "-1")
+	t.Setenv(EnvParamMaxLen, "200")
+	t.Setenv(EnvFormat, "xml")
+	t.Setenv(EnvFrameFilter, " , ")
+	t.Setenv(EnvCapture, "full:2")
+
+	err := ConfigureFromEnv()
+	require.Error(t, err)
+	assert.Equal(t, ""+
+		`GOERRS_MAX_FRAMES="-1": expected a positive integer`+"\n"+
+		`GOERRS_FORMAT="xml": invalid output format "xml", expected text, compact or json`+"\n"+
+		`GOERRS_FRAME_FILTER=",": expected comma separated function name prefixes`+"\n"+
+		`GOERRS_CAPTURE="full:2": sample rate only valid for sampled`,
+		err.Error(),
+	)
+	// Valid variables are still applied
+	assert.Equal(t, defaultMaxCallStackFrames, DefaultConfig().MaxCallStackFrames())
+	assert.Equal(t, 200, DefaultConfig().FormatParamMaxLen())
+	assert.Equal(t, CaptureFull, CaptureMode(captureMode.Load()))
+}
+
+func TestEnvInitError(t *testing.T) {
+	restoreEnvConfig(t)
+	defer func(err error) { envInitErr = err }(envInitErr)
+	t.Setenv(EnvFormat, "xml")
+
+	// Like at package initialization
+	envInitErr = ConfigureFromEnv()
+	assert.EqualError(t, EnvInitError(), `GOERRS_FORMAT="xml": invalid output format "xml", expected text, compact or json`)
+}
+
+func TestParseCaptureMode(t *testing.T) {
+	tests := []struct {
+		s          string
+		mode       CaptureMode
+		sampleRate int
+		wantErr    bool
+	}{
+		{s: "full", mode: CaptureFull},
+		{s: "OFF", mode: CaptureOff},
+		{s: "sampled", mode: CaptureSampled},
+		{s: "sampled:50", mode: CaptureSampled, sampleRate: 50},
+		{s: "sampled:0", wantErr: true},
+		{s: "sampled:x", wantErr: true},
+		{s: "off:1", wantErr: true},
+		{s: "always", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			mode, sampleRate, err := parseCaptureMode(tt.s)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.mode, mode)
+			assert.Equal(t, tt.sampleRate, sampleRate)
+		})
+	}
+}
@@ -3,10 +3,13 @@ package errs
 import (
 	"bytes"
 	"errors"
-	"fmt"
+	"log/slog"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
+
+	"github.com/domonda/go-pretty"
 )
 
 // FormatFunctionCall formats a function call with parameters using go-pretty.
@@ -28,24 +31,60 @@ import (
 // Since go-pretty handles recursive checking in nested struct fields,
 // types are properly formatted at any nesting level.
 
+// formatError formats an error with its call stack and function parameters
+// using the DefaultConfig, see Config.formatError.
+func formatError(err error) string {
+	return DefaultConfig().formatError(err)
+}
+
 // formatError formats an error with its call stack and function parameters.
 // It unwraps the error chain and builds a formatted string showing:
 //   - The root error message
 //   - Each function call with its parameters (if wrapped with WrapWithFuncParams)
 //   - The file and line number for each call
-func formatError(err error) string {
+//
+// The layout depends on the OutputFormat and the message scrubbers
+// are applied to the result.
+func (c *Config) formatError(err error) string {
 	var (
 		firstWithoutStack error
-		calls             []string
+		calls             []formattedCall
 	)
 
 	for err != nil {
 		switch e := err.(type) {
 		case callStackParamsProvider:
-			calls = append(calls, formatCallStackParams(e))
+			stack, params := e.CallStackParams()
+			frame, ok := firstFrame(stack, c.trimFilePathPrefix)
+			if !ok {
+				// No call stack captured, see CaptureMode
+				frame.Function = unknownFunction
+			}
+			if c.showFrame(frame, ok) {
+				call := formattedCall{
+					call:     c.FormatFunctionCall(frame.Function, params...),
+					frame:    frame,
+					hasFrame: ok,
+				}
+				if w, ok := e.(interface{ contextAttrs() []slog.Attr }); ok {
+					call.attrs = w.contextAttrs()
+				}
+				if w, ok := e.(interface{ contextDeadline() *ContextDeadline }); ok && w.contextDeadline() != nil {
+					call.deadline = w.contextDeadline().attrs()
+				}
+				calls = append(calls, call)
+			}
 
 		case callStackProvider:
-			calls = append(calls, formatCallStack(e))
+			// Errors created without capturing a call stack,
+			// see CaptureMode, have no location to show
+			if frame, ok := firstFrame(e.CallStack(), c.trimFilePathPrefix); ok && c.showFrame(frame, ok) {
+				calls = append(calls, formattedCall{
+					call:     frame.Function,
+					frame:    frame,
+					hasFrame: true,
+				})
+			}
 
 		default:
 			if firstWithoutStack == nil {
@@ -61,52 +100,41 @@ func formatError(err error) string {
 		firstWithoutStack = errors.New("no wrapped error found")
 	}
 
-	var b strings.Builder
-	b.WriteString(firstWithoutStack.Error()) //#nosec
-	b.WriteByte('\n')                        //#nosec
-	for i := len(calls) - 1; i >= 0; i-- {
-		b.WriteString(calls[i]) //#nosec
-		b.WriteByte('\n')       //#nosec
+	// Show the innermost call first
+	slices.Reverse(calls)
+
+	switch c.outputFormat {
+	case OutputCompact:
+		return c.ScrubMessage(formatCompact(firstWithoutStack.Error(), calls))
+	case OutputJSON:
+		return formatJSON(c.ScrubMessage(firstWithoutStack.Error()), calls, c.ScrubMessage)
 	}
-	return b.String()
+	return c.ScrubMessage(formatText(firstWithoutStack.Error(), calls))
 }
 
-func formatCallStack(e callStackProvider) string {
-	stack := e.CallStack()
-	frame, _ := runtime.CallersFrames(stack).Next()
-	return fmt.Sprintf(
-		"%s\n    %s:%d",
-		frame.Function,
-		callStackFilePath(frame),
-		frame.Line,
-	)
+// showFrame reports if the call of frame is shown
+// according to the frame filter of the Config.
+// Frames of errors without call stack are always shown.
+func (c *Config) showFrame(frame Frame, ok bool) bool {
+	filter := c.frameFilter
+	if c.frameFilterVar {
+		filter = FrameFilter
+	}
+	return !ok || filter == nil || filter(frame)
 }
 
-func formatCallStackParams(e callStackParamsProvider) string {
-	stack, params := e.CallStackParams()
-	frame, _ := runtime.CallersFrames(stack).Next()
-	return fmt.Sprintf(
-		"%s\n    %s:%d",
-		FormatFunctionCall(frame.Function, params...),
-		callStackFilePath(frame),
-		frame.Line,
-	)
-}
+// unknownFunction is shown as function name for function parameters
+// of errors created without capturing a call stack.
+const unknownFunction = "<unknown>"
 
-// callStackFilePath returns the source file path to display for a stack frame.
-//
-// If [TrimFilePathPrefix] is set it is trimmed from the raw runtime file-path
-// (legacy behavior). Otherwise the path is returned in a checkout-independent
-// import-path form: a file-path that is already relative (built with -trimpath)
-// is used as-is, while an absolute build path is reconstructed from the frame's
-// package import path (always carried by frame.Function) and the file's base
-// name. That makes the output identical no matter where the module is checked
-// out.
-func callStackFilePath(frame runtime.Frame) string {
+// importPathFile returns the source file path of a stack frame
+// in a checkout-independent import-path form: a file-path that is already
+// relative (built with -trimpath) is used as-is, while an absolute build path
+// is reconstructed from the frame's package import path (always carried by
+// frame.Function) and the file's base name. That makes the output identical
+// no matter where the module is checked out.
+func importPathFile(frame runtime.Frame) string {
 	file := frame.File
-	if TrimFilePathPrefix != "" {
-		return strings.TrimPrefix(file, TrimFilePathPrefix)
-	}
 	if file == "" || !filepath.IsAbs(file) {
 		return file
 	}
@@ -143,6 +171,7 @@ func funcPackagePath(fn string) string {
 //
 // FormatFunctionCall is a function variable that can be changed
 // to globally configure the formatting of function calls.
+// Use WithFormatFunctionCall to configure it for a Config.
 //
 // Default Implementation:
 //
@@ -150,10 +179,29 @@ func funcPackagePath(fn string) string {
 //
 //	functionName(param1, param2, ...)
 //
-// Each parameter is formatted using the Printer variable. If a formatted
+// Each parameter is formatted using the Printer variable
+// with the Redaction policy applied. If a formatted
 // parameter exceeds FormatParamMaxLen bytes, it will be truncated to ensure
 // valid UTF-8 and suffixed with "…(TRUNCATED)".
-var FormatFunctionCall = func(function string, params ...any) string {
+// If a Config was installed with SetDefaultConfig
+// its settings are used instead of the variables.
+var FormatFunctionCall = defaultFormatFunctionCall
+
+// defaultFormatFunctionCall is the default implementation of FormatFunctionCall
+// using the settings of the installed Config or the configuration variables.
+// It must not call FormatFunctionCall or Config.FormatFunctionCall,
+// because replacements of FormatFunctionCall may call it.
+func defaultFormatFunctionCall(function string, params ...any) string {
+	if c := defaultConfig.Load(); c != nil {
+		return formatFunctionCall(c.redaction.Printer(c.printer), c.formatParamMaxLen, function, params)
+	}
+	return formatFunctionCall(Redaction.Printer(Printer), FormatParamMaxLen, function, params)
+}
+
+// formatFunctionCall is the default implementation
+// of FormatFunctionCall formatting params with printer
+// truncated to maxLen bytes.
+func formatFunctionCall(printer *pretty.Printer, maxLen int, function string, params []any) string {
 	var b strings.Builder
 	b.WriteString(function)
 	b.WriteByte('(')
@@ -161,23 +209,60 @@ var FormatFunctionCall = func(function string, params ...any) string {
 		if i > 0 {
 			b.WriteString(", ")
 		}
-		paramStr := Printer.Sprint(param)
-		if len(paramStr) > FormatParamMaxLen {
-			// Cut off slice may end with invalid UTF-8 sequence
-			b.Write(bytes.ToValidUTF8([]byte(paramStr[:FormatParamMaxLen]), nil))
-			b.WriteString("…(TRUNCATED)")
-		} else {
-			b.WriteString(paramStr)
-		}
+		b.WriteString(formatParam(printer, maxLen, param))
 	}
 	b.WriteByte(')')
 	return b.String()
 }
 
+// formatParam formats a single function parameter with printer,
+// truncated to maxLen bytes.
+// Parameters already formatted by snapshotFuncParams
+// are returned unchanged.
+func formatParam(printer *pretty.Printer, maxLen int, param any) string {
+	if snapshot, ok := param.(formattedParam); ok {
+		return string(snapshot)
+	}
+	paramStr := printer.Sprint(param)
+	if len(paramStr) <= maxLen {
+		return paramStr
+	}
+	// Cut off slice may end with invalid UTF-8 sequence
+	return string(bytes.ToValidUTF8([]byte(paramStr[:maxLen]), nil)) + "…(TRUNCATED)"
+}
+
+// formattedParam is a function parameter that was
+// already formatted when the error was wrapped.
+// It implements pretty.Stringer so that it is printed
+// unchanged by custom FormatFunctionCall implementations.
+type formattedParam string
+
+// PrettyString implements pretty.Stringer.
+func (p formattedParam) PrettyString() string {
+	return string(p)
+}
+
+// snapshotFuncParams formats params at wrap time
+// so that the returned slice holds no references
+// to the original parameter values.
+func snapshotFuncParams(params []any) []any {
+	if len(params) == 0 {
+		return params
+	}
+	config := DefaultConfig()
+	printer := config.redaction.Printer(config.printer)
+	snapshot := make([]any, len(params))
+	for i, param := range params {
+		snapshot[i] = formattedParam(formatParam(printer, config.formatParamMaxLen, param))
+	}
+	return snapshot
+}
+
 // LogFunctionCall logs a formatted function call using FormatFunctionCall if logger is not nil.
 // This is useful for logging function calls with their parameters for debugging.
+// The message scrubbers of the DefaultConfig are applied to the formatted function call.
 func LogFunctionCall(logger Logger, function string, params ...any) {
 	if logger != nil {
-		logger.Printf(FormatFunctionCall(function, params...))
+		logger.Printf("%s", ScrubMessage(FormatFunctionCall(function, params...)))
 	}
 }
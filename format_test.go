@@ -758,37 +758,89 @@ func TestFuncPackagePath(t *testing.T) {
 	}
 }
 
-func TestCallStackFilePath(t *testing.T) {
-	// Restore the global override after the test.
-	defer func(prev string) { TrimFilePathPrefix = prev }(TrimFilePathPrefix)
-
+func TestCachedFrame_File(t *testing.T) {
 	const fn = "github.com/domonda/go-errs.funcC"
 	const want = "github.com/domonda/go-errs/wrapwithfuncparams_test.go"
 
+	file := func(f runtime.Frame, trimPrefix string) string {
+		cached := cachedFrame{Frame: f, importPathFile: importPathFile(f)}
+		return cached.frame(0, trimPrefix).File
+	}
+
 	// Default (empty prefix): an absolute build path is reconstructed into the
 	// checkout-independent import-path form.
-	TrimFilePathPrefix = ""
-	assert.Equal(t, want, callStackFilePath(runtime.Frame{
+	assert.Equal(t, want, file(runtime.Frame{
 		Function: fn,
 		File:     "/Users/somebody/conductor/workspaces/go-errs/san-diego/wrapwithfuncparams_test.go",
-	}), "absolute path reconstructed")
+	}, ""), "absolute path reconstructed")
 
 	// A different absolute checkout path yields the SAME output.
-	assert.Equal(t, want, callStackFilePath(runtime.Frame{
+	assert.Equal(t, want, file(runtime.Frame{
 		Function: fn,
 		File:     "/home/runner/work/go-errs/go-errs/wrapwithfuncparams_test.go",
-	}), "checkout-independent")
+	}, ""), "checkout-independent")
 
 	// An already-relative path (built with -trimpath) is used as-is.
-	assert.Equal(t, want, callStackFilePath(runtime.Frame{
+	assert.Equal(t, want, file(runtime.Frame{
 		Function: fn,
 		File:     "github.com/domonda/go-errs/wrapwithfuncparams_test.go",
-	}), "relative path used as-is")
+	}, ""), "relative path used as-is")
 
-	// An explicit override trims the raw path (legacy behavior).
-	TrimFilePathPrefix = "/Users/somebody/go/src/"
-	assert.Equal(t, want, callStackFilePath(runtime.Frame{
+	// An explicit TrimFilePathPrefix trims the raw path (legacy behavior).
+	assert.Equal(t, want, file(runtime.Frame{
 		Function: fn,
 		File:     "/Users/somebody/go/src/github.com/domonda/go-errs/wrapwithfuncparams_test.go",
-	}), "override trims prefix")
+	}, "/Users/somebody/go/src/"), "override trims prefix")
+}
+
+func TestSnapshotFuncParams(t *testing.T) {
+	defer func(snapshot bool) { SnapshotFuncParams = snapshot }(SnapshotFuncParams)
+
+	update := func(s *strct, buf []byte, password Secret) (err error) {
+		defer WrapWith3FuncParams(&err, s, buf, password)
+		return New("update failed")
+	}
+	s := &strct{A: 1}
+	buf := []byte("buffer")
+
+	SnapshotFuncParams = false
+	lazy := update(s, buf, KeepSecret("hunter2"))
+
+	SnapshotFuncParams = true
+	eager := update(s, buf, KeepSecret("hunter2"))
+
+	// Mutate the pointer parameter after the failure
+	s.A = 2
+
+	assert.Contains(t, lazy.Error(), "strct{A:2}")
+	assert.Contains(t, eager.Error(), "strct{A:1}")
+	assert.Contains(t, eager.Error(), "`buffer`")
+	assert.Contains(t, eager.Error(), "***REDACTED***")
+	assert.NotContains(t, eager.Error(), "hunter2")
+
+	// No references to the parameter values are kept
+	_, params := eager.(callStackParamsProvider).CallStackParams()
+	require.Len(t, params, 3)
+	for _, param := range params {
+		assert.IsType(t, formattedParam(""), param)
+	}
+}
+
+func TestSnapshotFuncParams_FormatParamMaxLen(t *testing.T) {
+	defer func(snapshot bool, maxLen int) {
+		SnapshotFuncParams = snapshot
+		FormatParamMaxLen = maxLen
+	}(SnapshotFuncParams, FormatParamMaxLen)
+	SnapshotFuncParams = true
+	FormatParamMaxLen = 10
+
+	process := func(data string) (err error) {
+		defer WrapWith1FuncParam(&err, data)
+		return New("process failed")
+	}
+	err := process(strings.Repeat("x", 100))
+
+	// Changing the limit after the snapshot has no effect
+	FormatParamMaxLen = 5
+	assert.Contains(t, err.Error(), "(`xxxxxxxxx…(TRUNCATED))")
 }
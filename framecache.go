@@ -0,0 +1,188 @@
+package errs
+
+import (
+	"errors"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Frame is a call stack frame resolved from a program counter.
+type Frame struct {
+	// PC is the program counter the frame was resolved from.
+	PC uintptr
+	// Function is the fully-qualified function name
+	// like "github.com/domonda/go-errs.New".
+	Function string
+	// File is the source file path as shown in formatted errors,
+	// see TrimFilePathPrefix.
+	File string
+	// Line is the line number in File.
+	Line int
+}
+
+// FrameCacheStats are statistics of the global cache
+// mapping program counters to resolved frames.
+type FrameCacheStats struct {
+	// Hits counts program counters resolved from the cache.
+	Hits uint64
+	// Misses counts program counters resolved with runtime.CallersFrames.
+	Misses uint64
+	// Evictions counts entries removed because
+	// the cache reached FrameCacheCapacity.
+	Evictions uint64
+	// Size is the current number of cached program counters.
+	Size int
+}
+
+// ReadFrameCacheStats returns the statistics of the global cache
+// mapping program counters to resolved frames,
+// useful for tuning FrameCacheCapacity.
+func ReadFrameCacheStats() FrameCacheStats {
+	frameCache.mtx.RLock()
+	size := len(frameCache.frames)
+	frameCache.mtx.RUnlock()
+	return FrameCacheStats{
+		Hits:      frameCache.hits.Load(),
+		Misses:    frameCache.misses.Load(),
+		Evictions: frameCache.evictions.Load(),
+		Size:      size,
+	}
+}
+
+// ResetFrameCache removes all entries from the global frame cache
+// and resets its statistics.
+func ResetFrameCache() {
+	frameCache.mtx.Lock()
+	clear(frameCache.frames)
+	frameCache.mtx.Unlock()
+	frameCache.hits.Store(0)
+	frameCache.misses.Store(0)
+	frameCache.evictions.Store(0)
+}
+
+// ResolveCallStack resolves the program counters of a call stack
+// as returned by runtime.Callers into frames using the global frame cache.
+// A program counter of an inlined call can resolve to multiple frames.
+func ResolveCallStack(pcs []uintptr) []Frame {
+	trimPrefix := currentTrimFilePathPrefix()
+	frames := make([]Frame, 0, len(pcs))
+	for _, pc := range pcs {
+		for _, f := range frameCache.resolve(pc) {
+			frames = append(frames, f.frame(pc, trimPrefix))
+		}
+	}
+	return frames
+}
+
+// CallStackFrames returns the resolved frames of the call stack
+// that StackTrace would return for err, or nil if err
+// was not wrapped with a call stack by this package.
+// For the cause of a context canceled by WithCancelCause
+// the call stack of the cancellation is returned.
+func CallStackFrames(err error) []Frame {
+	for ; err != nil; err = errors.Unwrap(err) {
+		switch w := err.(type) {
+		case interface {
+			callStackProvider
+			StackTrace() []uintptr
+		}:
+			return ResolveCallStack(w.StackTrace())
+		case canceledError:
+			return ResolveCallStack(w.callStack)
+		}
+	}
+	return nil
+}
+
+// firstFrame returns the first frame of a call stack
+// resolved using the global frame cache
+// with trimPrefix as TrimFilePathPrefix.
+func firstFrame(pcs []uintptr, trimPrefix string) (Frame, bool) {
+	if len(pcs) == 0 {
+		return Frame{}, false
+	}
+	frames := frameCache.resolve(pcs[0])
+	if len(frames) == 0 {
+		return Frame{}, false
+	}
+	return frames[0].frame(pcs[0], trimPrefix), true
+}
+
+// cachedFrame is a runtime.Frame together with
+// the checkout-independent import-path form of its file.
+type cachedFrame struct {
+	runtime.Frame
+	importPathFile string
+}
+
+// frame returns the Frame with the file path
+// as shown in formatted errors using trimPrefix
+// as TrimFilePathPrefix.
+func (f *cachedFrame) frame(pc uintptr, trimPrefix string) Frame {
+	file := f.importPathFile
+	if trimPrefix != "" {
+		file = strings.TrimPrefix(f.File, trimPrefix)
+	}
+	return Frame{
+		PC:       pc,
+		Function: f.Function,
+		File:     file,
+		Line:     f.Line,
+	}
+}
+
+var frameCache = pcFrameCache{frames: make(map[uintptr][]cachedFrame)}
+
+// pcFrameCache is a bounded concurrent cache mapping program counters
+// to frames resolved with runtime.CallersFrames.
+type pcFrameCache struct {
+	mtx    sync.RWMutex
+	frames map[uintptr][]cachedFrame
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+func (c *pcFrameCache) resolve(pc uintptr) []cachedFrame {
+	c.mtx.RLock()
+	frames, ok := c.frames[pc]
+	c.mtx.RUnlock()
+	if ok {
+		c.hits.Add(1)
+		return frames
+	}
+	c.misses.Add(1)
+
+	// Resolving a single program counter yields the same frames
+	// as resolving it as part of the whole call stack
+	callersFrames := runtime.CallersFrames([]uintptr{pc})
+	for {
+		f, more := callersFrames.Next()
+		frames = append(frames, cachedFrame{Frame: f, importPathFile: importPathFile(f)})
+		if !more {
+			break
+		}
+	}
+
+	capacity := DefaultConfig().frameCacheCapacity
+	if capacity <= 0 {
+		return frames
+	}
+	c.mtx.Lock()
+	if len(c.frames) >= capacity {
+		// Evict an arbitrary entry, map iteration order is random
+		for evict := range c.frames {
+			delete(c.frames, evict)
+			c.evictions.Add(1)
+			if len(c.frames) < capacity {
+				break
+			}
+		}
+	}
+	c.frames[pc] = frames
+	c.mtx.Unlock()
+	return frames
+}
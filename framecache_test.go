@@ -0,0 +1,142 @@
+package errs
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveCallStack(t *testing.T) {
+	skipWithoutCallStacks(t)
+	pcs := callStack(0)
+
+	var want []Frame
+	callersFrames := runtime.CallersFrames(pcs)
+	for {
+		f, more := callersFrames.Next()
+		want = append(want, Frame{Function: f.Function, File: importPathFile(f), Line: f.Line})
+		if !more {
+			break
+		}
+	}
+
+	frames := ResolveCallStack(pcs)
+	require.Len(t, frames, len(want))
+	for i := range frames {
+		assert.Equal(t, want[i].Function, frames[i].Function)
+		assert.Equal(t, want[i].File, frames[i].File)
+		assert.Equal(t, want[i].Line, frames[i].Line)
+	}
+	assert.Equal(t, "github.com/domonda/go-errs.TestResolveCallStack", frames[0].Function)
+	assert.Equal(t, "github.com/domonda/go-errs/framecache_test.go", frames[0].File)
+}
+
+func TestFrameCache_Stats(t *testing.T) {
+	ResetFrameCache()
+	pcs := callStack(0)
+
+	ResolveCallStack(pcs)
+	stats := ReadFrameCacheStats()
+	assert.Equal(t, uint64(0), stats.Hits)
+	assert.Equal(t, uint64(len(pcs)), stats.Misses)
+	assert.Equal(t, len(pcs), stats.Size)
+
+	ResolveCallStack(pcs)
+	stats = ReadFrameCacheStats()
+	assert.Equal(t, uint64(len(pcs)), stats.Hits)
+	assert.Equal(t, uint64(len(pcs)), stats.Misses)
+	assert.Equal(t, uint64(0), stats.Evictions)
+}
+
+func TestFrameCache_Capacity(t *testing.T) {
+	skipWithoutCallStacks(t)
+	defer func(capacity int) { FrameCacheCapacity = capacity }(FrameCacheCapacity)
+	ResetFrameCache()
+	FrameCacheCapacity = 2
+
+	pcs := callStack(0)
+	require.Greater(t, len(pcs), 2)
+	frames := ResolveCallStack(pcs)
+	require.Len(t, frames, len(pcs))
+
+	stats := ReadFrameCacheStats()
+	assert.Equal(t, 2, stats.Size)
+	assert.Equal(t, uint64(len(pcs)-2), stats.Evictions)
+
+	FrameCacheCapacity = 0
+	ResetFrameCache()
+	ResolveCallStack(pcs)
+	assert.Equal(t, 0, ReadFrameCacheStats().Size)
+}
+
+func TestFrameCache_TrimFilePathPrefix(t *testing.T) {
+	skipWithoutCallStacks(t)
+	defer func(prefix string) { TrimFilePathPrefix = prefix }(TrimFilePathPrefix)
+
+	frame, ok := firstFrame(callStack(0), TrimFilePathPrefix)
+	require.True(t, ok)
+	assert.Equal(t, "github.com/domonda/go-errs/framecache_test.go", frame.File)
+
+	// The cached frame respects later changes of TrimFilePathPrefix
+	raw, _ := runtime.CallersFrames([]uintptr{frame.PC}).Next()
+	TrimFilePathPrefix = raw.File[:len(raw.File)-len("framecache_test.go")]
+	frame, ok = firstFrame([]uintptr{frame.PC}, TrimFilePathPrefix)
+	require.True(t, ok)
+	assert.Equal(t, "framecache_test.go", frame.File)
+}
+
+func TestFrameCache_Concurrent(t *testing.T) {
+	ResetFrameCache()
+	pcs := callStack(0)
+	want := ResolveCallStack(pcs)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, want, ResolveCallStack(pcs))
+		}()
+	}
+	wg.Wait()
+}
+
+func TestCallStackFrames(t *testing.T) {
+	skipWithoutCallStacks(t)
+	assert.Nil(t, CallStackFrames(nil))
+	assert.Nil(t, CallStackFrames(Sentinel("no call stack")))
+
+	err := benchmarkErrorChain(2)
+	frames := CallStackFrames(err)
+	require.NotEmpty(t, frames)
+	assert.Equal(t, "github.com/domonda/go-errs.benchmarkErrorChain", frames[0].Function)
+	assert.Equal(t, ResolveCallStack(err.(*withCallStackFuncParams).StackTrace()), frames)
+}
+
+func BenchmarkResolveCallStack(b *testing.B) {
+	pcs := callStack(0)
+
+	b.Run("CallersFrames", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			callersFrames := runtime.CallersFrames(pcs)
+			for {
+				f, more := callersFrames.Next()
+				_ = importPathFile(f)
+				if !more {
+					break
+				}
+			}
+		}
+	})
+
+	b.Run("Cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			_ = ResolveCallStack(pcs)
+		}
+	})
+}
@@ -4,15 +4,16 @@ go 1.24.0
 
 require (
 	github.com/domonda/go-pretty v1.0.0
+	github.com/golangci/plugin-module-register v0.1.2
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/stretchr/testify v1.11.1
 	github.com/ungerik/go-astvisit v0.0.0-20251017171216-b7bb0384dd33
 	golang.org/x/tools v0.42.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/pmezard/go-difflib v1.0.0 // indirect
 	golang.org/x/mod v0.33.0 // indirect
 	golang.org/x/sync v0.19.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
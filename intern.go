@@ -0,0 +1,75 @@
+package errs
+
+import (
+	"slices"
+	"sync"
+)
+
+// internedCallStacks holds shared immutable copies
+// of captured call stacks keyed by a hash of their program counters.
+var internedCallStacks = callStackInterner{stacks: make(map[uint64][][]uintptr)}
+
+type callStackInterner struct {
+	mtx    sync.RWMutex
+	stacks map[uint64][][]uintptr
+	count  int
+}
+
+// intern returns a shared copy of pcs if one exists
+// or stores a copy of pcs as shared copy if the number of
+// interned call stacks is below the MaxInternedCallStacks
+// of the DefaultConfig.
+// The passed pcs is never referenced by the result,
+// so it can be a reused buffer.
+func (in *callStackInterner) intern(pcs []uintptr) []uintptr {
+	hash := hashCallStack(pcs)
+
+	in.mtx.RLock()
+	shared := in.lookup(hash, pcs)
+	in.mtx.RUnlock()
+	if shared != nil {
+		return shared
+	}
+
+	in.mtx.Lock()
+	defer in.mtx.Unlock()
+	if shared = in.lookup(hash, pcs); shared != nil {
+		return shared
+	}
+	shared = slices.Clone(pcs)
+	if in.count < DefaultConfig().maxInternedCallStacks {
+		in.stacks[hash] = append(in.stacks[hash], shared)
+		in.count++
+	}
+	return shared
+}
+
+func (in *callStackInterner) lookup(hash uint64, pcs []uintptr) []uintptr {
+	for _, stack := range in.stacks[hash] {
+		if slices.Equal(stack, pcs) {
+			return stack
+		}
+	}
+	return nil
+}
+
+func (in *callStackInterner) reset() {
+	in.mtx.Lock()
+	clear(in.stacks)
+	in.count = 0
+	in.mtx.Unlock()
+}
+
+// hashCallStack returns the FNV-1a hash of the program counters.
+func hashCallStack(pcs []uintptr) uint64 {
+	hash := uint64(14695981039346656037)
+	for _, pc := range pcs {
+		hash ^= uint64(pc)
+		hash *= 1099511628211
+	}
+	return hash
+}
+
+// callStackBuffers are reused buffers for capturing
+// call stacks that are interned afterwards.
+var callStackBuffers sync.Pool
@@ -0,0 +1,105 @@
+package errs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withInternedCallStacks(t testing.TB, maxInterned int) {
+	t.Helper()
+	intern, max := InternCallStacks, MaxInternedCallStacks
+	t.Cleanup(func() {
+		InternCallStacks, MaxInternedCallStacks = intern, max
+		internedCallStacks.reset()
+	})
+	InternCallStacks, MaxInternedCallStacks = true, maxInterned
+	internedCallStacks.reset()
+}
+
+func internTestError() error {
+	return New("intern test")
+}
+
+func TestInternCallStacks(t *testing.T) {
+	skipWithoutCallStacks(t)
+	withInternedCallStacks(t, 100)
+
+	var errs []error
+	for range 3 {
+		errs = append(errs, internTestError())
+	}
+	other := New("other call site")
+
+	a := errs[0].(*withCallStack).callStack
+	require.NotEmpty(t, a)
+	for _, err := range errs[1:] {
+		b := err.(*withCallStack).callStack
+		assert.Same(t, &a[0], &b[0], "same call site shares the interned call stack")
+	}
+	assert.NotSame(t, &a[0], &other.(*withCallStack).callStack[0])
+	assert.Equal(t, cap(a), len(a), "interned call stack has no spare capacity")
+
+	// StackTrace returns a copy so the shared call stack can't be modified
+	stackTrace := errs[0].(*withCallStack).StackTrace()
+	assert.Equal(t, a, stackTrace)
+	assert.NotSame(t, &a[0], &stackTrace[0])
+
+	assert.Equal(t, errs[1].Error(), errs[0].Error())
+}
+
+func TestInternCallStacks_MaxInternedCallStacks(t *testing.T) {
+	skipWithoutCallStacks(t)
+	withInternedCallStacks(t, 1)
+
+	var stacks [][]uintptr
+	for range 2 {
+		stacks = append(stacks, internTestError().(*withCallStack).callStack)
+	}
+	assert.Same(t, &stacks[0][0], &stacks[1][0])
+
+	// Limit reached, call stacks of new call sites are not shared
+	stacks = nil
+	for range 2 {
+		stacks = append(stacks, New("not interned").(*withCallStack).callStack)
+	}
+	assert.Equal(t, stacks[0], stacks[1])
+	assert.NotSame(t, &stacks[0][0], &stacks[1][0])
+	assert.Equal(t, 1, internedCallStacks.count)
+}
+
+func TestInternCallStacks_Disabled(t *testing.T) {
+	skipWithoutCallStacks(t)
+	require.False(t, InternCallStacks)
+
+	var stacks [][]uintptr
+	for range 2 {
+		stacks = append(stacks, internTestError().(*withCallStack).callStack)
+	}
+	assert.Equal(t, stacks[0], stacks[1])
+	assert.NotSame(t, &stacks[0][0], &stacks[1][0])
+}
+
+func TestHashCallStack(t *testing.T) {
+	assert.Equal(t, hashCallStack([]uintptr{1, 2, 3}), hashCallStack([]uintptr{1, 2, 3}))
+	assert.NotEqual(t, hashCallStack([]uintptr{1, 2, 3}), hashCallStack([]uintptr{3, 2, 1}))
+	assert.NotEqual(t, hashCallStack(nil), hashCallStack([]uintptr{0}))
+}
+
+func BenchmarkNew(b *testing.B) {
+	b.Run("Default", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			_ = internTestError()
+		}
+	})
+
+	b.Run("InternCallStacks", func(b *testing.B) {
+		withInternedCallStacks(b, 100)
+		b.ReportAllocs()
+		for b.Loop() {
+			_ = internTestError()
+		}
+	})
+}
@@ -0,0 +1,198 @@
+package errs
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// OutputFormat is the layout of the text returned by the Error method
+// of errors wrapped by this package, see WithOutputFormat.
+type OutputFormat int
+
+const (
+	// OutputText formats the error message followed by one function call
+	// per wrapper and its source location on a separate line:
+	//
+	//	error in funcC
+	//	github.com/domonda/go-errs.funcC()
+	//	    github.com/domonda/go-errs/wrapwithfuncparams_test.go:24
+	//
+	// Context attributes of WrapWithContext are shown on an
+	// additional indented line as key=value pairs.
+	OutputText OutputFormat = iota
+
+	// OutputCompact formats the error message and the function calls
+	// on a single line separated by " | ":
+	//
+	//	error in funcC | github.com/domonda/go-errs.funcC() at github.com/domonda/go-errs/wrapwithfuncparams_test.go:24
+	//
+	// Context attributes of WrapWithContext are appended
+	// to their call in curly braces.
+	OutputCompact
+
+	// OutputJSON formats the error message and the function calls
+	// as JSON object:
+	//
+	//	{"message":"error in funcC","calls":[{"call":"github.com/domonda/go-errs.funcC()","file":"github.com/domonda/go-errs/wrapwithfuncparams_test.go","line":27}]}
+	//
+	// Context attributes of WrapWithContext are added
+	// to their call as "context" object.
+	OutputJSON
+)
+
+// ParseOutputFormat parses the names "text", "compact" and "json"
+// as returned by OutputFormat.String.
+func ParseOutputFormat(s string) (OutputFormat, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "text":
+		return OutputText, nil
+	case "compact":
+		return OutputCompact, nil
+	case "json":
+		return OutputJSON, nil
+	}
+	return 0, fmt.Errorf("invalid output format %q, expected text, compact or json", s)
+}
+
+// String implements the fmt.Stringer interface.
+func (f OutputFormat) String() string {
+	switch f {
+	case OutputText:
+		return "text"
+	case OutputCompact:
+		return "compact"
+	case OutputJSON:
+		return "json"
+	}
+	return fmt.Sprintf("OutputFormat(%d)", int(f))
+}
+
+// formattedCall is a formatted function call of an error wrapper
+// with the frame of its source location.
+type formattedCall struct {
+	call     string
+	frame    Frame
+	hasFrame bool
+	attrs    []slog.Attr
+	deadline []slog.Attr // ContextDeadline of WrapContextErr
+}
+
+func formatText(message string, calls []formattedCall) string {
+	var b strings.Builder
+	b.WriteString(message) //#nosec
+	b.WriteByte('\n')      //#nosec
+	for _, c := range calls {
+		b.WriteString(c.call) //#nosec
+		if c.hasFrame {
+			fmt.Fprintf(&b, "\n    %s:%d", c.frame.File, c.frame.Line) //#nosec
+		}
+		if attrs := slices.Concat(c.attrs, c.deadline); len(attrs) > 0 {
+			b.WriteString("\n    ") //#nosec
+			writeAttrs(&b, attrs)
+		}
+		b.WriteByte('\n') //#nosec
+	}
+	return b.String()
+}
+
+func formatCompact(message string, calls []formattedCall) string {
+	var b strings.Builder
+	b.WriteString(strings.ReplaceAll(message, "\n", `\n`)) //#nosec
+	for _, c := range calls {
+		b.WriteString(" | ")                                  //#nosec
+		b.WriteString(strings.ReplaceAll(c.call, "\n", `\n`)) //#nosec
+		if c.hasFrame {
+			b.WriteString(" at ")                     //#nosec
+			b.WriteString(c.frame.File)               //#nosec
+			b.WriteByte(':')                          //#nosec
+			b.WriteString(strconv.Itoa(c.frame.Line)) //#nosec
+		}
+		if attrs := slices.Concat(c.attrs, c.deadline); len(attrs) > 0 {
+			b.WriteString(" {") //#nosec
+			writeAttrs(&b, attrs)
+			b.WriteByte('}') //#nosec
+		}
+	}
+	return b.String()
+}
+
+// writeAttrs writes attrs as space separated key=value pairs
+// quoting values that would be ambiguous otherwise.
+func writeAttrs(b *strings.Builder, attrs []slog.Attr) {
+	for i, attr := range attrs {
+		if i > 0 {
+			b.WriteByte(' ') //#nosec
+		}
+		b.WriteString(attr.Key) //#nosec
+		b.WriteByte('=')        //#nosec
+		value := attr.Value.String()
+		if value == "" || strings.ContainsAny(value, " \t\n\"={}|") {
+			value = strconv.Quote(value)
+		}
+		b.WriteString(value) //#nosec
+	}
+}
+
+type jsonError struct {
+	Message string     `json:"message"`
+	Calls   []jsonCall `json:"calls,omitempty"`
+}
+
+type jsonCall struct {
+	Call     string         `json:"call"`
+	File     string         `json:"file,omitempty"`
+	Line     int            `json:"line,omitempty"`
+	Context  map[string]any `json:"context,omitempty"`
+	Deadline map[string]any `json:"deadline,omitempty"`
+}
+
+// formatJSON formats the error as JSON object.
+// The message and calls are scrubbed before encoding
+// so that scrubbers can't break the JSON syntax.
+func formatJSON(message string, calls []formattedCall, scrub func(string) string) string {
+	e := jsonError{Message: message}
+	for _, c := range calls {
+		call := jsonCall{
+			Call: scrub(c.call),
+			File: c.frame.File,
+			Line: c.frame.Line,
+		}
+		call.Context = jsonAttrs(c.attrs, scrub)
+		call.Deadline = jsonAttrs(c.deadline, scrub)
+		e.Calls = append(e.Calls, call)
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		// Can't happen for strings and ints
+		return message
+	}
+	return string(b)
+}
+
+// jsonAttrs returns attrs as JSON object
+// or nil if there are none.
+func jsonAttrs(attrs []slog.Attr, scrub func(string) string) map[string]any {
+	if len(attrs) == 0 {
+		return nil
+	}
+	m := make(map[string]any, len(attrs))
+	for _, attr := range attrs {
+		m[attr.Key] = jsonAttrValue(attr.Value, scrub)
+	}
+	return m
+}
+
+// jsonAttrValue returns integers and booleans unchanged
+// and all other values as scrubbed strings,
+// so that encoding never fails.
+func jsonAttrValue(v slog.Value, scrub func(string) string) any {
+	switch v.Kind() {
+	case slog.KindInt64, slog.KindUint64, slog.KindBool:
+		return v.Any()
+	}
+	return scrub(v.String())
+}
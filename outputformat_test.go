@@ -0,0 +1,80 @@
+package errs
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func outputFormatInner() (err error) {
+	defer WrapWithFuncParams(&err)
+
+	return New("error\nin inner")
+}
+
+func outputFormatOuter(s string) (err error) {
+	defer WrapWithFuncParams(&err, s)
+
+	return outputFormatInner()
+}
+
+func TestParseOutputFormat(t *testing.T) {
+	for _, format := range []OutputFormat{OutputText, OutputCompact, OutputJSON} {
+		parsed, err := ParseOutputFormat(format.String())
+		require.NoError(t, err)
+		assert.Equal(t, format, parsed)
+	}
+	_, err := ParseOutputFormat("yaml")
+	assert.EqualError(t, err, `invalid output format "yaml", expected text, compact or json`)
+	assert.Equal(t, "OutputFormat(7)", OutputFormat(7).String())
+}
+
+func TestOutputFormat(t *testing.T) {
+	skipWithoutCallStacks(t)
+	err := outputFormatOuter("a\tb")
+	frames := CallStackFrames(err)
+	require.GreaterOrEqual(t, len(frames), 2)
+
+	text := NewConfig().FormatError(err)
+	assert.Equal(t, err.Error(), text)
+	assert.Contains(t, text, "error\nin inner\ngithub.com/domonda/go-errs.outputFormatInner()\n    github.com/domonda/go-errs/outputformat_test.go:")
+
+	compact := NewConfig(WithOutputFormat(OutputCompact)).FormatError(err)
+	assert.NotContains(t, compact, "\n")
+	assert.Regexp(t, ""+
+		`^error\\nin inner`+
+		` \| github\.com/domonda/go-errs\.outputFormatInner\(\) at github\.com/domonda/go-errs/outputformat_test\.go:\d+`+
+		" \\| github\\.com/domonda/go-errs\\.outputFormatOuter\\(`a\\tb`\\) at github\\.com/domonda/go-errs/outputformat_test\\.go:\\d+$",
+		compact,
+	)
+
+	var parsed struct {
+		Message string `json:"message"`
+		Calls   []struct {
+			Call string `json:"call"`
+			File string `json:"file"`
+			Line int    `json:"line"`
+		} `json:"calls"`
+	}
+	formatted := NewConfig(WithOutputFormat(OutputJSON)).FormatError(err)
+	require.NoError(t, json.Unmarshal([]byte(formatted), &parsed), formatted)
+	assert.Equal(t, "error\nin inner", parsed.Message)
+	require.Len(t, parsed.Calls, 2)
+	assert.Equal(t, "github.com/domonda/go-errs.outputFormatInner()", parsed.Calls[0].Call)
+	assert.Equal(t, "github.com/domonda/go-errs/outputformat_test.go", parsed.Calls[0].File)
+	assert.Positive(t, parsed.Calls[0].Line)
+	assert.Equal(t, "github.com/domonda/go-errs.outputFormatOuter(`a\tb`)", parsed.Calls[1].Call)
+}
+
+func TestOutputFormat_FrameFilter(t *testing.T) {
+	skipWithoutCallStacks(t)
+	err := outputFormatOuter("x")
+
+	config := NewConfig(WithFrameFilter(ExcludeFunctionPrefixes("github.com/domonda/go-errs.outputFormatInner")))
+	formatted := config.FormatError(err)
+	assert.NotContains(t, formatted, "outputFormatInner")
+	assert.Contains(t, formatted, "outputFormatOuter(`x`)")
+	assert.Contains(t, formatted, "error\nin inner\n")
+}
@@ -0,0 +1,337 @@
+package errs
+
+import (
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+
+	"github.com/domonda/go-pretty"
+)
+
+// RedactionStructTag is the struct tag key checked by a RedactionPolicy.
+// Struct fields tagged with `errs:"secret"` are always redacted.
+const RedactionStructTag = "errs"
+
+// DefaultRedactionFieldNames are the field name patterns
+// of the default Redaction policy, see RedactionPolicy.WithFieldNames.
+var DefaultRedactionFieldNames = []string{
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"apiKey",
+	"privateKey",
+	"iban",
+}
+
+// RedactionPolicy decides which function parameter values
+// are replaced with "***REDACTED***" by FormatFunctionCall
+// without the need to wrap every sensitive parameter with KeepSecret.
+//
+// A policy redacts:
+//   - struct fields tagged with `errs:"secret"`
+//   - struct fields whose names match one of the field name patterns
+//   - values of the registered types, also when referenced by a pointer
+//
+// Embedded struct fields are redacted like other fields
+// by their tag and type name. If they are not redacted as a whole
+// their own fields are checked.
+//
+// The rules apply at any nesting depth because the policy is installed
+// as pretty.Printer.PrintFuncFor hook which go-pretty calls
+// for every value it formats.
+//
+// A RedactionPolicy is immutable, the With methods return a modified copy,
+// so it is safe for concurrent use.
+// A nil *RedactionPolicy is valid and redacts nothing.
+type RedactionPolicy struct {
+	fieldNames []string // normalized patterns
+	types      []reflect.Type
+
+	structs *sync.Map // reflect.Type -> []bool of redacted struct fields or nil
+
+	// cachedPrinter is the Printer returned for the last base printer
+	cachedPrinter atomic.Pointer[redactionPrinter]
+}
+
+type redactionPrinter struct {
+	base    *pretty.Printer
+	printer *pretty.Printer
+}
+
+// NewRedactionPolicy returns a RedactionPolicy that only
+// redacts struct fields tagged with `errs:"secret"`.
+func NewRedactionPolicy() *RedactionPolicy {
+	return &RedactionPolicy{structs: new(sync.Map)}
+}
+
+// WithFieldNames returns a copy of the policy that additionally redacts
+// struct fields whose names end with the whole words of one of the passed patterns.
+//
+// Field names are split into words at camel case boundaries
+// and '_' and '-' characters, an acronym like API in APIKey is one word.
+// Matching is case-insensitive and ignores '_' and '-' characters,
+// so the pattern "apiKey" matches the field names APIKey, UserApiKey
+// and Api_Key but not ApiKeyID, the pattern "token" matches AccessToken
+// but not TokenCount, and the pattern "secret" doesn't match Secretary.
+func (p *RedactionPolicy) WithFieldNames(patterns ...string) *RedactionPolicy {
+	c := p.clone()
+	for _, pattern := range patterns {
+		if pattern = normalizeFieldName(pattern); pattern != "" && !slices.Contains(c.fieldNames, pattern) {
+			c.fieldNames = append(c.fieldNames, pattern)
+		}
+	}
+	return c
+}
+
+// WithTypes returns a copy of the policy that additionally
+// redacts all values of the passed types.
+//
+// Example:
+//
+//	errs.Redaction = errs.Redaction.WithTypes(
+//	    reflect.TypeFor[rsa.PrivateKey](),
+//	    reflect.TypeFor[Credentials](),
+//	)
+func (p *RedactionPolicy) WithTypes(types ...reflect.Type) *RedactionPolicy {
+	c := p.clone()
+	for _, t := range types {
+		if t != nil && !slices.Contains(c.types, t) {
+			c.types = append(c.types, t)
+		}
+	}
+	return c
+}
+
+func (p *RedactionPolicy) clone() *RedactionPolicy {
+	if p == nil {
+		return NewRedactionPolicy()
+	}
+	return &RedactionPolicy{
+		fieldNames: slices.Clone(p.fieldNames),
+		types:      slices.Clone(p.types),
+		structs:    new(sync.Map),
+	}
+}
+
+// RedactsType reports whether values of type t are redacted.
+// Pointers to redacted types are also redacted.
+func (p *RedactionPolicy) RedactsType(t reflect.Type) bool {
+	if p == nil || t == nil {
+		return false
+	}
+	for t.Kind() == reflect.Pointer {
+		if slices.Contains(p.types, t) {
+			return true
+		}
+		t = t.Elem()
+	}
+	return slices.Contains(p.types, t)
+}
+
+// RedactsField reports whether the value of the struct field f is redacted.
+func (p *RedactionPolicy) RedactsField(f reflect.StructField) bool {
+	if p == nil {
+		return false
+	}
+	if slices.Contains(strings.Split(f.Tag.Get(RedactionStructTag), ","), "secret") {
+		return true
+	}
+	if len(p.fieldNames) == 0 {
+		return false
+	}
+	words := fieldNameWords(f.Name)
+	for i := range words {
+		if slices.Contains(p.fieldNames, strings.Join(words[i:], "")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Printer returns a copy of base that applies the policy
+// before the PrintFuncFor hook of base.
+// If p is nil then base is returned unchanged.
+//
+// The printer for the last passed base is cached,
+// so base must not be modified after it was passed.
+func (p *RedactionPolicy) Printer(base *pretty.Printer) *pretty.Printer {
+	if p == nil {
+		return base
+	}
+	if cached := p.cachedPrinter.Load(); cached != nil && cached.base == base {
+		return cached.printer
+	}
+	next := base.PrintFuncFor
+	if next == nil {
+		next = pretty.PrintFuncForPrintable
+	}
+	printer := p.printer(base, next, nil)
+	p.cachedPrinter.Store(&redactionPrinter{base: base, printer: printer})
+	return printer
+}
+
+// printer returns a copy of base with a PrintFuncFor hook applying the policy.
+// visited holds the pointers of the structs currently printed by the hook
+// because every redacted struct is printed with a new go-pretty call
+// that does not know about the circular reference detection of the outer call.
+func (p *RedactionPolicy) printer(base *pretty.Printer, next func(reflect.Value) pretty.PrintFunc, visited []uintptr) *pretty.Printer {
+	var printer *pretty.Printer
+	printer = base.WithPrintFuncFor(func(v reflect.Value) pretty.PrintFunc {
+		if !v.IsValid() {
+			return next(v)
+		}
+		if p.redactsValue(v) {
+			return func(w io.Writer) (int, error) {
+				return io.WriteString(w, redacted)
+			}
+		}
+		if printFunc := next(v); printFunc != nil {
+			// Types printing themselves take precedence over field redaction
+			return printFunc
+		}
+
+		var ptr uintptr
+		for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return nil
+			}
+			if v.Kind() == reflect.Pointer {
+				ptr = v.Pointer()
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return nil
+		}
+		redactedFields := p.redactedStructFields(v.Type())
+		if redactedFields == nil {
+			return nil
+		}
+		if ptr != 0 && slices.Contains(visited, ptr) {
+			return func(w io.Writer) (int, error) {
+				return io.WriteString(w, pretty.CircularRef)
+			}
+		}
+		fieldPrinter := printer
+		if ptr != 0 {
+			fieldPrinter = p.printer(base, next, append(slices.Clip(visited), ptr))
+		}
+		return func(w io.Writer) (int, error) {
+			return printRedactedStruct(w, fieldPrinter, v, redactedFields)
+		}
+	})
+	return printer
+}
+
+// redactsValue reports whether v or the value
+// referenced by v via pointers or interfaces is of a redacted type.
+func (p *RedactionPolicy) redactsValue(v reflect.Value) bool {
+	if len(p.types) == 0 {
+		return false
+	}
+	for {
+		if slices.Contains(p.types, v.Type()) {
+			return true
+		}
+		if (v.Kind() != reflect.Pointer && v.Kind() != reflect.Interface) || v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+}
+
+// redactedStructFields returns which fields of the struct type t are redacted
+// or nil if none of its fields is redacted.
+func (p *RedactionPolicy) redactedStructFields(t reflect.Type) []bool {
+	if cached, ok := p.structs.Load(t); ok {
+		return cached.([]bool)
+	}
+	var redactedFields []bool
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if f.IsExported() && p.RedactsField(f) {
+			if redactedFields == nil {
+				redactedFields = make([]bool, t.NumField())
+			}
+			redactedFields[i] = true
+		}
+	}
+	p.structs.Store(t, redactedFields)
+	return redactedFields
+}
+
+// printRedactedStruct prints a struct in the same
+// format as go-pretty but with redacted field values.
+func printRedactedStruct(w io.Writer, printer *pretty.Printer, v reflect.Value, redactedFields []bool) (int, error) {
+	var b strings.Builder
+	b.WriteString(v.Type().Name())
+	b.WriteByte('{')
+	first := true
+	for i := range v.NumField() {
+		f := v.Type().Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if !first {
+			b.WriteByte(';')
+		}
+		first = false
+		if !f.Anonymous || redactedFields[i] {
+			// Name redacted embedded fields because their value isn't shown
+			b.WriteString(f.Name)
+			b.WriteByte(':')
+		}
+		switch field := v.Field(i); {
+		case redactedFields[i]:
+			b.WriteString(redacted)
+		case field.CanAddr() && field.Kind() != reflect.Pointer && field.Kind() != reflect.Interface:
+			// Pass a pointer so that methods with pointer receivers are found
+			b.WriteString(printer.Sprint(field.Addr().Interface()))
+		default:
+			b.WriteString(printer.Sprint(field.Interface()))
+		}
+	}
+	b.WriteByte('}')
+	return io.WriteString(w, b.String())
+}
+
+func normalizeFieldName(name string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(name))
+}
+
+// fieldNameWords returns the lower case words of a field name
+// split at camel case boundaries and '_' and '-' characters.
+// Upper case acronyms are one word, so APIKey has the words api and key.
+func fieldNameWords(name string) []string {
+	var (
+		words []string
+		word  []rune
+	)
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+	runes := []rune(name)
+	for i, r := range runes {
+		if r == '_' || r == '-' {
+			flush()
+			continue
+		}
+		if unicode.IsUpper(r) && len(word) > 0 {
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if !unicode.IsUpper(runes[i-1]) || nextLower {
+				flush()
+			}
+		}
+		word = append(word, r)
+	}
+	flush()
+	return words
+}
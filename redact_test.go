@@ -0,0 +1,263 @@
+package errs
+
+import (
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/domonda/go-pretty"
+)
+
+type redactLogin struct {
+	Username string
+	Password string
+	PIN      int `errs:"secret"`
+}
+
+type redactRequest struct {
+	ID     int
+	Login  redactLogin
+	Logins []*redactLogin
+	Meta   any
+	APIKey string
+}
+
+type redactIBAN string
+
+type redactCycle struct {
+	Name  string
+	Token string
+	Next  *redactCycle
+}
+
+func TestRedactionPolicy_StructTag(t *testing.T) {
+	printer := NewRedactionPolicy().Printer(Printer)
+
+	result := printer.Sprint(redactLogin{Username: "admin", Password: "pw", PIN: 1234})
+
+	assert.Equal(t, "redactLogin{Username:`admin`;Password:`pw`;PIN:***REDACTED***}", result)
+}
+
+func TestRedactionPolicy_FieldNames(t *testing.T) {
+	printer := NewRedactionPolicy().WithFieldNames("password", "api_key").Printer(Printer)
+
+	result := printer.Sprint(redactLogin{Username: "admin", Password: "pw", PIN: 1234})
+	assert.Equal(t, "redactLogin{Username:`admin`;Password:***REDACTED***;PIN:***REDACTED***}", result)
+
+	result = printer.Sprint(&redactRequest{ID: 1, APIKey: "sk-123"})
+	assert.Contains(t, result, "ID:1")
+	assert.Contains(t, result, "APIKey:***REDACTED***")
+	assert.NotContains(t, result, "sk-123")
+}
+
+func TestRedactionPolicy_FieldNameWords(t *testing.T) {
+	policy := NewRedactionPolicy().WithFieldNames(DefaultRedactionFieldNames...)
+
+	for name, want := range map[string]bool{
+		"Password":      true,
+		"UserPassword":  true,
+		"DB_PASSWORD":   true,
+		"APIKey":        true,
+		"UserApiKey":    true,
+		"Api_Key":       true,
+		"AccessToken":   true,
+		"OAuth2Token":   true,
+		"ClientSecret":  true,
+		"RSAPrivateKey": true,
+		"IBAN":          true,
+		"CustomerIBAN":  true,
+		"TokenCount":    false,
+		"ApiKeyID":      false,
+		"Secretary":     false,
+		"Ibanez":        false,
+		"Tokenizer":     false,
+		"PasswordHint":  false,
+	} {
+		assert.Equal(t, want, policy.RedactsField(reflect.StructField{Name: name}), name)
+	}
+}
+
+func TestFieldNameWords(t *testing.T) {
+	assert.Equal(t, []string{"api", "key"}, fieldNameWords("APIKey"))
+	assert.Equal(t, []string{"user", "api", "key", "id"}, fieldNameWords("UserAPIKeyID"))
+	assert.Equal(t, []string{"db", "password"}, fieldNameWords("DB_PASSWORD"))
+	assert.Equal(t, []string{"o", "auth2", "token"}, fieldNameWords("OAuth2Token"))
+	assert.Equal(t, []string{"token", "count"}, fieldNameWords("tokenCount"))
+	assert.Empty(t, fieldNameWords("_"))
+}
+
+type RedactToken string
+
+type RedactCredentials struct {
+	User     string
+	Password string
+}
+
+func TestRedactionPolicy_EmbeddedFields(t *testing.T) {
+	printer := NewRedactionPolicy().WithFieldNames(DefaultRedactionFieldNames...).Printer(Printer)
+
+	// Fields of embedded structs
+	result := printer.Sprint(struct {
+		RedactCredentials
+		ID int
+	}{RedactCredentials{User: "admin", Password: "embedded-password"}, 1})
+	assert.Equal(t, "{RedactCredentials{User:`admin`;Password:***REDACTED***};ID:1}", result)
+
+	// Embedded types with redacted names
+	result = printer.Sprint(struct {
+		RedactToken
+		ID int
+	}{"embedded-token", 1})
+	assert.Equal(t, "{RedactToken:***REDACTED***;ID:1}", result)
+
+	// Tagged embedded structs
+	result = printer.Sprint(struct {
+		*RedactCredentials `errs:"secret"`
+		ID                 int
+	}{&RedactCredentials{User: "tagged-user"}, 1})
+	assert.Equal(t, "{RedactCredentials:***REDACTED***;ID:1}", result)
+}
+
+func TestRedactionPolicy_PrinterCached(t *testing.T) {
+	policy := NewRedactionPolicy().WithFieldNames("password")
+
+	printer := policy.Printer(Printer)
+	assert.Same(t, printer, policy.Printer(Printer))
+
+	base := Printer.WithPrintFuncFor(pretty.PrintFuncForPrintable)
+	assert.NotSame(t, printer, policy.Printer(base))
+	assert.Same(t, policy.Printer(base), policy.Printer(base))
+}
+
+func TestRedactionPolicy_NestedDepth(t *testing.T) {
+	printer := NewRedactionPolicy().WithFieldNames(DefaultRedactionFieldNames...).Printer(Printer)
+
+	request := &redactRequest{
+		ID:     7,
+		Login:  redactLogin{Username: "nested", Password: "nested-password"},
+		Logins: []*redactLogin{{Username: "in-slice", Password: "slice-password"}},
+		Meta:   map[string]any{"login": redactLogin{Username: "in-map", Password: "map-password"}},
+		APIKey: "sk-nested",
+	}
+	result := printer.Sprint(request)
+
+	assert.Contains(t, result, "ID:7")
+	assert.Contains(t, result, "Username:`nested`")
+	assert.Contains(t, result, "Username:`in-slice`")
+	assert.Contains(t, result, "Username:`in-map`")
+	assert.NotContains(t, result, "nested-password")
+	assert.NotContains(t, result, "slice-password")
+	assert.NotContains(t, result, "map-password")
+	assert.NotContains(t, result, "sk-nested")
+}
+
+func TestRedactionPolicy_Types(t *testing.T) {
+	printer := NewRedactionPolicy().WithTypes(reflect.TypeFor[redactIBAN]()).Printer(Printer)
+
+	iban := redactIBAN("AT611904300234573201")
+	for _, value := range []any{
+		iban,
+		&iban,
+		struct{ Account redactIBAN }{iban},
+		struct{ Account any }{iban},
+		[]redactIBAN{iban},
+	} {
+		result := printer.Sprint(value)
+		assert.Contains(t, result, "***REDACTED***", "%T", value)
+		assert.NotContains(t, result, string(iban), "%T", value)
+	}
+
+	assert.True(t, NewRedactionPolicy().WithTypes(reflect.TypeFor[redactIBAN]()).RedactsType(reflect.TypeFor[**redactIBAN]()))
+	assert.False(t, NewRedactionPolicy().RedactsType(reflect.TypeFor[redactIBAN]()))
+}
+
+func TestRedactionPolicy_CircularReference(t *testing.T) {
+	printer := NewRedactionPolicy().WithFieldNames("token").Printer(Printer)
+
+	a := &redactCycle{Name: "a", Token: "token-a"}
+	b := &redactCycle{Name: "b", Token: "token-b", Next: a}
+	a.Next = b
+
+	result := printer.Sprint(a)
+
+	assert.Contains(t, result, "Name:`a`")
+	assert.Contains(t, result, "Name:`b`")
+	assert.Contains(t, result, pretty.CircularRef)
+	assert.NotContains(t, result, "token-a")
+	assert.NotContains(t, result, "token-b")
+}
+
+func TestRedactionPolicy_Nil(t *testing.T) {
+	var policy *RedactionPolicy
+	require.Same(t, Printer, policy.Printer(Printer))
+	require.False(t, policy.RedactsField(reflect.TypeFor[redactLogin]().Field(1)))
+
+	policy = policy.WithFieldNames("password")
+	require.True(t, policy.RedactsField(reflect.TypeFor[redactLogin]().Field(1)))
+}
+
+func TestRedactionPolicy_KeepsBasePrintFuncFor(t *testing.T) {
+	base := Printer.WithPrintFuncFor(func(v reflect.Value) pretty.PrintFunc {
+		if v.Kind() == reflect.String && v.String() == "admin" {
+			return func(w io.Writer) (int, error) {
+				return io.WriteString(w, "ADMIN")
+			}
+		}
+		return pretty.PrintFuncForPrintable(v)
+	})
+	printer := NewRedactionPolicy().WithFieldNames("password").Printer(base)
+
+	result := printer.Sprint(redactLogin{Username: "admin", Password: "pw"})
+
+	assert.Equal(t, "redactLogin{Username:ADMIN;Password:***REDACTED***;PIN:***REDACTED***}", result)
+}
+
+func TestRedactionPolicy_PrintableTakesPrecedence(t *testing.T) {
+	type withPrintable struct {
+		Token *SimplePrintable
+	}
+	printer := NewRedactionPolicy().WithFieldNames("value").Printer(Printer)
+
+	// SimplePrintable has a field named Value but prints itself
+	result := printer.Sprint(withPrintable{Token: &SimplePrintable{Value: "visible"}})
+
+	assert.Equal(t, "withPrintable{Token:Simple{visible}}", result)
+}
+
+func TestFormatFunctionCall_DefaultRedaction(t *testing.T) {
+	result := FormatFunctionCall("Login", redactLogin{Username: "admin", Password: "hunter2", PIN: 1234})
+
+	assert.Equal(t, "Login(redactLogin{Username:`admin`;Password:***REDACTED***;PIN:***REDACTED***})", result)
+}
+
+func TestFormatFunctionCall_RedactionDisabled(t *testing.T) {
+	defer func(r *RedactionPolicy) { Redaction = r }(Redaction)
+	Redaction = nil
+
+	result := FormatFunctionCall("Login", redactLogin{Username: "admin", Password: "hunter2", PIN: 1234})
+
+	assert.Equal(t, "Login(redactLogin{Username:`admin`;Password:`hunter2`;PIN:1234})", result)
+}
+
+func TestWrapWithFuncParams_Redaction(t *testing.T) {
+	login := func(request *redactRequest) (err error) {
+		defer WrapWithFuncParams(&err, request)
+		return New("login failed")
+	}
+
+	err := login(&redactRequest{
+		ID:     1,
+		Login:  redactLogin{Username: "admin", Password: "hunter2"},
+		APIKey: "sk-live-123",
+	})
+	require.Error(t, err)
+
+	errStr := err.Error()
+	assert.Contains(t, errStr, "login failed")
+	assert.Contains(t, errStr, "Username:`admin`")
+	assert.NotContains(t, errStr, "hunter2")
+	assert.NotContains(t, errStr, "sk-live-123")
+}
@@ -1,6 +1,14 @@
 package errs
 
-import "fmt"
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/domonda/go-pretty"
+)
 
 // Secret is an interface that wraps a secret value
 // to prevent it from being logged or printed.
@@ -17,10 +25,28 @@ type Secret interface {
 
 // KeepSecret wraps the passed value in a Secret
 // to prevent it from being logged or printed.
+//
+// The returned Secret also implements fmt.Formatter, fmt.GoStringer,
+// json.Marshaler, encoding.TextMarshaler, slog.LogValuer and
+// pretty.Stringer, so every encoder of the standard library
+// and go-pretty renders it as "***REDACTED***".
 func KeepSecret(val any) Secret {
 	return secret{val}
 }
 
+// redacted is the placeholder rendered instead of a secret value.
+const redacted = "***REDACTED***"
+
+var (
+	_ Secret                 = secret{}
+	_ fmt.Formatter          = secret{}
+	_ fmt.GoStringer         = secret{}
+	_ json.Marshaler         = secret{}
+	_ encoding.TextMarshaler = secret{}
+	_ slog.LogValuer         = secret{}
+	_ pretty.Stringer        = secret{}
+)
+
 type secret struct{ val any }
 
 func (s secret) Secret() any {
@@ -28,15 +54,49 @@ func (s secret) Secret() any {
 }
 
 func (secret) String() string {
-	return "***REDACTED***"
+	return redacted
 }
 
 func (s secret) GoString() string {
-	return fmt.Sprintf("%T(***REDACTED***)", s.val)
+	return fmt.Sprintf("%T(%s)", s.val, redacted)
+}
+
+// Format implements fmt.Formatter so that every verb and flag
+// combination renders the redacted placeholder.
+// Without it verbs like %d or %x would be applied
+// to the placeholder string or, for %#v, to the wrapped value.
+func (s secret) Format(f fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && f.Flag('#'):
+		fmt.Fprint(f, s.GoString())
+	case verb == 'q':
+		fmt.Fprintf(f, fmt.FormatString(f, verb), redacted)
+	default:
+		fmt.Fprintf(f, fmt.FormatString(f, 's'), redacted)
+	}
+}
+
+// MarshalJSON implements json.Marshaler
+// by encoding the redacted placeholder as JSON string.
+func (secret) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(redacted)), nil
+}
+
+// MarshalText implements encoding.TextMarshaler
+// which is used by encoding/xml, as JSON map key,
+// and by slog text handlers.
+func (secret) MarshalText() ([]byte, error) {
+	return []byte(redacted), nil
+}
+
+// LogValue implements slog.LogValuer
+// to log the redacted placeholder instead of the secret.
+func (secret) LogValue() slog.Value {
+	return slog.StringValue(redacted)
 }
 
 // PrettyString implements the pretty.Stringer interface
 // to ensure secrets are never revealed in pretty-printed output or error messages.
 func (secret) PrettyString() string {
-	return "***REDACTED***"
+	return redacted
 }
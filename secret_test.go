@@ -1,8 +1,12 @@
 package errs_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"fmt"
+	"log/slog"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -121,3 +125,123 @@ func TestSecret_DifferentTypes(t *testing.T) {
 		})
 	}
 }
+
+// secretHolder embeds a Secret in the ways
+// encoders typically find values by reflection.
+type secretHolder struct {
+	Name     string
+	Password errs.Secret
+	Nested   struct{ Token errs.Secret }
+	Map      map[string]any
+}
+
+type secretXML struct {
+	Name     string
+	Password errs.Secret
+	Attr     errs.Secret `xml:"attr,attr"`
+}
+
+func newSecretHolder(secretValue string) secretHolder {
+	h := secretHolder{
+		Name:     "user",
+		Password: errs.KeepSecret(secretValue),
+		Map:      map[string]any{"key": errs.KeepSecret(secretValue)},
+	}
+	h.Nested.Token = errs.KeepSecret(secretValue)
+	return h
+}
+
+func TestSecret_Encoders(t *testing.T) {
+	const secretValue = "hunter2-secret"
+	holder := newSecretHolder(secretValue)
+	secret := errs.KeepSecret(secretValue)
+
+	encoders := map[string]func() (string, error){
+		"json.Marshal": func() (string, error) {
+			b, err := json.Marshal(holder)
+			return string(b), err
+		},
+		"json.Marshal top-level": func() (string, error) {
+			b, err := json.Marshal(secret)
+			return string(b), err
+		},
+		"json.Marshal map key": func() (string, error) {
+			b, err := json.Marshal(map[errs.Secret]int{secret: 1})
+			return string(b), err
+		},
+		"xml.Marshal": func() (string, error) {
+			b, err := xml.Marshal(secretXML{Name: "user", Password: secret, Attr: secret})
+			return string(b), err
+		},
+		"MarshalText": func() (string, error) {
+			b, err := secret.(interface{ MarshalText() ([]byte, error) }).MarshalText()
+			return string(b), err
+		},
+		"slog.TextHandler": func() (string, error) {
+			var buf bytes.Buffer
+			slog.New(slog.NewTextHandler(&buf, nil)).Info("msg", "secret", secret, "holder", holder)
+			return buf.String(), nil
+		},
+		"slog.JSONHandler": func() (string, error) {
+			var buf bytes.Buffer
+			slog.New(slog.NewJSONHandler(&buf, nil)).Info("msg", "secret", secret, "holder", holder)
+			return buf.String(), nil
+		},
+		"slog.Group": func() (string, error) {
+			var buf bytes.Buffer
+			slog.New(slog.NewJSONHandler(&buf, nil)).Info("msg", slog.Group("g", slog.Any("secret", secret)))
+			return buf.String(), nil
+		},
+	}
+	for name, encode := range encoders {
+		t.Run(name, func(t *testing.T) {
+			encoded, err := encode()
+			require.NoError(t, err)
+			require.Contains(t, encoded, "***REDACTED***")
+			require.NotContains(t, encoded, secretValue)
+		})
+	}
+}
+
+func TestSecret_FmtVerbs(t *testing.T) {
+	tests := []struct {
+		value any
+		want  map[string]string
+	}{
+		{
+			value: "hunter2-secret",
+			want: map[string]string{
+				"%s":   "***REDACTED***",
+				"%v":   "***REDACTED***",
+				"%+v":  "***REDACTED***",
+				"%#v":  "string(***REDACTED***)",
+				"%q":   `"***REDACTED***"`,
+				"%x":   "***REDACTED***",
+				"%d":   "***REDACTED***",
+				"%20s": "      ***REDACTED***",
+			},
+		},
+		{
+			value: 123456789,
+			want: map[string]string{
+				"%d":  "***REDACTED***",
+				"%x":  "***REDACTED***",
+				"%#v": "int(***REDACTED***)",
+			},
+		},
+	}
+	for _, tt := range tests {
+		secret := errs.KeepSecret(tt.value)
+		for format, want := range tt.want {
+			t.Run(fmt.Sprintf("%T/%s", tt.value, format), func(t *testing.T) {
+				require.Equal(t, want, fmt.Sprintf(format, secret))
+			})
+		}
+	}
+
+	// Secrets nested in structs printed with fmt
+	holder := newSecretHolder("hunter2-secret")
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		require.NotContains(t, fmt.Sprintf(format, holder), "hunter2-secret", format)
+	}
+}
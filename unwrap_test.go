@@ -992,6 +992,7 @@ func TestAs_ComparisonWithStdlibErrorsAs(t *testing.T) {
 }
 
 func TestUnwrapCallStack(t *testing.T) {
+	skipWithoutCallStacks(t)
 	sentinel := Sentinel("sentinel error")
 
 	t.Run("nil error", func(t *testing.T) {
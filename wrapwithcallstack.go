@@ -19,9 +19,11 @@
 package errs
 
 import (
+	"errors"
 	"fmt"
 	"runtime"
 	"slices"
+	"sync/atomic"
 )
 
 // New returns a new error with the passed text
@@ -78,10 +80,14 @@ func WrapWithCallStackSkip(skip int, err error) error {
 	if err == nil {
 		return nil
 	}
-	return &withCallStack{
+	w := &withCallStack{
 		err:       err,
 		callStack: callStack(1 + skip),
 	}
+	if hasContextAttrs(err) {
+		return &withCallStackLogAttrs{withCallStack: w}
+	}
+	return w
 }
 
 type callStackProvider interface {
@@ -99,10 +105,43 @@ var (
 type withCallStack struct {
 	err       error
 	callStack []uintptr
+
+	// callerOnly is true if callStack only holds the program counter
+	// of the calling function because an error further down the chain
+	// already captured the full call stack
+	callerOnly bool
+
+	// errorString caches the result of formatError
+	errorString atomic.Pointer[string]
 }
 
+// Error returns the error message followed by the call stack.
+//
+// The message is rendered by the first call and cached
+// for all further calls, which is safe for concurrent use.
+// This means that changes of the configuration variables,
+// of values referenced by pointer function parameters,
+// or of the messages of wrapped errors that happen after
+// the first call don't change the result anymore.
 func (w *withCallStack) Error() string {
-	return formatError(w)
+	return w.memoizeError(w)
+}
+
+// memoizeError returns the cached errorString
+// or formats err and caches the result.
+// The outer error is passed because withCallStack
+// is embedded by other wrapper types.
+func (w *withCallStack) memoizeError(err error) string {
+	if s := w.errorString.Load(); s != nil {
+		return *s
+	}
+	s := formatError(err)
+	// Concurrent first calls may format in parallel,
+	// all of them return the first stored result
+	if !w.errorString.CompareAndSwap(nil, &s) {
+		return *w.errorString.Load()
+	}
+	return s
 }
 
 func (w *withCallStack) Unwrap() error {
@@ -134,12 +173,58 @@ func (w *withCallStack) CallStack() []uintptr {
 // The returned slice is a copy, matching the pkg/errors contract, so a
 // consumer that reorders it in place cannot corrupt the error's stored
 // call stack.
+//
+// Wrappers that only captured the program counter of their calling
+// function because the wrapped error already has a call stack
+// return the full call stack of the wrapped error instead,
+// which also contains the frames of the outer wrappers.
 func (w *withCallStack) StackTrace() []uintptr {
+	if w.callerOnly {
+		for err := w.err; err != nil; err = errors.Unwrap(err) {
+			if st, ok := err.(interface{ StackTrace() []uintptr }); ok {
+				return st.StackTrace()
+			}
+		}
+	}
 	return slices.Clone(w.callStack)
 }
 
+// callStack returns the call stack skipping skip frames,
+// or nil if the CaptureMode skips capturing it.
 func callStack(skip int) []uintptr {
-	c := make([]uintptr, MaxCallStackFrames)
-	n := runtime.Callers(skip+2, c)
-	return c[:n]
+	if !captureCallStack() {
+		return nil
+	}
+	maxFrames := currentMaxCallStackFrames()
+	if !currentInternCallStacks() {
+		c := make([]uintptr, maxFrames)
+		n := runtime.Callers(skip+2, c)
+		return c[:n]
+	}
+	// Capture into a reused buffer, the interned
+	// call stack never references the buffer
+	buf, _ := callStackBuffers.Get().(*[]uintptr)
+	if buf == nil || len(*buf) < maxFrames {
+		b := make([]uintptr, maxFrames)
+		buf = &b
+	}
+	n := runtime.Callers(skip+2, (*buf)[:maxFrames])
+	stack := internedCallStacks.intern((*buf)[:n])
+	callStackBuffers.Put(buf)
+	return stack
+}
+
+// callerPC returns only the program counter
+// of the calling function skipping skip frames,
+// or nil if the CaptureMode is CaptureOff.
+func callerPC(skip int) []uintptr {
+	if !captureCallerPC() {
+		return nil
+	}
+	var c [1]uintptr
+	n := runtime.Callers(skip+2, c[:])
+	if currentInternCallStacks() {
+		return internedCallStacks.intern(c[:n])
+	}
+	return slices.Clone(c[:n])
 }
@@ -1,8 +1,11 @@
 package errs
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
 	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -108,6 +111,7 @@ func makeFuncParamsError() (err error) {
 }
 
 func TestStackTrace_SentryCompatible(t *testing.T) {
+	skipWithoutCallStacks(t)
 	err := New("boom") // concrete type *withCallStack, stack captured in this func
 	require.IsType(t, &withCallStack{}, err)
 
@@ -120,6 +124,7 @@ func TestStackTrace_SentryCompatible(t *testing.T) {
 }
 
 func TestStackTrace_SentryCompatible_FuncParams(t *testing.T) {
+	skipWithoutCallStacks(t)
 	err := makeFuncParamsError()
 	// Method is promoted from the embedded withCallStack.
 	require.IsType(t, &withCallStackFuncParams{}, err)
@@ -141,3 +146,136 @@ func TestStackTrace_MatchesCallStack(t *testing.T) {
 	assert.Equal(t, funcParams.CallStack(), funcParams.StackTrace(),
 		"promoted StackTrace must return the same program counters as CallStack")
 }
+
+func TestError_Memoized(t *testing.T) {
+	s := &strct{A: 1}
+	update := func(s *strct) (err error) {
+		defer WrapWith1FuncParam(&err, s)
+		return New("update failed")
+	}
+	err := update(s)
+
+	first := err.Error()
+	assert.Contains(t, first, "strct{A:1}")
+
+	// Mutations after the first Error call are not rendered
+	s.A = 2
+	assert.Equal(t, first, err.Error())
+
+	// Wrapping creates a new wrapper that renders again
+	wrapped := WrapWithCallStack(err)
+	assert.Contains(t, wrapped.Error(), "strct{A:2}")
+}
+
+func TestError_MemoizedConcurrent(t *testing.T) {
+	err := benchmarkErrorChain(3)
+
+	results := make(chan string)
+	for range 8 {
+		go func() { results <- err.Error() }()
+	}
+	want := formatError(err)
+	for range 8 {
+		assert.Equal(t, want, <-results)
+	}
+}
+
+// benchmarkErrorChain returns an error wrapped
+// with function parameters by depth+1 nested calls.
+func benchmarkErrorChain(depth int) (err error) {
+	defer WrapWith3FuncParams(&err, depth, &strct{A: depth}, "param")
+
+	if depth == 0 {
+		return New("benchmark error")
+	}
+	return benchmarkErrorChain(depth - 1)
+}
+
+func BenchmarkError(b *testing.B) {
+	err := benchmarkErrorChain(9)
+
+	b.Run("Uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			_ = formatError(err)
+		}
+	})
+
+	b.Run("Memoized", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			_ = err.Error()
+		}
+	})
+}
+
+func TestWrapWithFuncParams_CallerOnly(t *testing.T) {
+	skipWithoutCallStacks(t)
+	err := benchmarkErrorChain(3)
+
+	var wrappers []*withCallStackFuncParams
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if w, ok := e.(*withCallStackFuncParams); ok {
+			wrappers = append(wrappers, w)
+		}
+	}
+	require.Len(t, wrappers, 4)
+
+	// Only the innermost wrapper has a full call stack
+	innermost := wrappers[len(wrappers)-1]
+	assert.False(t, innermost.callerOnly)
+	assert.Greater(t, len(innermost.callStack), 4)
+	for _, w := range wrappers[:len(wrappers)-1] {
+		assert.True(t, w.callerOnly)
+		assert.Len(t, w.callStack, 1)
+		assert.Equal(t, "github.com/domonda/go-errs.benchmarkErrorChain", frameFunctions(w.callStack)[0])
+	}
+
+	// Sentry gets the full call stack from the outermost wrapper
+	assert.Equal(t, innermost.StackTrace(), extractSentryPCs(err))
+	assert.Contains(t, frameFunctions(extractSentryPCs(err)), "github.com/domonda/go-errs.TestWrapWithFuncParams_CallerOnly")
+
+	// Every wrapper still renders its function call and location
+	assert.Equal(t, 4, strings.Count(err.Error(), "github.com/domonda/go-errs.benchmarkErrorChain("))
+	assert.Equal(t, 4, strings.Count(err.Error(), "wrapwithcallstack_test.go:"))
+}
+
+// baselineErrorChain is benchmarkErrorChain wrapped
+// like before caller-only call stacks were introduced:
+// every level captures a full call stack.
+func baselineErrorChain(depth int) (err error) {
+	defer func() {
+		err = &withCallStackFuncParams{
+			withCallStack: withCallStack{
+				err:       err,
+				callStack: callStack(1),
+			},
+			params: []any{depth, &strct{A: depth}, "param"},
+		}
+	}()
+
+	if depth == 0 {
+		return New("benchmark error")
+	}
+	return baselineErrorChain(depth - 1)
+}
+
+func BenchmarkWrapWithFuncParams_DeepChain(b *testing.B) {
+	chains := []struct {
+		name  string
+		chain func(depth int) error
+	}{
+		{name: "CallerOnly", chain: benchmarkErrorChain},
+		{name: "Baseline", chain: baselineErrorChain},
+	}
+	for _, c := range chains {
+		for _, depth := range []int{1, 10, 50} {
+			b.Run(fmt.Sprintf("%s/Depth%d", c.name, depth), func(b *testing.B) {
+				b.ReportAllocs()
+				for b.Loop() {
+					_ = c.chain(depth - 1)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package errs
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// contextExtractor is a registered named function
+// extracting an attribute value from a context.
+type contextExtractor struct {
+	key     string
+	extract func(context.Context) (any, bool)
+}
+
+var (
+	contextExtractorsMtx sync.Mutex
+	// contextExtractors is replaced on every change
+	// so it can be read without locking
+	contextExtractors atomic.Pointer[[]contextExtractor]
+)
+
+// RegisterContextExtractor registers a function that extracts
+// a request-scoped value like a request ID, tenant, user ID
+// or trace ID from the context passed to WrapWithContext.
+// The value is attached to the wrapped error as attribute
+// with the passed key if extract returns true.
+//
+// Registering an extractor for an already registered key
+// replaces the existing extractor. It is safe to call concurrently.
+//
+// Example:
+//
+//	func init() {
+//	    errs.RegisterContextExtractor("request_id", errs.ContextValueExtractor(requestIDKey{}))
+//	    errs.RegisterContextExtractor("trace_id", func(ctx context.Context) (any, bool) {
+//	        spanCtx := trace.SpanContextFromContext(ctx)
+//	        return spanCtx.TraceID().String(), spanCtx.HasTraceID()
+//	    })
+//	}
+func RegisterContextExtractor(key string, extract func(ctx context.Context) (value any, ok bool)) {
+	contextExtractorsMtx.Lock()
+	defer contextExtractorsMtx.Unlock()
+
+	var extractors []contextExtractor
+	if current := contextExtractors.Load(); current != nil {
+		extractors = slices.DeleteFunc(slices.Clone(*current), func(e contextExtractor) bool { return e.key == key })
+	}
+	extractors = append(extractors, contextExtractor{key: key, extract: extract})
+	contextExtractors.Store(&extractors)
+}
+
+// UnregisterContextExtractor removes the extractor
+// registered for key with RegisterContextExtractor.
+// It is safe to call concurrently.
+func UnregisterContextExtractor(key string) {
+	contextExtractorsMtx.Lock()
+	defer contextExtractorsMtx.Unlock()
+
+	current := contextExtractors.Load()
+	if current == nil {
+		return
+	}
+	extractors := slices.DeleteFunc(slices.Clone(*current), func(e contextExtractor) bool { return e.key == key })
+	contextExtractors.Store(&extractors)
+}
+
+// ContextValueExtractor returns an extractor for RegisterContextExtractor
+// that returns ctx.Value(ctxKey) if it is not nil.
+func ContextValueExtractor(ctxKey any) func(context.Context) (any, bool) {
+	return func(ctx context.Context) (any, bool) {
+		value := ctx.Value(ctxKey)
+		return value, value != nil
+	}
+}
+
+// extractContextAttrs returns the attributes of all
+// registered extractors that found a value in ctx.
+// LogValuer values like secrets are resolved
+// so the attributes don't reference ctx values.
+func extractContextAttrs(ctx context.Context) []slog.Attr {
+	extractors := contextExtractors.Load()
+	if ctx == nil || extractors == nil {
+		return nil
+	}
+	var attrs []slog.Attr
+	for _, e := range *extractors {
+		if value, ok := e.extract(ctx); ok {
+			attrs = append(attrs, slog.Attr{Key: e.key, Value: slog.AnyValue(value).Resolve()})
+		}
+	}
+	return attrs
+}
+
+// WrapWithContext wraps an error with the current call stack,
+// function parameters, and the request-scoped attributes
+// extracted from ctx by the extractors registered with
+// RegisterContextExtractor.
+//
+// The attributes are rendered after the location of the function call
+// by the Error method in all OutputFormat layouts,
+// returned by ContextAttrs, and logged as attributes
+// by log/slog together with the error text,
+// also when the error is wrapped again by outer call stack wrappers.
+//
+// Example:
+//
+//	func HandleOrder(ctx context.Context, orderID string) (err error) {
+//	    defer errs.WrapWithContext(ctx, &err, orderID)
+//	    // ...
+//	}
+//
+// Without registered extractors or extracted values
+// it behaves like WrapWithFuncParams.
+func WrapWithContext(ctx context.Context, resultVar *error, params ...any) {
+	if *resultVar != nil {
+		*resultVar = wrapWithContextSkip(1, ctx, *resultVar, params...)
+	}
+}
+
+// WrapWithContextSkip is like WrapWithContext but skips skip stack frames,
+// see WrapWithFuncParamsSkip.
+func WrapWithContextSkip(skip int, ctx context.Context, resultVar *error, params ...any) {
+	if *resultVar != nil {
+		*resultVar = wrapWithContextSkip(1+skip, ctx, *resultVar, params...)
+	}
+}
+
+func wrapWithContextSkip(skip int, ctx context.Context, err error, params ...any) error {
+	attrs := extractContextAttrs(ctx)
+	if len(attrs) == 0 {
+		return wrapWithFuncParamsSkip(1+skip, err, params...)
+	}
+	return newWithContextAttrs(newWithFuncParamsSkip(1+skip, err, params...), attrs)
+}
+
+// newWithContextAttrs returns a withContextAttrs
+// with the fields of wrapped and attrs.
+func newWithContextAttrs(wrapped *withCallStackFuncParams, attrs []slog.Attr) *withContextAttrs {
+	return &withContextAttrs{
+		withCallStackFuncParams: withCallStackFuncParams{
+			withCallStack: withCallStack{
+				err:        wrapped.err,
+				callStack:  wrapped.callStack,
+				callerOnly: wrapped.callerOnly,
+			},
+			params: wrapped.params,
+		},
+		attrs: attrs,
+	}
+}
+
+var (
+	_ error                   = &withContextAttrs{}
+	_ callStackParamsProvider = &withContextAttrs{}
+	_ slog.LogValuer          = &withContextAttrs{}
+)
+
+// withContextAttrs is a withCallStackFuncParams
+// with attributes extracted from a context.
+// Without own attributes it is returned by WrapWithFuncParams
+// and WrapContextErr for error chains with context attributes
+// to log them.
+type withContextAttrs struct {
+	withCallStackFuncParams
+
+	attrs []slog.Attr
+
+	// deadline is set by WrapContextErr
+	deadline *ContextDeadline
+}
+
+// Error returns the error message followed by the call stack
+// including the function parameters and context attributes.
+//
+// The message is rendered by the first call and cached
+// for all further calls, see withCallStack.Error.
+func (w *withContextAttrs) Error() string {
+	return w.memoizeError(w)
+}
+
+func (w *withContextAttrs) contextAttrs() []slog.Attr {
+	return w.attrs
+}
+
+func (w *withContextAttrs) contextDeadline() *ContextDeadline {
+	return w.deadline
+}
+
+// LogValue implements slog.LogValuer by returning a group
+// with the error text under the key "error"
+// followed by the attributes of ContextAttrs
+// of the whole error chain.
+func (w *withContextAttrs) LogValue() slog.Value {
+	return errorLogValue(w)
+}
+
+// errorLogValue returns the slog.Value of the wrapper err
+// of an error chain with context attributes,
+// a group with the error text under the key "error"
+// followed by the attributes of ContextAttrs.
+func errorLogValue(err error) slog.Value {
+	return slog.GroupValue(append([]slog.Attr{slog.String("error", err.Error())}, ContextAttrs(err)...)...)
+}
+
+var (
+	_ error          = &withCallStackLogAttrs{}
+	_ slog.LogValuer = &withCallStackLogAttrs{}
+)
+
+// withCallStackLogAttrs is the withCallStack returned by
+// WrapWithCallStack for an error chain with context attributes,
+// so that they are logged by slog through the wrapper.
+// Other wrappers don't implement slog.LogValuer
+// to be logged unchanged like any other error.
+type withCallStackLogAttrs struct {
+	*withCallStack
+}
+
+// LogValue implements slog.LogValuer, see withContextAttrs.LogValue.
+func (w *withCallStackLogAttrs) LogValue() slog.Value {
+	return errorLogValue(w)
+}
+
+// hasContextAttrs reports whether the error chain of err
+// has context attributes, see ContextAttrs.
+func hasContextAttrs(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		switch err.(type) {
+		case *withContextAttrs, *withCallStackLogAttrs:
+			return true
+		case *withCallStack, *withCallStackFuncParams, *withContextDeadline:
+			// Only returned for error chains without context attributes
+			return false
+		}
+	}
+	return false
+}
+
+// ContextAttrs returns the attributes extracted from contexts
+// by WrapWithContext for all wrappers in the error chain of err.
+// Attributes are ordered by the outermost wrapper first,
+// for keys found multiple times the value of the innermost wrapper is used.
+func ContextAttrs(err error) []slog.Attr {
+	var attrs []slog.Attr
+	for ; err != nil; err = errors.Unwrap(err) {
+		w, ok := err.(interface{ contextAttrs() []slog.Attr })
+		if !ok {
+			continue
+		}
+		for _, attr := range w.contextAttrs() {
+			i := slices.IndexFunc(attrs, func(a slog.Attr) bool { return a.Key == attr.Key })
+			if i >= 0 {
+				attrs[i] = attr
+			} else {
+				attrs = append(attrs, attr)
+			}
+		}
+	}
+	return attrs
+}
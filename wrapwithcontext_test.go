@@ -0,0 +1,229 @@
+package errs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	requestIDKey struct{}
+	tenantKey    struct{}
+)
+
+func withTestContextExtractors(t *testing.T) {
+	t.Helper()
+	RegisterContextExtractor("request_id", ContextValueExtractor(requestIDKey{}))
+	RegisterContextExtractor("tenant", ContextValueExtractor(tenantKey{}))
+	t.Cleanup(func() {
+		UnregisterContextExtractor("request_id")
+		UnregisterContextExtractor("tenant")
+	})
+}
+
+func contextTestHandler(ctx context.Context, orderID string) (err error) {
+	defer WrapWithContext(ctx, &err, orderID)
+
+	return contextTestService(ctx)
+}
+
+func contextTestOuter(ctx context.Context, orderID string) (err error) {
+	defer WrapWithFuncParams(&err, orderID)
+
+	return contextTestHandler(ctx, orderID)
+}
+
+func contextTestService(ctx context.Context) (err error) {
+	defer WrapWithContext(ctx, &err)
+
+	return New("not available")
+}
+
+func TestWrapWithContext(t *testing.T) {
+	skipWithoutCallStacks(t)
+	withTestContextExtractors(t)
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "req-1")
+	ctx = context.WithValue(ctx, tenantKey{}, "acme corp")
+
+	err := contextTestHandler(ctx, "order-7")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, Sentinel("not available"))
+
+	assert.Equal(t,
+		[]slog.Attr{slog.String("request_id", "req-1"), slog.String("tenant", "acme corp")},
+		ContextAttrs(err),
+	)
+
+	text := err.Error()
+	assert.Regexp(t, ""+
+		"^not available\n"+
+		`github\.com/domonda/go-errs\.contextTestService\(\)\n`+
+		`    github\.com/domonda/go-errs/wrapwithcontext_test\.go:\d+\n`+
+		`    request_id=req-1 tenant="acme corp"\n`+
+		"github\\.com/domonda/go-errs\\.contextTestHandler\\(`order-7`\\)\n"+
+		`    github\.com/domonda/go-errs/wrapwithcontext_test\.go:\d+\n`+
+		`    request_id=req-1 tenant="acme corp"\n$`,
+		text,
+	)
+
+	compact := NewConfig(WithOutputFormat(OutputCompact)).FormatError(err)
+	assert.Contains(t, compact, `wrapwithcontext_test.go:`)
+	assert.Contains(t, compact, ` {request_id=req-1 tenant="acme corp"}`)
+
+	var parsed struct {
+		Calls []struct {
+			Context map[string]any `json:"context"`
+		} `json:"calls"`
+	}
+	formatted := NewConfig(WithOutputFormat(OutputJSON)).FormatError(err)
+	require.NoError(t, json.Unmarshal([]byte(formatted), &parsed), formatted)
+	require.Len(t, parsed.Calls, 2)
+	assert.Equal(t, map[string]any{"request_id": "req-1", "tenant": "acme corp"}, parsed.Calls[1].Context)
+}
+
+func TestWrapWithContext_Slog(t *testing.T) {
+	withTestContextExtractors(t)
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "req-2")
+
+	err := contextTestHandler(ctx, "order-8")
+
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Error("failed", slog.Any("err", err))
+	var logged struct {
+		Err struct {
+			Error     string `json:"error"`
+			RequestID string `json:"request_id"`
+		} `json:"err"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &logged), buf.String())
+	assert.Equal(t, "req-2", logged.Err.RequestID)
+	assert.Equal(t, err.Error(), logged.Err.Error)
+}
+
+func TestWrapWithContext_SlogOuterWrapper(t *testing.T) {
+	withTestContextExtractors(t)
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "req-3")
+
+	err := contextTestOuter(ctx, "order-10")
+	require.IsType(t, &withContextAttrs{}, err)
+	require.Empty(t, err.(*withContextAttrs).attrs, "outer wrapper has no own attributes")
+
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Error("failed", slog.Any("err", err))
+	var logged struct {
+		Err struct {
+			Error     string `json:"error"`
+			RequestID string `json:"request_id"`
+		} `json:"err"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &logged), buf.String())
+	assert.Equal(t, "req-3", logged.Err.RequestID)
+	assert.Equal(t, err.Error(), logged.Err.Error)
+
+	err = WrapWithCallStack(contextTestHandler(ctx, "order-12"))
+	buf.Reset()
+	slog.New(slog.NewJSONHandler(&buf, nil)).Error("failed", slog.Any("err", err))
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &logged), buf.String())
+	assert.Equal(t, "req-3", logged.Err.RequestID)
+	assert.Equal(t, err.Error(), logged.Err.Error)
+
+	// Without context attributes the error is logged as text
+	err = contextTestOuter(context.Background(), "order-11")
+	buf.Reset()
+	slog.New(slog.NewJSONHandler(&buf, nil)).Error("failed", slog.Any("err", err))
+	var loggedText struct {
+		Err string `json:"err"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &loggedText), buf.String())
+	assert.Equal(t, err.Error(), loggedText.Err)
+}
+
+func TestSlog_WithoutContextAttrs(t *testing.T) {
+	withTestContextExtractors(t)
+
+	for name, err := range map[string]error{
+		"New":                New("not found"),
+		"Errorf":             Errorf("wrapped: %w", New("not found")),
+		"WrapWithCallStack":  WrapWithCallStack(errors.New("not found")),
+		"WrapWithFuncParams": contextTestOuter(context.Background(), "order-13"),
+		"WrapWithContext":    contextTestService(context.Background()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			// The error is logged as the value itself
+			// like errors without slog.LogValuer
+			assert.NotImplements(t, (*slog.LogValuer)(nil), err)
+			assert.Same(t, err, slog.AnyValue(err).Resolve().Any())
+
+			var buf, want bytes.Buffer
+			slog.New(slog.NewTextHandler(&buf, nil)).Error("failed", "err", err)
+			slog.New(slog.NewTextHandler(&want, nil)).Error("failed", "err", errors.New(err.Error()))
+			assert.Equal(t, dropTime(want.String()), dropTime(buf.String()))
+		})
+	}
+}
+
+// dropTime returns the slog.TextHandler output line
+// without its time attribute.
+func dropTime(line string) string {
+	_, after, _ := strings.Cut(line, " ")
+	return after
+}
+
+func TestWrapWithContext_NoAttrs(t *testing.T) {
+	skipWithoutCallStacks(t)
+	// No extractors registered
+	err := contextTestHandler(context.Background(), "order-9")
+	assert.IsType(t, &withCallStackFuncParams{}, err)
+	assert.Nil(t, ContextAttrs(err))
+
+	withTestContextExtractors(t)
+	// No values in context
+	err = contextTestHandler(context.Background(), "order-9")
+	assert.IsType(t, &withCallStackFuncParams{}, err)
+	assert.Contains(t, err.Error(), "contextTestHandler(`order-9`)")
+
+	var nilErr error
+	WrapWithContext(context.Background(), &nilErr)
+	assert.NoError(t, nilErr)
+}
+
+func TestWrapWithContext_Secret(t *testing.T) {
+	RegisterContextExtractor("token", func(ctx context.Context) (any, bool) {
+		return KeepSecret("hunter2"), true
+	})
+	t.Cleanup(func() { UnregisterContextExtractor("token") })
+
+	err := contextTestService(context.Background())
+	assert.Contains(t, err.Error(), "token=***REDACTED***")
+	assert.NotContains(t, err.Error(), "hunter2")
+}
+
+func TestContextAttrs_InnermostWins(t *testing.T) {
+	withTestContextExtractors(t)
+	inner := context.WithValue(context.Background(), requestIDKey{}, "inner")
+	outer := context.WithValue(context.Background(), requestIDKey{}, "outer")
+	outer = context.WithValue(outer, tenantKey{}, "t")
+
+	err := contextTestService(inner)
+	WrapWithContext(outer, &err)
+	assert.Equal(t,
+		[]slog.Attr{slog.String("request_id", "inner"), slog.String("tenant", "t")},
+		ContextAttrs(err),
+	)
+	assert.Nil(t, ContextAttrs(errors.New("plain")))
+}
+
+func TestRegisterContextExtractor_Replace(t *testing.T) {
+	RegisterContextExtractor("key", func(context.Context) (any, bool) { return 1, true })
+	RegisterContextExtractor("key", func(context.Context) (any, bool) { return 2, true })
+	t.Cleanup(func() { UnregisterContextExtractor("key") })
+
+	assert.Equal(t, []slog.Attr{slog.Int("key", 2)}, extractContextAttrs(context.Background()))
+}
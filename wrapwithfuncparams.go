@@ -1,5 +1,9 @@
 package errs
 
+import (
+	"errors"
+)
+
 /*
 Call argument parameters are available on the stack,
 but in a platform dependent packed format and not directly accessible
@@ -9,22 +13,66 @@ Could only be parsed from runtime.Stack() result text.
 See https://www.ardanlabs.com/blog/2015/01/stack-traces-in-go.html
 */
 
-func wrapWithFuncParamsSkip(skip int, err error, params ...any) *withCallStackFuncParams {
+// wrapWithFuncParamsSkip returns err wrapped by newWithFuncParamsSkip,
+// as withContextAttrs without own attributes if the error chain
+// of err has context attributes, so that they are logged by slog
+// through the wrapper, see withContextAttrs.LogValue.
+func wrapWithFuncParamsSkip(skip int, err error, params ...any) error {
+	w := newWithFuncParamsSkip(1+skip, err, params...)
+	if hasContextAttrs(err) {
+		return newWithContextAttrs(w, nil)
+	}
+	return w
+}
+
+// newWithFuncParamsSkip returns err wrapped with params
+// and the call stack, skipping skip stack frames.
+func newWithFuncParamsSkip(skip int, err error, params ...any) *withCallStackFuncParams {
+	if currentSnapshotFuncParams() {
+		params = snapshotFuncParams(params)
+	}
 	switch w := err.(type) {
 	case callStackParamsProvider:
-		// OK, wrap the wrapped
+		// Wrap the wrapped, only the first wrapper
+		// of a chain needs to capture a full call stack
+		return &withCallStackFuncParams{
+			withCallStack: withCallStack{
+				err:        err,
+				callStack:  callerPC(skip + 1),
+				callerOnly: true,
+			},
+			params: params,
+		}
 	case callStackProvider:
 		// Already wrapped with call stack,
 		// replace with withCallStackFuncParams
+		stack := w.CallStack()
+		if len(stack) == 0 {
+			// The call stack was not captured because of the CaptureMode,
+			// at least show the location of the function with the params
+			stack = callerPC(skip + 1)
+		}
 		return &withCallStackFuncParams{
 			withCallStack: withCallStack{
-				err:       w.Unwrap(),
-				callStack: w.CallStack(),
+				err:        w.Unwrap(),
+				callStack:  stack,
+				callerOnly: isCallerOnly(err),
 			},
 			params: params,
 		}
 	}
 
+	if hasCallStack(err) {
+		// An error further down the chain already has a call stack
+		return &withCallStackFuncParams{
+			withCallStack: withCallStack{
+				err:        err,
+				callStack:  callerPC(skip + 1),
+				callerOnly: true,
+			},
+			params: params,
+		}
+	}
 	return &withCallStackFuncParams{
 		withCallStack: withCallStack{
 			err:       err,
@@ -34,6 +82,35 @@ func wrapWithFuncParamsSkip(skip int, err error, params ...any) *withCallStackFu
 	}
 }
 
+// hasCallStack reports whether err or any error
+// in its Unwrap() error chain provides a call stack.
+func hasCallStack(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if _, ok := err.(callStackProvider); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isCallerOnly reports whether err is a wrapper of this package
+// that only captured the program counter of the calling function.
+func isCallerOnly(err error) bool {
+	switch w := err.(type) {
+	case *withCallStack:
+		return w.callerOnly
+	case *withCallStackLogAttrs:
+		return w.callerOnly
+	case *withCallStackFuncParams:
+		return w.callerOnly
+	case *withContextAttrs:
+		return w.callerOnly
+	case *withContextDeadline:
+		return w.callerOnly
+	}
+	return false
+}
+
 // WrapWithFuncParamsSkip wraps an error with the current call stack and function parameters,
 // skipping skip stack frames.
 //
@@ -205,8 +282,13 @@ type withCallStackFuncParams struct {
 	params []any
 }
 
+// Error returns the error message followed by the call stack
+// including the function parameters.
+//
+// The message is rendered by the first call and cached
+// for all further calls, see withCallStack.Error.
 func (w *withCallStackFuncParams) Error() string {
-	return formatError(w)
+	return w.memoizeError(w)
 }
 
 func (w *withCallStackFuncParams) CallStackParams() ([]uintptr, []any) {
@@ -0,0 +1,25 @@
+//go:build !errs_nostack
+
+package errs
+
+import (
+	"context"
+	"fmt"
+)
+
+// The output of the example contains the call stack
+// that is not captured with the errs_nostack build tag.
+
+func ExampleWrapWithFuncParams() {
+	err := funcA(context.Background(), 666, "Hello World!", &strct{A: -1})
+	fmt.Println(err)
+
+	// Output:
+	// error in funcC
+	// github.com/domonda/go-errs.funcC()
+	//     github.com/domonda/go-errs/wrapwithfuncparams_test.go:24
+	// github.com/domonda/go-errs.funcB([`Hello World!`,`X\nX`])
+	//     github.com/domonda/go-errs/wrapwithfuncparams_test.go:18
+	// github.com/domonda/go-errs.funcA(Context{}, 666, `Hello World!`, strct{A:-1})
+	//     github.com/domonda/go-errs/wrapwithfuncparams_test.go:12
+}
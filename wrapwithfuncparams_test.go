@@ -1,9 +1,6 @@
 package errs
 
-import (
-	"context"
-	"fmt"
-)
+import "context"
 
 type strct struct {
 	A int
@@ -26,17 +23,3 @@ func funcC() (err error) {
 
 	return New("error in funcC")
 }
-
-func ExampleWrapWithFuncParams() {
-	err := funcA(context.Background(), 666, "Hello World!", &strct{A: -1})
-	fmt.Println(err)
-
-	// Output:
-	// error in funcC
-	// github.com/domonda/go-errs.funcC()
-	//     github.com/domonda/go-errs/wrapwithfuncparams_test.go:27
-	// github.com/domonda/go-errs.funcB([`Hello World!`,`X\nX`])
-	//     github.com/domonda/go-errs/wrapwithfuncparams_test.go:21
-	// github.com/domonda/go-errs.funcA(Context{}, 666, `Hello World!`, strct{A:-1})
-	//     github.com/domonda/go-errs/wrapwithfuncparams_test.go:15
-}